@@ -0,0 +1,175 @@
+/*
+Package firehose decodes the frames of the ATProto repository event stream,
+also known as the firehose (com.atproto.sync.subscribeRepos).
+
+Each WebSocket message is a frame made of two concatenated DRISL items: a header
+with the type of the message, and the body. DecodeFrame only decodes the header,
+so frames can be filtered by type before the body is decoded.
+
+	frame, err := firehose.DecodeFrame(msg)
+	if err != nil {
+		return err
+	}
+	body, err := frame.Body()
+	if err != nil {
+		return err
+	}
+	switch body := body.(type) {
+	case *firehose.Commit:
+		// ...
+	case *firehose.StreamError:
+		return body
+	}
+
+https://atproto.com/specs/event-stream
+*/
+package firehose
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/hyphacoop/go-dasl/blockstore"
+	"github.com/hyphacoop/go-dasl/car"
+	"github.com/hyphacoop/go-dasl/drisl"
+)
+
+//go:generate go run ../../cmd/drislgen -type Header,Commit,RepoOp,Identity,Account,Sync,Info,StreamError -output messages_drisl.go
+
+// Header operations.
+const (
+	// OpMessage is the operation of regular messages.
+	OpMessage = 1
+	// OpError is the operation of error frames, after which the stream is closed.
+	OpError = -1
+)
+
+// Message types of the firehose.
+const (
+	TypeCommit   = "#commit"
+	TypeIdentity = "#identity"
+	TypeAccount  = "#account"
+	TypeSync     = "#sync"
+	TypeInfo     = "#info"
+)
+
+var (
+	// ErrInvalidFrame is returned when a message is not a valid frame.
+	// It is wrapped with more details.
+	ErrInvalidFrame = errors.New("go-dasl/atproto/firehose: invalid frame")
+
+	// ErrUnknownType is returned by Frame.Body for message types it doesn't know.
+	// Their body can still be decoded with Frame.DecodeBody.
+	ErrUnknownType = errors.New("go-dasl/atproto/firehose: unknown message type")
+)
+
+// Header is the first item of a frame.
+type Header struct {
+	// Op is OpMessage or OpError.
+	Op int64 `cbor:"op"`
+	// Type is the message type, like TypeCommit. It is empty for error frames.
+	Type string `cbor:"t,omitempty"`
+}
+
+// Frame is a decoded header and the raw body of a WebSocket message.
+type Frame struct {
+	Header Header
+	body   []byte
+}
+
+// DecodeFrame decodes the header of a frame. The body is only decoded when
+// Body or DecodeBody are called.
+func DecodeFrame(msg []byte) (*Frame, error) {
+	dec := drisl.NewDecoder(bytes.NewReader(msg))
+	var f Frame
+	if err := dec.Decode(&f.Header); err != nil {
+		return nil, fmt.Errorf("%w: header: %w", ErrInvalidFrame, err)
+	}
+	switch f.Header.Op {
+	case OpMessage:
+		if f.Header.Type == "" {
+			return nil, fmt.Errorf("%w: header has no message type", ErrInvalidFrame)
+		}
+	case OpError:
+	default:
+		return nil, fmt.Errorf("%w: unknown header operation %d", ErrInvalidFrame, f.Header.Op)
+	}
+	f.body = msg[dec.NumBytesRead():]
+	if len(f.body) == 0 {
+		return nil, fmt.Errorf("%w: missing body", ErrInvalidFrame)
+	}
+	return &f, nil
+}
+
+// EncodeFrame encodes a frame with the given header and body.
+func EncodeFrame(h Header, body any) ([]byte, error) {
+	b, err := h.appendDRISL(nil)
+	if err != nil {
+		return nil, err
+	}
+	data, err := drisl.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, data...), nil
+}
+
+// RawBody returns the encoded body of the frame. It has not been validated.
+func (f *Frame) RawBody() []byte {
+	return f.body
+}
+
+// DecodeBody decodes the body of the frame into v, with drisl.Unmarshal.
+func (f *Frame) DecodeBody(v any) error {
+	return drisl.Unmarshal(f.body, v)
+}
+
+// Body decodes the body of the frame into the type for its header: *Commit, *Identity,
+// *Account, *Sync, *Info, or *StreamError for error frames.
+// ErrUnknownType is returned for other message types.
+//
+// The body is decoded again on each call.
+func (f *Frame) Body() (any, error) {
+	var v drisl.Unmarshaler
+	if f.Header.Op == OpError {
+		v = &StreamError{}
+	} else {
+		switch f.Header.Type {
+		case TypeCommit:
+			v = &Commit{}
+		case TypeIdentity:
+			v = &Identity{}
+		case TypeAccount:
+			v = &Account{}
+		case TypeSync:
+			v = &Sync{}
+		case TypeInfo:
+			v = &Info{}
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrUnknownType, f.Header.Type)
+		}
+	}
+	if err := v.UnmarshalCBOR(f.body); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// readBlocks reads the blocks of a CAR file into memory.
+func readBlocks(data []byte) (*blockstore.Memory, error) {
+	cr, err := car.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	bs := blockstore.NewMemory()
+	for c, block := range cr.Blocks() {
+		if err := bs.Put(c, block); err != nil {
+			return nil, err
+		}
+	}
+	if err := cr.Err(); err != nil {
+		return nil, err
+	}
+	return bs, nil
+}
@@ -0,0 +1,240 @@
+package firehose_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/hyphacoop/go-dasl/atproto/firehose"
+	"github.com/hyphacoop/go-dasl/atproto/mst"
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+)
+
+// readFrames reads the recorded WebSocket messages, one hex-encoded message per line.
+func readFrames(t testing.TB) [][]byte {
+	t.Helper()
+	f, err := os.Open("testdata/frames.hex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var msgs [][]byte
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		msg, err := hex.DecodeString(sc.Text())
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, msg)
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return msgs
+}
+
+// commitObject is the signed commit of a repository.
+type commitObject struct {
+	Did     string   `cbor:"did"`
+	Version int      `cbor:"version"`
+	Data    cid.Cid  `cbor:"data"`
+	Rev     string   `cbor:"rev"`
+	Prev    *cid.Cid `cbor:"prev"`
+	Sig     []byte   `cbor:"sig"`
+}
+
+// checkCommit checks that the record operations match the MST in the commit blocks.
+func checkCommit(t *testing.T, c *firehose.Commit) {
+	t.Helper()
+	bs, err := c.ReadBlocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := bs.Get(c.Commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var obj commitObject
+	if err := drisl.Unmarshal(data, &obj); err != nil {
+		t.Fatal(err)
+	}
+	if obj.Did != c.Repo || obj.Rev != c.Rev {
+		t.Fatalf("commit object is for %s at %s, want %s at %s", obj.Did, obj.Rev, c.Repo, c.Rev)
+	}
+	tree, err := mst.Load(bs, obj.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range c.Ops {
+		value, err := tree.Get(op.Path)
+		switch op.Action {
+		case firehose.ActionCreate, firehose.ActionUpdate:
+			if err != nil {
+				t.Fatalf("%s %s: %v", op.Action, op.Path, err)
+			}
+			if op.Cid == nil || value != *op.Cid {
+				t.Fatalf("%s %s: got %s in the MST, want %v", op.Action, op.Path, value, op.Cid)
+			}
+			if has, _ := bs.Has(value); !has {
+				t.Fatalf("%s %s: record is not in the blocks", op.Action, op.Path)
+			}
+		case firehose.ActionDelete:
+			if !errors.Is(err, mst.ErrNotFound) {
+				t.Fatalf("delete %s: got %v, want ErrNotFound", op.Path, err)
+			}
+		default:
+			t.Fatalf("unknown action %q", op.Action)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	wantTypes := []string{
+		firehose.TypeIdentity,
+		firehose.TypeAccount,
+		firehose.TypeCommit,
+		firehose.TypeCommit,
+		firehose.TypeSync,
+		firehose.TypeAccount,
+		"#labels",
+		firehose.TypeInfo,
+		"",
+	}
+	msgs := readFrames(t)
+	if len(msgs) != len(wantTypes) {
+		t.Fatalf("got %d frames, want %d", len(msgs), len(wantTypes))
+	}
+
+	var lastSeq int64
+	checkSeq := func(seq int64) {
+		if seq <= lastSeq {
+			t.Fatalf("seq %d after %d", seq, lastSeq)
+		}
+		lastSeq = seq
+	}
+	for i, msg := range msgs {
+		f, err := firehose.DecodeFrame(msg)
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		if f.Header.Type != wantTypes[i] {
+			t.Fatalf("frame %d: got type %q, want %q", i, f.Header.Type, wantTypes[i])
+		}
+		body, err := f.Body()
+		if f.Header.Type == "#labels" {
+			if !errors.Is(err, firehose.ErrUnknownType) {
+				t.Fatalf("frame %d: got %v, want ErrUnknownType", i, err)
+			}
+			var labels struct {
+				Seq int64 `cbor:"seq"`
+			}
+			if err := f.DecodeBody(&labels); err != nil {
+				t.Fatalf("frame %d: %v", i, err)
+			}
+			checkSeq(labels.Seq)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+
+		switch body := body.(type) {
+		case *firehose.Commit:
+			checkSeq(body.Seq)
+			checkCommit(t, body)
+		case *firehose.Identity:
+			checkSeq(body.Seq)
+			if body.Handle != "alice.example.com" {
+				t.Fatalf("got handle %q", body.Handle)
+			}
+		case *firehose.Account:
+			checkSeq(body.Seq)
+			if body.Active != (body.Status == "") {
+				t.Fatalf("got active %v with status %q", body.Active, body.Status)
+			}
+		case *firehose.Sync:
+			checkSeq(body.Seq)
+			bs, err := body.ReadBlocks()
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := 0
+			for _, err := range bs.AllKeys() {
+				if err != nil {
+					t.Fatal(err)
+				}
+				n++
+			}
+			if n != 1 {
+				t.Fatalf("got %d blocks in sync message, want the commit only", n)
+			}
+		case *firehose.Info:
+			if body.Name != "OutdatedCursor" {
+				t.Fatalf("got info %q", body.Name)
+			}
+		case *firehose.StreamError:
+			if f.Header.Op != firehose.OpError || body.Name != "ConsumerTooSlow" {
+				t.Fatalf("got error frame %+v, %+v", f.Header, body)
+			}
+		default:
+			t.Fatalf("frame %d: unexpected body %T", i, body)
+		}
+
+		// Frames are encoded the same way
+		enc, err := firehose.EncodeFrame(f.Header, body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(enc, msg) {
+			t.Fatalf("frame %d: got %x, want %x", i, enc, msg)
+		}
+	}
+}
+
+func TestDecodeFrameInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		msg  string
+	}{
+		{"empty", ""},
+		{"header only", "a2617468236163636f756e74626f7001"},
+		{"no type", "a1626f7001a0"},
+		{"unknown op", "a2617468236163636f756e74626f7002a0"},
+		{"invalid header", "a2626f7001617468236163636f756e74a0"},
+	} {
+		msg, _ := hex.DecodeString(tc.msg)
+		if _, err := firehose.DecodeFrame(msg); !errors.Is(err, firehose.ErrInvalidFrame) {
+			t.Fatalf("%s: got %v, want ErrInvalidFrame", tc.name, err)
+		}
+	}
+
+	// The body is only checked when it is decoded
+	msg, _ := hex.DecodeString("a2617468236163636f756e74626f7001ff")
+	f, err := firehose.DecodeFrame(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Body(); err == nil {
+		t.Fatal("Body: got no error for invalid body")
+	}
+}
+
+func BenchmarkDecodeCommit(b *testing.B) {
+	msgs := readFrames(b)
+	msg := msgs[2]
+	b.SetBytes(int64(len(msg)))
+	for b.Loop() {
+		f, err := firehose.DecodeFrame(msg)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := f.Body(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package firehose
+
+import (
+	"github.com/hyphacoop/go-dasl/blockstore"
+	"github.com/hyphacoop/go-dasl/cid"
+)
+
+// Commit is the body of TypeCommit messages: an update to a repository.
+type Commit struct {
+	Seq int64 `cbor:"seq"`
+	// Rebase is deprecated and always false.
+	Rebase bool `cbor:"rebase"`
+	// TooBig is deprecated and always false.
+	TooBig bool `cbor:"tooBig"`
+	// Repo is the DID of the repository.
+	Repo string `cbor:"repo"`
+	// Commit is the CID of the new commit object.
+	Commit cid.Cid `cbor:"commit"`
+	// Rev is the revision of the new commit.
+	Rev string `cbor:"rev"`
+	// Since is the revision of the previous commit, if any.
+	Since *string `cbor:"since"`
+	// Blocks is a CAR file with the commit object, the changed MST nodes, and the
+	// records that were created or updated.
+	Blocks []byte `cbor:"blocks"`
+	// Ops are the changes to the records.
+	Ops []RepoOp `cbor:"ops"`
+	// Blobs is deprecated and always empty.
+	Blobs []cid.Cid `cbor:"blobs"`
+	// PrevData is the MST root of the previous commit.
+	PrevData *cid.Cid `cbor:"prevData,omitempty"`
+	// Time is when the message was emitted, in RFC 3339 format.
+	Time string `cbor:"time"`
+}
+
+// ReadBlocks reads the blocks of the commit into memory.
+// They can be used to load the MST of the repository with mst.Load.
+func (c *Commit) ReadBlocks() (*blockstore.Memory, error) {
+	return readBlocks(c.Blocks)
+}
+
+// Record operations.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// RepoOp is a change to a record.
+type RepoOp struct {
+	// Action is ActionCreate, ActionUpdate, or ActionDelete.
+	Action string `cbor:"action"`
+	// Path is the collection and record key, like "app.bsky.feed.post/3jqfcqzm3fo2j".
+	Path string `cbor:"path"`
+	// Cid is the CID of the new record, or nil for deletions.
+	Cid *cid.Cid `cbor:"cid"`
+	// Prev is the CID of the previous record for updates and deletions.
+	Prev *cid.Cid `cbor:"prev,omitempty"`
+}
+
+// Identity is the body of TypeIdentity messages: the identity of an account may have changed.
+type Identity struct {
+	Seq  int64  `cbor:"seq"`
+	Did  string `cbor:"did"`
+	Time string `cbor:"time"`
+	// Handle is the current handle of the account, if known.
+	Handle string `cbor:"handle,omitempty"`
+}
+
+// Account is the body of TypeAccount messages: the hosting status of an account changed.
+type Account struct {
+	Seq  int64  `cbor:"seq"`
+	Did  string `cbor:"did"`
+	Time string `cbor:"time"`
+	// Active is whether the repository is available.
+	Active bool `cbor:"active"`
+	// Status is the reason the account is not active, like "takendown" or "deactivated".
+	Status string `cbor:"status,omitempty"`
+}
+
+// Sync is the body of TypeSync messages: the current state of a repository,
+// without the changes that led to it.
+type Sync struct {
+	Seq int64  `cbor:"seq"`
+	Did string `cbor:"did"`
+	// Blocks is a CAR file with the commit object.
+	Blocks []byte `cbor:"blocks"`
+	Rev    string `cbor:"rev"`
+	Time   string `cbor:"time"`
+}
+
+// ReadBlocks reads the blocks of the sync message into memory.
+func (s *Sync) ReadBlocks() (*blockstore.Memory, error) {
+	return readBlocks(s.Blocks)
+}
+
+// Info is the body of TypeInfo messages, which are informational.
+type Info struct {
+	// Name is the kind of message, like "OutdatedCursor".
+	Name    string `cbor:"name"`
+	Message string `cbor:"message,omitempty"`
+}
+
+// StreamError is the body of error frames.
+type StreamError struct {
+	// Name is the kind of error, like "FutureCursor" or "ConsumerTooSlow".
+	Name    string `cbor:"error"`
+	Message string `cbor:"message,omitempty"`
+}
+
+func (e *StreamError) Error() string {
+	if e.Message == "" {
+		return "go-dasl/atproto/firehose: stream error: " + e.Name
+	}
+	return "go-dasl/atproto/firehose: stream error: " + e.Name + ": " + e.Message
+}
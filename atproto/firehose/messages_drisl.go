@@ -0,0 +1,769 @@
+// Code generated by "drislgen -type Header,Commit,RepoOp,Identity,Account,Sync,Info,StreamError -output messages_drisl.go"; DO NOT EDIT.
+
+package firehose
+
+import (
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+	"github.com/hyphacoop/go-dasl/drisl/gensupport"
+)
+
+// MarshalCBOR fulfills the drisl.Marshaler interface.
+func (v Header) MarshalCBOR() ([]byte, error) {
+	return v.appendDRISL(nil)
+}
+
+func (v Header) appendDRISL(b []byte) ([]byte, error) {
+	n := 2
+	if !(v.Type != "") {
+		n--
+	}
+	b = gensupport.AppendMapHead(b, n)
+	if v.Type != "" {
+		b = gensupport.AppendString(b, "t")
+		b = gensupport.AppendString(b, v.Type)
+	}
+	b = gensupport.AppendString(b, "op")
+	b = gensupport.AppendInt(b, int64(v.Op))
+	return b, nil
+}
+
+// UnmarshalCBOR fulfills the drisl.Unmarshaler interface.
+func (v *Header) UnmarshalCBOR(data []byte) error {
+	d, err := gensupport.NewDecoder(data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+// UnmarshalDRISL fulfills the drisl.ModeUnmarshaler interface.
+func (v *Header) UnmarshalDRISL(dm drisl.DecMode, data []byte) error {
+	d, err := gensupport.NewDecoderMode(dm, data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+var drislFieldsHeader = []string{"op", "t"}
+
+func (v *Header) decodeDRISL(d *gensupport.Decoder) {
+	if d.SkipNull() {
+		return
+	}
+	n, err := d.MapHead("firehose.Header")
+	if err != nil {
+		return
+	}
+	var seen [2]bool
+	for i := range n {
+		f, err := d.Field("firehose.Header", drislFieldsHeader, seen[:])
+		if err != nil {
+			d.SkipPairs(n - i - 1)
+			return
+		}
+		switch f {
+		case 0:
+			if !d.SkipNull() {
+				gensupport.Int(d, &v.Op)
+			}
+		case 1:
+			if !d.SkipNull() {
+				d.String(&v.Type)
+			}
+		}
+	}
+}
+
+// MarshalCBOR fulfills the drisl.Marshaler interface.
+func (v Commit) MarshalCBOR() ([]byte, error) {
+	return v.appendDRISL(nil)
+}
+
+func (v Commit) appendDRISL(b []byte) ([]byte, error) {
+	var err error
+	n := 12
+	if !(v.PrevData != nil) {
+		n--
+	}
+	b = gensupport.AppendMapHead(b, n)
+	b = gensupport.AppendString(b, "ops")
+	if v.Ops == nil {
+		b = gensupport.AppendNull(b)
+	} else {
+		b = gensupport.AppendArrayHead(b, len(v.Ops))
+		for _, e1 := range v.Ops {
+			if b, err = e1.appendDRISL(b); err != nil {
+				return nil, err
+			}
+		}
+	}
+	b = gensupport.AppendString(b, "rev")
+	b = gensupport.AppendString(b, v.Rev)
+	b = gensupport.AppendString(b, "seq")
+	b = gensupport.AppendInt(b, int64(v.Seq))
+	b = gensupport.AppendString(b, "repo")
+	b = gensupport.AppendString(b, v.Repo)
+	b = gensupport.AppendString(b, "time")
+	b = gensupport.AppendString(b, v.Time)
+	b = gensupport.AppendString(b, "blobs")
+	if v.Blobs == nil {
+		b = gensupport.AppendNull(b)
+	} else {
+		b = gensupport.AppendArrayHead(b, len(v.Blobs))
+		for _, e2 := range v.Blobs {
+			if b, err = gensupport.AppendCid(b, e2); err != nil {
+				return nil, err
+			}
+		}
+	}
+	b = gensupport.AppendString(b, "since")
+	if v.Since == nil {
+		b = gensupport.AppendNull(b)
+	} else {
+		b = gensupport.AppendString(b, (*v.Since))
+	}
+	b = gensupport.AppendString(b, "blocks")
+	b = gensupport.AppendBytes(b, v.Blocks)
+	b = gensupport.AppendString(b, "commit")
+	if b, err = gensupport.AppendCid(b, v.Commit); err != nil {
+		return nil, err
+	}
+	b = gensupport.AppendString(b, "rebase")
+	b = gensupport.AppendBool(b, v.Rebase)
+	b = gensupport.AppendString(b, "tooBig")
+	b = gensupport.AppendBool(b, v.TooBig)
+	if v.PrevData != nil {
+		b = gensupport.AppendString(b, "prevData")
+		if v.PrevData == nil {
+			b = gensupport.AppendNull(b)
+		} else {
+			if b, err = gensupport.AppendCid(b, (*v.PrevData)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b, nil
+}
+
+// UnmarshalCBOR fulfills the drisl.Unmarshaler interface.
+func (v *Commit) UnmarshalCBOR(data []byte) error {
+	d, err := gensupport.NewDecoder(data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+// UnmarshalDRISL fulfills the drisl.ModeUnmarshaler interface.
+func (v *Commit) UnmarshalDRISL(dm drisl.DecMode, data []byte) error {
+	d, err := gensupport.NewDecoderMode(dm, data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+var drislFieldsCommit = []string{"seq", "rebase", "tooBig", "repo", "commit", "rev", "since", "blocks", "ops", "blobs", "prevData", "time"}
+
+func (v *Commit) decodeDRISL(d *gensupport.Decoder) {
+	if d.SkipNull() {
+		return
+	}
+	n, err := d.MapHead("firehose.Commit")
+	if err != nil {
+		return
+	}
+	var seen [12]bool
+	for i := range n {
+		f, err := d.Field("firehose.Commit", drislFieldsCommit, seen[:])
+		if err != nil {
+			d.SkipPairs(n - i - 1)
+			return
+		}
+		switch f {
+		case 0:
+			if !d.SkipNull() {
+				gensupport.Int(d, &v.Seq)
+			}
+		case 1:
+			if !d.SkipNull() {
+				d.Bool(&v.Rebase)
+			}
+		case 2:
+			if !d.SkipNull() {
+				d.Bool(&v.TooBig)
+			}
+		case 3:
+			if !d.SkipNull() {
+				d.String(&v.Repo)
+			}
+		case 4:
+			d.Cid(&v.Commit)
+		case 5:
+			if !d.SkipNull() {
+				d.String(&v.Rev)
+			}
+		case 6:
+			if d.SkipNull() {
+				v.Since = nil
+			} else {
+				if v.Since == nil {
+					v.Since = new(string)
+				}
+				if !d.SkipNull() {
+					d.String(&(*v.Since))
+				}
+			}
+		case 7:
+			if d.SkipNull() {
+				v.Blocks = nil
+			} else {
+				d.Bytes(&v.Blocks)
+			}
+		case 8:
+			if d.SkipNull() {
+				v.Ops = nil
+			} else if n3, err := d.ArrayHead("[]RepoOp"); err == nil {
+				if n3 == 0 || cap(v.Ops) < n3 {
+					v.Ops = make([]RepoOp, n3)
+				} else {
+					v.Ops = v.Ops[:n3]
+				}
+				for i4 := range v.Ops {
+					v.Ops[i4].decodeDRISL(d)
+				}
+			}
+		case 9:
+			if d.SkipNull() {
+				v.Blobs = nil
+			} else if n5, err := d.ArrayHead("[]cid.Cid"); err == nil {
+				if n5 == 0 || cap(v.Blobs) < n5 {
+					v.Blobs = make([]cid.Cid, n5)
+				} else {
+					v.Blobs = v.Blobs[:n5]
+				}
+				for i6 := range v.Blobs {
+					d.Cid(&v.Blobs[i6])
+				}
+			}
+		case 10:
+			if d.SkipNull() {
+				v.PrevData = nil
+			} else {
+				if v.PrevData == nil {
+					v.PrevData = new(cid.Cid)
+				}
+				d.Cid(&(*v.PrevData))
+			}
+		case 11:
+			if !d.SkipNull() {
+				d.String(&v.Time)
+			}
+		}
+	}
+}
+
+// MarshalCBOR fulfills the drisl.Marshaler interface.
+func (v RepoOp) MarshalCBOR() ([]byte, error) {
+	return v.appendDRISL(nil)
+}
+
+func (v RepoOp) appendDRISL(b []byte) ([]byte, error) {
+	var err error
+	n := 4
+	if !(v.Prev != nil) {
+		n--
+	}
+	b = gensupport.AppendMapHead(b, n)
+	b = gensupport.AppendString(b, "cid")
+	if v.Cid == nil {
+		b = gensupport.AppendNull(b)
+	} else {
+		if b, err = gensupport.AppendCid(b, (*v.Cid)); err != nil {
+			return nil, err
+		}
+	}
+	b = gensupport.AppendString(b, "path")
+	b = gensupport.AppendString(b, v.Path)
+	if v.Prev != nil {
+		b = gensupport.AppendString(b, "prev")
+		if v.Prev == nil {
+			b = gensupport.AppendNull(b)
+		} else {
+			if b, err = gensupport.AppendCid(b, (*v.Prev)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	b = gensupport.AppendString(b, "action")
+	b = gensupport.AppendString(b, v.Action)
+	return b, nil
+}
+
+// UnmarshalCBOR fulfills the drisl.Unmarshaler interface.
+func (v *RepoOp) UnmarshalCBOR(data []byte) error {
+	d, err := gensupport.NewDecoder(data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+// UnmarshalDRISL fulfills the drisl.ModeUnmarshaler interface.
+func (v *RepoOp) UnmarshalDRISL(dm drisl.DecMode, data []byte) error {
+	d, err := gensupport.NewDecoderMode(dm, data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+var drislFieldsRepoOp = []string{"action", "path", "cid", "prev"}
+
+func (v *RepoOp) decodeDRISL(d *gensupport.Decoder) {
+	if d.SkipNull() {
+		return
+	}
+	n, err := d.MapHead("firehose.RepoOp")
+	if err != nil {
+		return
+	}
+	var seen [4]bool
+	for i := range n {
+		f, err := d.Field("firehose.RepoOp", drislFieldsRepoOp, seen[:])
+		if err != nil {
+			d.SkipPairs(n - i - 1)
+			return
+		}
+		switch f {
+		case 0:
+			if !d.SkipNull() {
+				d.String(&v.Action)
+			}
+		case 1:
+			if !d.SkipNull() {
+				d.String(&v.Path)
+			}
+		case 2:
+			if d.SkipNull() {
+				v.Cid = nil
+			} else {
+				if v.Cid == nil {
+					v.Cid = new(cid.Cid)
+				}
+				d.Cid(&(*v.Cid))
+			}
+		case 3:
+			if d.SkipNull() {
+				v.Prev = nil
+			} else {
+				if v.Prev == nil {
+					v.Prev = new(cid.Cid)
+				}
+				d.Cid(&(*v.Prev))
+			}
+		}
+	}
+}
+
+// MarshalCBOR fulfills the drisl.Marshaler interface.
+func (v Identity) MarshalCBOR() ([]byte, error) {
+	return v.appendDRISL(nil)
+}
+
+func (v Identity) appendDRISL(b []byte) ([]byte, error) {
+	n := 4
+	if !(v.Handle != "") {
+		n--
+	}
+	b = gensupport.AppendMapHead(b, n)
+	b = gensupport.AppendString(b, "did")
+	b = gensupport.AppendString(b, v.Did)
+	b = gensupport.AppendString(b, "seq")
+	b = gensupport.AppendInt(b, int64(v.Seq))
+	b = gensupport.AppendString(b, "time")
+	b = gensupport.AppendString(b, v.Time)
+	if v.Handle != "" {
+		b = gensupport.AppendString(b, "handle")
+		b = gensupport.AppendString(b, v.Handle)
+	}
+	return b, nil
+}
+
+// UnmarshalCBOR fulfills the drisl.Unmarshaler interface.
+func (v *Identity) UnmarshalCBOR(data []byte) error {
+	d, err := gensupport.NewDecoder(data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+// UnmarshalDRISL fulfills the drisl.ModeUnmarshaler interface.
+func (v *Identity) UnmarshalDRISL(dm drisl.DecMode, data []byte) error {
+	d, err := gensupport.NewDecoderMode(dm, data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+var drislFieldsIdentity = []string{"seq", "did", "time", "handle"}
+
+func (v *Identity) decodeDRISL(d *gensupport.Decoder) {
+	if d.SkipNull() {
+		return
+	}
+	n, err := d.MapHead("firehose.Identity")
+	if err != nil {
+		return
+	}
+	var seen [4]bool
+	for i := range n {
+		f, err := d.Field("firehose.Identity", drislFieldsIdentity, seen[:])
+		if err != nil {
+			d.SkipPairs(n - i - 1)
+			return
+		}
+		switch f {
+		case 0:
+			if !d.SkipNull() {
+				gensupport.Int(d, &v.Seq)
+			}
+		case 1:
+			if !d.SkipNull() {
+				d.String(&v.Did)
+			}
+		case 2:
+			if !d.SkipNull() {
+				d.String(&v.Time)
+			}
+		case 3:
+			if !d.SkipNull() {
+				d.String(&v.Handle)
+			}
+		}
+	}
+}
+
+// MarshalCBOR fulfills the drisl.Marshaler interface.
+func (v Account) MarshalCBOR() ([]byte, error) {
+	return v.appendDRISL(nil)
+}
+
+func (v Account) appendDRISL(b []byte) ([]byte, error) {
+	n := 5
+	if !(v.Status != "") {
+		n--
+	}
+	b = gensupport.AppendMapHead(b, n)
+	b = gensupport.AppendString(b, "did")
+	b = gensupport.AppendString(b, v.Did)
+	b = gensupport.AppendString(b, "seq")
+	b = gensupport.AppendInt(b, int64(v.Seq))
+	b = gensupport.AppendString(b, "time")
+	b = gensupport.AppendString(b, v.Time)
+	b = gensupport.AppendString(b, "active")
+	b = gensupport.AppendBool(b, v.Active)
+	if v.Status != "" {
+		b = gensupport.AppendString(b, "status")
+		b = gensupport.AppendString(b, v.Status)
+	}
+	return b, nil
+}
+
+// UnmarshalCBOR fulfills the drisl.Unmarshaler interface.
+func (v *Account) UnmarshalCBOR(data []byte) error {
+	d, err := gensupport.NewDecoder(data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+// UnmarshalDRISL fulfills the drisl.ModeUnmarshaler interface.
+func (v *Account) UnmarshalDRISL(dm drisl.DecMode, data []byte) error {
+	d, err := gensupport.NewDecoderMode(dm, data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+var drislFieldsAccount = []string{"seq", "did", "time", "active", "status"}
+
+func (v *Account) decodeDRISL(d *gensupport.Decoder) {
+	if d.SkipNull() {
+		return
+	}
+	n, err := d.MapHead("firehose.Account")
+	if err != nil {
+		return
+	}
+	var seen [5]bool
+	for i := range n {
+		f, err := d.Field("firehose.Account", drislFieldsAccount, seen[:])
+		if err != nil {
+			d.SkipPairs(n - i - 1)
+			return
+		}
+		switch f {
+		case 0:
+			if !d.SkipNull() {
+				gensupport.Int(d, &v.Seq)
+			}
+		case 1:
+			if !d.SkipNull() {
+				d.String(&v.Did)
+			}
+		case 2:
+			if !d.SkipNull() {
+				d.String(&v.Time)
+			}
+		case 3:
+			if !d.SkipNull() {
+				d.Bool(&v.Active)
+			}
+		case 4:
+			if !d.SkipNull() {
+				d.String(&v.Status)
+			}
+		}
+	}
+}
+
+// MarshalCBOR fulfills the drisl.Marshaler interface.
+func (v Sync) MarshalCBOR() ([]byte, error) {
+	return v.appendDRISL(nil)
+}
+
+func (v Sync) appendDRISL(b []byte) ([]byte, error) {
+	b = gensupport.AppendMapHead(b, 5)
+	b = gensupport.AppendString(b, "did")
+	b = gensupport.AppendString(b, v.Did)
+	b = gensupport.AppendString(b, "rev")
+	b = gensupport.AppendString(b, v.Rev)
+	b = gensupport.AppendString(b, "seq")
+	b = gensupport.AppendInt(b, int64(v.Seq))
+	b = gensupport.AppendString(b, "time")
+	b = gensupport.AppendString(b, v.Time)
+	b = gensupport.AppendString(b, "blocks")
+	b = gensupport.AppendBytes(b, v.Blocks)
+	return b, nil
+}
+
+// UnmarshalCBOR fulfills the drisl.Unmarshaler interface.
+func (v *Sync) UnmarshalCBOR(data []byte) error {
+	d, err := gensupport.NewDecoder(data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+// UnmarshalDRISL fulfills the drisl.ModeUnmarshaler interface.
+func (v *Sync) UnmarshalDRISL(dm drisl.DecMode, data []byte) error {
+	d, err := gensupport.NewDecoderMode(dm, data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+var drislFieldsSync = []string{"seq", "did", "blocks", "rev", "time"}
+
+func (v *Sync) decodeDRISL(d *gensupport.Decoder) {
+	if d.SkipNull() {
+		return
+	}
+	n, err := d.MapHead("firehose.Sync")
+	if err != nil {
+		return
+	}
+	var seen [5]bool
+	for i := range n {
+		f, err := d.Field("firehose.Sync", drislFieldsSync, seen[:])
+		if err != nil {
+			d.SkipPairs(n - i - 1)
+			return
+		}
+		switch f {
+		case 0:
+			if !d.SkipNull() {
+				gensupport.Int(d, &v.Seq)
+			}
+		case 1:
+			if !d.SkipNull() {
+				d.String(&v.Did)
+			}
+		case 2:
+			if d.SkipNull() {
+				v.Blocks = nil
+			} else {
+				d.Bytes(&v.Blocks)
+			}
+		case 3:
+			if !d.SkipNull() {
+				d.String(&v.Rev)
+			}
+		case 4:
+			if !d.SkipNull() {
+				d.String(&v.Time)
+			}
+		}
+	}
+}
+
+// MarshalCBOR fulfills the drisl.Marshaler interface.
+func (v Info) MarshalCBOR() ([]byte, error) {
+	return v.appendDRISL(nil)
+}
+
+func (v Info) appendDRISL(b []byte) ([]byte, error) {
+	n := 2
+	if !(v.Message != "") {
+		n--
+	}
+	b = gensupport.AppendMapHead(b, n)
+	b = gensupport.AppendString(b, "name")
+	b = gensupport.AppendString(b, v.Name)
+	if v.Message != "" {
+		b = gensupport.AppendString(b, "message")
+		b = gensupport.AppendString(b, v.Message)
+	}
+	return b, nil
+}
+
+// UnmarshalCBOR fulfills the drisl.Unmarshaler interface.
+func (v *Info) UnmarshalCBOR(data []byte) error {
+	d, err := gensupport.NewDecoder(data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+// UnmarshalDRISL fulfills the drisl.ModeUnmarshaler interface.
+func (v *Info) UnmarshalDRISL(dm drisl.DecMode, data []byte) error {
+	d, err := gensupport.NewDecoderMode(dm, data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+var drislFieldsInfo = []string{"name", "message"}
+
+func (v *Info) decodeDRISL(d *gensupport.Decoder) {
+	if d.SkipNull() {
+		return
+	}
+	n, err := d.MapHead("firehose.Info")
+	if err != nil {
+		return
+	}
+	var seen [2]bool
+	for i := range n {
+		f, err := d.Field("firehose.Info", drislFieldsInfo, seen[:])
+		if err != nil {
+			d.SkipPairs(n - i - 1)
+			return
+		}
+		switch f {
+		case 0:
+			if !d.SkipNull() {
+				d.String(&v.Name)
+			}
+		case 1:
+			if !d.SkipNull() {
+				d.String(&v.Message)
+			}
+		}
+	}
+}
+
+// MarshalCBOR fulfills the drisl.Marshaler interface.
+func (v StreamError) MarshalCBOR() ([]byte, error) {
+	return v.appendDRISL(nil)
+}
+
+func (v StreamError) appendDRISL(b []byte) ([]byte, error) {
+	n := 2
+	if !(v.Message != "") {
+		n--
+	}
+	b = gensupport.AppendMapHead(b, n)
+	b = gensupport.AppendString(b, "error")
+	b = gensupport.AppendString(b, v.Name)
+	if v.Message != "" {
+		b = gensupport.AppendString(b, "message")
+		b = gensupport.AppendString(b, v.Message)
+	}
+	return b, nil
+}
+
+// UnmarshalCBOR fulfills the drisl.Unmarshaler interface.
+func (v *StreamError) UnmarshalCBOR(data []byte) error {
+	d, err := gensupport.NewDecoder(data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+// UnmarshalDRISL fulfills the drisl.ModeUnmarshaler interface.
+func (v *StreamError) UnmarshalDRISL(dm drisl.DecMode, data []byte) error {
+	d, err := gensupport.NewDecoderMode(dm, data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+var drislFieldsStreamError = []string{"error", "message"}
+
+func (v *StreamError) decodeDRISL(d *gensupport.Decoder) {
+	if d.SkipNull() {
+		return
+	}
+	n, err := d.MapHead("firehose.StreamError")
+	if err != nil {
+		return
+	}
+	var seen [2]bool
+	for i := range n {
+		f, err := d.Field("firehose.StreamError", drislFieldsStreamError, seen[:])
+		if err != nil {
+			d.SkipPairs(n - i - 1)
+			return
+		}
+		switch f {
+		case 0:
+			if !d.SkipNull() {
+				d.String(&v.Name)
+			}
+		case 1:
+			if !d.SkipNull() {
+				d.String(&v.Message)
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package mst
+
+import (
+	"github.com/hyphacoop/go-dasl/cid"
+)
+
+// Change is a difference between two trees for a key.
+// From is undefined if the key was created, and To is undefined if it was deleted.
+type Change struct {
+	Key  string
+	From cid.Cid
+	To   cid.Cid
+}
+
+// Diff returns the changes to go from one tree to another, in key order.
+// Subtrees that are the same in both trees are skipped without being loaded.
+func Diff(from, to *Tree) ([]Change, error) {
+	fromRoot, err := from.Root()
+	if err != nil {
+		return nil, err
+	}
+	toRoot, err := to.Root()
+	if err != nil {
+		return nil, err
+	}
+	if fromRoot == toRoot {
+		return nil, nil
+	}
+
+	a := &walker{t: from}
+	b := &walker{t: to}
+	if err := a.push(from.root); err != nil {
+		return nil, err
+	}
+	if err := b.push(to.root); err != nil {
+		return nil, err
+	}
+	var changes []Change
+	for {
+		ia, aok := a.head()
+		ib, bok := b.head()
+		switch {
+		case !aok && !bok:
+			return changes, nil
+
+		case aok && bok && ia.sub != nil && ib.sub != nil && ia.sub.cid == ib.sub.cid:
+			a.pop()
+			b.pop()
+
+		// Subtrees are expanded until both sides are at an entry, starting with the
+		// highest one so that identical subtrees line up
+		case aok && ia.sub != nil && (!bok || ib.sub == nil || ia.sub.layer >= ib.sub.layer):
+			if err := a.expand(); err != nil {
+				return nil, err
+			}
+		case bok && ib.sub != nil:
+			if err := b.expand(); err != nil {
+				return nil, err
+			}
+
+		case !bok || (aok && ia.entry.key < ib.entry.key):
+			changes = append(changes, Change{Key: ia.entry.key, From: ia.entry.value})
+			a.pop()
+		case !aok || ib.entry.key < ia.entry.key:
+			changes = append(changes, Change{Key: ib.entry.key, To: ib.entry.value})
+			b.pop()
+		default:
+			if ia.entry.value != ib.entry.value {
+				changes = append(changes, Change{Key: ia.entry.key, From: ia.entry.value, To: ib.entry.value})
+			}
+			a.pop()
+			b.pop()
+		}
+	}
+}
+
+// walkItem is either an entry or a subtree.
+type walkItem struct {
+	entry entry
+	sub   *node
+}
+
+// walker goes through the items of a tree in key order, expanding subtrees on demand.
+type walker struct {
+	t     *Tree
+	stack [][]walkItem
+}
+
+// push adds the items of a node to the top of the stack.
+func (w *walker) push(n *node) error {
+	if err := w.t.load(n); err != nil {
+		return err
+	}
+	items := make([]walkItem, 0, 2*len(n.entries)+1)
+	if n.left != nil {
+		items = append(items, walkItem{sub: n.left})
+	}
+	for _, e := range n.entries {
+		items = append(items, walkItem{entry: e})
+		if e.right != nil {
+			items = append(items, walkItem{sub: e.right})
+		}
+	}
+	w.stack = append(w.stack, items)
+	return nil
+}
+
+// head returns the next item, or false if there are none left.
+func (w *walker) head() (walkItem, bool) {
+	for len(w.stack) > 0 {
+		if top := w.stack[len(w.stack)-1]; len(top) > 0 {
+			return top[0], true
+		}
+		w.stack = w.stack[:len(w.stack)-1]
+	}
+	return walkItem{}, false
+}
+
+// pop skips the next item.
+func (w *walker) pop() {
+	top := len(w.stack) - 1
+	w.stack[top] = w.stack[top][1:]
+}
+
+// expand replaces the next item, which must be a subtree, with its contents.
+func (w *walker) expand() error {
+	item, _ := w.head()
+	w.pop()
+	return w.push(item.sub)
+}
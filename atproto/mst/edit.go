@@ -0,0 +1,157 @@
+package mst
+
+import (
+	"slices"
+
+	"github.com/hyphacoop/go-dasl/cid"
+)
+
+// put returns a copy of the loaded node n with the key set, where layer is the layer of the key.
+func (t *Tree) put(n *node, key string, value cid.Cid, layer int) (*node, error) {
+	if layer > n.layer {
+		// The key goes above n, which is split around it
+		left, right, err := t.split(n, key)
+		if err != nil {
+			return nil, err
+		}
+		return &node{
+			loaded:  true,
+			layer:   layer,
+			left:    wrap(left, n.layer, layer-1),
+			entries: []entry{{key: key, value: value, right: wrap(right, n.layer, layer-1)}},
+		}, nil
+	}
+
+	i, found := n.search(key)
+	c := n.copy()
+	if found {
+		c.entries[i].value = value
+		return c, nil
+	}
+	if layer == n.layer {
+		// The key goes in n, and the subtree where it would be is split around it
+		left, right, err := t.split(n.child(i), key)
+		if err != nil {
+			return nil, err
+		}
+		c.entries = slices.Insert(c.entries, i, entry{key: key, value: value, right: right})
+		c.setChild(i, left)
+		return c, nil
+	}
+
+	// The key goes below n
+	child := n.child(i)
+	if child == nil {
+		child = &node{loaded: true, layer: n.layer - 1}
+	} else if err := t.load(child); err != nil {
+		return nil, err
+	}
+	child, err := t.put(child, key, value, layer)
+	if err != nil {
+		return nil, err
+	}
+	c.setChild(i, child)
+	return c, nil
+}
+
+// split returns the parts of the subtree n with keys lower and higher than key,
+// which is not in n. Both parts are in the same layer as n, and nil if they are empty.
+func (t *Tree) split(n *node, key string) (*node, *node, error) {
+	if n == nil {
+		return nil, nil, nil
+	}
+	if err := t.load(n); err != nil {
+		return nil, nil, err
+	}
+	i, _ := n.search(key)
+	childLeft, childRight, err := t.split(n.child(i), key)
+	if err != nil {
+		return nil, nil, err
+	}
+	left := &node{loaded: true, layer: n.layer, left: n.left, entries: slices.Clone(n.entries[:i])}
+	left.setChild(i, childLeft)
+	right := &node{loaded: true, layer: n.layer, left: childRight, entries: slices.Clone(n.entries[i:])}
+	return left.orNil(), right.orNil(), nil
+}
+
+// del returns a copy of the subtree n without the key, or nil if it is empty.
+func (t *Tree) del(n *node, key string) (*node, error) {
+	if n == nil {
+		return nil, ErrNotFound
+	}
+	if err := t.load(n); err != nil {
+		return nil, err
+	}
+	i, found := n.search(key)
+	c := n.copy()
+	if found {
+		// The subtrees on both sides of the entry are joined
+		merged, err := t.merge(n.child(i), n.entries[i].right)
+		if err != nil {
+			return nil, err
+		}
+		c.entries = slices.Delete(c.entries, i, i+1)
+		c.setChild(i, merged)
+		return c.orNil(), nil
+	}
+	child, err := t.del(n.child(i), key)
+	if err != nil {
+		return nil, err
+	}
+	c.setChild(i, child)
+	return c.orNil(), nil
+}
+
+// merge joins two subtrees in the same layer, where all the keys in a are lower
+// than the keys in b.
+func (t *Tree) merge(a, b *node) (*node, error) {
+	if a == nil {
+		return b, nil
+	}
+	if b == nil {
+		return a, nil
+	}
+	if err := t.load(a); err != nil {
+		return nil, err
+	}
+	if err := t.load(b); err != nil {
+		return nil, err
+	}
+	// The last subtree of a and the first subtree of b are next to each other
+	middle, err := t.merge(a.child(len(a.entries)), b.left)
+	if err != nil {
+		return nil, err
+	}
+	c := &node{
+		loaded:  true,
+		layer:   a.layer,
+		left:    a.left,
+		entries: slices.Concat(a.entries, b.entries),
+	}
+	c.setChild(len(a.entries), middle)
+	return c, nil
+}
+
+// copy returns a new node with the same contents, which can be modified.
+func (n *node) copy() *node {
+	return &node{loaded: true, layer: n.layer, left: n.left, entries: slices.Clone(n.entries)}
+}
+
+// orNil returns nil for nodes with no entries and no subtree.
+func (n *node) orNil() *node {
+	if len(n.entries) == 0 && n.left == nil {
+		return nil
+	}
+	return n
+}
+
+// wrap adds nodes without entries above n, to move it from one layer up to another.
+func wrap(n *node, from, to int) *node {
+	if n == nil {
+		return nil
+	}
+	for layer := from + 1; layer <= to; layer++ {
+		n = &node{loaded: true, layer: layer, left: n}
+	}
+	return n
+}
@@ -0,0 +1,313 @@
+/*
+Package mst implements the Merkle Search Tree (MST) used by ATProto repositories.
+
+An MST is a sorted map from record paths, like "app.bsky.feed.post/3jqfcqzm3fo2j",
+to the CIDs of the records. It is stored as a tree of DRISL nodes linked by CIDs.
+The shape of the tree only depends on its keys, so two trees with the same contents
+always have the same root CID, and unchanged subtrees can be skipped when comparing them.
+
+Nodes are loaded from a BlockGetter as they are needed. Changes to a Tree never modify
+the nodes it was loaded from: new nodes are created instead, and can be retrieved with
+NewBlocks to be stored or sent along with a commit.
+
+https://atproto.com/specs/repository#mst-structure
+*/
+package mst
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"iter"
+	"math/bits"
+	"sort"
+
+	"github.com/hyphacoop/go-dasl/cid"
+)
+
+//go:generate go run ../../cmd/drislgen -type nodeData,entryData -output node_drisl.go
+
+// MaxKeyLength is the maximum length of a key in bytes.
+const MaxKeyLength = 1024
+
+var (
+	// ErrNotFound is returned when a key is not in the tree.
+	ErrNotFound = errors.New("go-dasl/mst: key not found")
+
+	// ErrInvalidKey is returned when a key is not a valid record path.
+	ErrInvalidKey = errors.New("go-dasl/mst: invalid key")
+
+	// ErrInvalidNode is returned when a node loaded from the BlockGetter
+	// is not a valid MST node. It is wrapped with more details.
+	ErrInvalidNode = errors.New("go-dasl/mst: invalid node")
+)
+
+// NodeError is returned when a node of the tree cannot be loaded,
+// because it is missing from the BlockGetter or is invalid.
+type NodeError struct {
+	Cid cid.Cid
+	Err error
+}
+
+func (e *NodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// BlockGetter returns the data of blocks by their CID.
+// Any blockstore.Blockstore can be used.
+type BlockGetter interface {
+	Get(c cid.Cid) ([]byte, error)
+}
+
+// Entry is a key and value in the tree.
+type Entry struct {
+	Key   string
+	Value cid.Cid
+}
+
+// Tree is a Merkle Search Tree.
+//
+// A Tree is not safe for concurrent use.
+type Tree struct {
+	bs   BlockGetter
+	root *node
+}
+
+// New returns an empty tree. Nodes are not loaded from bs unless the tree is
+// compared with one that was loaded from it, so it can be nil.
+func New(bs BlockGetter) *Tree {
+	return &Tree{bs: bs, root: emptyNode()}
+}
+
+// Load returns the tree with the given root node.
+// The root node is loaded right away, and other nodes as they are needed.
+func Load(bs BlockGetter, root cid.Cid) (*Tree, error) {
+	t := &Tree{bs: bs, root: &node{cid: root, layer: -1}}
+	if err := t.load(t.root); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Layer returns the layer of the tree a key belongs to, which is the number of
+// leading zero bits of its SHA-256 hash divided by two.
+func Layer(key string) int {
+	digest := sha256.Sum256([]byte(key))
+	zeros := 0
+	for _, b := range digest {
+		zeros += bits.LeadingZeros8(b)
+		if b != 0 {
+			break
+		}
+	}
+	return zeros / 2
+}
+
+// ValidKey reports whether a key is a valid record path: a collection and a record key
+// separated by a slash, using only ASCII letters and digits and the characters "_~-:.".
+func ValidKey(key string) bool {
+	if len(key) == 0 || len(key) > MaxKeyLength {
+		return false
+	}
+	slash := -1
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c == '/':
+			if slash != -1 {
+				return false
+			}
+			slash = i
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '_', c == '~', c == '-', c == ':', c == '.':
+		default:
+			return false
+		}
+	}
+	return slash > 0 && slash < len(key)-1
+}
+
+// Get returns the value of a key, or ErrNotFound.
+func (t *Tree) Get(key string) (cid.Cid, error) {
+	n := t.root
+	for n != nil {
+		if err := t.load(n); err != nil {
+			return cid.Cid{}, err
+		}
+		i, found := n.search(key)
+		if found {
+			return n.entries[i].value, nil
+		}
+		n = n.child(i)
+	}
+	return cid.Cid{}, ErrNotFound
+}
+
+// All returns an iterator over every entry of the tree, in key order.
+// Iteration stops at the first error loading a node.
+func (t *Tree) All() iter.Seq2[Entry, error] {
+	return t.List("")
+}
+
+// List returns an iterator over the entries whose key starts with prefix, in key order.
+// For example, the prefix "app.bsky.feed.post/" lists all the posts in a repository.
+// Iteration stops at the first error loading a node.
+func (t *Tree) List(prefix string) iter.Seq2[Entry, error] {
+	return func(yield func(Entry, error) bool) {
+		t.walk(t.root, prefix, func(e Entry) bool {
+			if len(e.Key) < len(prefix) || e.Key[:len(prefix)] != prefix {
+				return false
+			}
+			return yield(e, nil)
+		}, func(err error) {
+			yield(Entry{}, err)
+		})
+	}
+}
+
+// walk calls fn for the entries under n with keys greater than or equal to from,
+// until it returns false. It returns false if the walk was stopped.
+func (t *Tree) walk(n *node, from string, fn func(Entry) bool, onErr func(error)) bool {
+	if n == nil {
+		return true
+	}
+	if err := t.load(n); err != nil {
+		onErr(err)
+		return false
+	}
+	i, _ := n.search(from)
+	if !t.walk(n.child(i), from, fn, onErr) {
+		return false
+	}
+	for _, e := range n.entries[i:] {
+		if !fn(Entry{e.key, e.value}) {
+			return false
+		}
+		if !t.walk(e.right, from, fn, onErr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Scan calls fn for every entry of the tree, in key order. Unlike All, it doesn't stop
+// at nodes that cannot be loaded: their subtrees are skipped, and the errors are returned.
+// This is useful to check that all the nodes of a tree are available.
+func (t *Tree) Scan(fn func(Entry)) []*NodeError {
+	return t.scan(t.root, fn, nil)
+}
+
+func (t *Tree) scan(n *node, fn func(Entry), errs []*NodeError) []*NodeError {
+	if n == nil {
+		return errs
+	}
+	if err := t.load(n); err != nil {
+		return append(errs, err.(*NodeError))
+	}
+	errs = t.scan(n.left, fn, errs)
+	for _, e := range n.entries {
+		fn(Entry{e.key, e.value})
+		errs = t.scan(e.right, fn, errs)
+	}
+	return errs
+}
+
+// Put sets the value of a key, inserting it if it is not in the tree.
+func (t *Tree) Put(key string, value cid.Cid) error {
+	if !ValidKey(key) {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	if !value.Defined() {
+		return cid.ErrUndefinedCid
+	}
+	if err := t.load(t.root); err != nil {
+		return err
+	}
+	root, err := t.put(t.root, key, value, Layer(key))
+	if err != nil {
+		return err
+	}
+	t.root = root
+	return nil
+}
+
+// Delete removes a key from the tree, or returns ErrNotFound.
+func (t *Tree) Delete(key string) error {
+	root, err := t.del(t.root, key)
+	if err != nil {
+		return err
+	}
+	// The root is at the layer of the highest key, so nodes without entries are removed
+	for root != nil && len(root.entries) == 0 && root.left != nil {
+		root = root.left
+		if err := t.load(root); err != nil {
+			return err
+		}
+	}
+	if root == nil {
+		root = emptyNode()
+	}
+	t.root = root
+	return nil
+}
+
+// Root returns the CID of the root node, encoding the nodes that changed.
+func (t *Tree) Root() (cid.Cid, error) {
+	if err := t.root.encode(); err != nil {
+		return cid.Cid{}, err
+	}
+	return t.root.cid, nil
+}
+
+// NewBlocks returns the encoded nodes of the tree that were not loaded from the
+// BlockGetter, by CID. After a change, these are the blocks needed to go from
+// the previous root to the new one.
+func (t *Tree) NewBlocks() (map[cid.Cid][]byte, error) {
+	if err := t.root.encode(); err != nil {
+		return nil, err
+	}
+	blocks := make(map[cid.Cid][]byte)
+	var collect func(n *node)
+	collect = func(n *node) {
+		// Children of loaded nodes are never created in memory
+		if n == nil || n.data == nil {
+			return
+		}
+		blocks[n.cid] = n.data
+		collect(n.left)
+		for _, e := range n.entries {
+			collect(e.right)
+		}
+	}
+	collect(t.root)
+	return blocks, nil
+}
+
+// search returns the index of the first entry with a key greater than or equal to key,
+// and whether that key is equal.
+func (n *node) search(key string) (int, bool) {
+	i := sort.Search(len(n.entries), func(i int) bool { return n.entries[i].key >= key })
+	return i, i < len(n.entries) && n.entries[i].key == key
+}
+
+// child returns the subtree before the entry at index i, which is the right subtree
+// of the previous entry, or the left subtree for the first entry.
+func (n *node) child(i int) *node {
+	if i == 0 {
+		return n.left
+	}
+	return n.entries[i-1].right
+}
+
+// setChild is the counterpart of child. It must only be used on new nodes.
+func (n *node) setChild(i int, c *node) {
+	if i == 0 {
+		n.left = c
+	} else {
+		n.entries[i-1].right = c
+	}
+}
@@ -0,0 +1,374 @@
+package mst_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/hyphacoop/go-dasl/atproto/mst"
+	"github.com/hyphacoop/go-dasl/blockstore"
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+	"pgregory.net/rapid"
+)
+
+// The value used for every key in the known roots
+var testValue = cid.MustNewCidFromString("bafyreie5cvv4h45feadgeuwhbcutmh6t2ceseocckahdoe6uat64zmz454")
+
+func readJSON(t *testing.T, name string, v any) {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// store writes the new blocks of a tree to bs, and returns the tree loaded from it.
+func store(t interface{ Fatal(...any) }, tree *mst.Tree, bs *blockstore.Memory) *mst.Tree {
+	blocks, err := tree.NewBlocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for c, data := range blocks {
+		if err := bs.Put(c, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root, err := tree.Root()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := mst.Load(bs, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loaded
+}
+
+func TestLayer(t *testing.T) {
+	var vectors []struct {
+		Key    string `json:"key"`
+		Height int    `json:"height"`
+	}
+	readJSON(t, "testdata/key_heights.json", &vectors)
+	for _, v := range vectors {
+		if got := mst.Layer(v.Key); got != v.Height {
+			t.Fatalf("Layer(%q) = %d, want %d", v.Key, got, v.Height)
+		}
+	}
+}
+
+func TestKnownRoots(t *testing.T) {
+	var vectors []struct {
+		Name string   `json:"name"`
+		Keys []string `json:"keys"`
+		Root string   `json:"root"`
+	}
+	readJSON(t, "testdata/known_roots.json", &vectors)
+	for _, v := range vectors {
+		want := cid.MustNewCidFromString(v.Root)
+		// The insertion order doesn't matter
+		reversed := slices.Clone(v.Keys)
+		slices.Reverse(reversed)
+		for _, keys := range [][]string{v.Keys, reversed} {
+			tree := mst.New(nil)
+			for _, k := range keys {
+				if err := tree.Put(k, testValue); err != nil {
+					t.Fatalf("%s: %v", v.Name, err)
+				}
+			}
+			got, err := tree.Root()
+			if err != nil {
+				t.Fatalf("%s: %v", v.Name, err)
+			}
+			if got != want {
+				t.Fatalf("%s: got root %s, want %s", v.Name, got, want)
+			}
+		}
+	}
+}
+
+func TestValidKey(t *testing.T) {
+	valid := []string{
+		"app.bsky.feed.post/3jqfcqzm3fo2j",
+		"com.example.record/self",
+		"a/b",
+		"coll/a_b~c-d:e.f",
+	}
+	invalid := []string{
+		"",
+		"nocollection",
+		"/rkey",
+		"coll/",
+		"a/b/c",
+		"coll/with space",
+		"coll/ünicode",
+		"coll/" + string(make([]byte, mst.MaxKeyLength)),
+	}
+	for _, k := range valid {
+		if !mst.ValidKey(k) {
+			t.Fatalf("ValidKey(%q) = false, want true", k)
+		}
+	}
+	for _, k := range invalid {
+		if mst.ValidKey(k) {
+			t.Fatalf("ValidKey(%q) = true, want false", k)
+		}
+		if err := mst.New(nil).Put(k, testValue); !errors.Is(err, mst.ErrInvalidKey) {
+			t.Fatalf("Put(%q): got %v, want ErrInvalidKey", k, err)
+		}
+	}
+}
+
+func genKey() *rapid.Generator[string] {
+	return rapid.Custom(func(t *rapid.T) string {
+		coll := rapid.SampledFrom([]string{"app.bsky.feed.post", "app.bsky.feed.like", "com.example.record"}).Draw(t, "collection")
+		return coll + "/" + rapid.StringMatching(`[a-z0-9]{1,6}`).Draw(t, "rkey")
+	})
+}
+
+func genContents() *rapid.Generator[map[string]cid.Cid] {
+	return rapid.MapOf(genKey(), rapid.Custom(func(t *rapid.T) cid.Cid {
+		return cid.HashBytes([]byte{rapid.Byte().Draw(t, "value")})
+	}))
+}
+
+func TestTree(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		contents := genContents().Draw(t, "contents")
+		keys := slices.Sorted(maps.Keys(contents))
+
+		tree := mst.New(nil)
+		for _, k := range rapid.Permutation(keys).Draw(t, "order") {
+			if err := tree.Put(k, contents[k]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		loaded := store(t, tree, blockstore.NewMemory())
+
+		for _, tr := range []*mst.Tree{tree, loaded} {
+			var got []string
+			for e, err := range tr.All() {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if e.Value != contents[e.Key] {
+					t.Fatalf("%s: got value %s, want %s", e.Key, e.Value, contents[e.Key])
+				}
+				got = append(got, e.Key)
+			}
+			if !slices.Equal(got, keys) {
+				t.Fatalf("got keys %v, want %v", got, keys)
+			}
+			for _, k := range keys {
+				if v, err := tr.Get(k); err != nil || v != contents[k] {
+					t.Fatalf("Get(%q) = %s, %v, want %s", k, v, err, contents[k])
+				}
+			}
+			if _, err := tr.Get("com.example.record/missing"); !errors.Is(err, mst.ErrNotFound) {
+				t.Fatalf("Get missing key: got %v, want ErrNotFound", err)
+			}
+
+			var likes []string
+			for e, err := range tr.List("app.bsky.feed.like/") {
+				if err != nil {
+					t.Fatal(err)
+				}
+				likes = append(likes, e.Key)
+			}
+			var wantLikes []string
+			for _, k := range keys {
+				if len(k) > 19 && k[:19] == "app.bsky.feed.like/" {
+					wantLikes = append(wantLikes, k)
+				}
+			}
+			if !slices.Equal(likes, wantLikes) {
+				t.Fatalf("List: got %v, want %v", likes, wantLikes)
+			}
+		}
+
+		// Deleting keys gives the same root as never inserting them
+		toDelete := rapid.SliceOfDistinct(rapid.SampledFrom(append(keys, "x/y")), rapid.ID).Draw(t, "delete")
+		rebuilt := mst.New(nil)
+		for _, k := range keys {
+			if !slices.Contains(toDelete, k) {
+				rebuilt.Put(k, contents[k])
+			}
+		}
+		for _, k := range toDelete {
+			err := loaded.Delete(k)
+			if _, ok := contents[k]; !ok {
+				if !errors.Is(err, mst.ErrNotFound) {
+					t.Fatalf("Delete(%q): got %v, want ErrNotFound", k, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+		}
+		got, err := loaded.Root()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := rebuilt.Root()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("after Delete: got root %s, want %s", got, want)
+		}
+	})
+}
+
+func TestNewBlocks(t *testing.T) {
+	bs := blockstore.NewMemory()
+	tree := mst.New(nil)
+	for _, k := range []string{"com.example.record/3jqfcqzm3ft2j", "com.example.record/3jqfcqzm3fz2j"} {
+		tree.Put(k, testValue)
+	}
+	loaded := store(t, tree, bs)
+	if err := loaded.Put("com.example.record/3jqfcqzm3fx2j", testValue); err != nil {
+		t.Fatal(err)
+	}
+	blocks, err := loaded.NewBlocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The new root, the two halves of the old root, and a node without entries
+	// above each half to fill the layer between them
+	if len(blocks) != 5 {
+		t.Fatalf("got %d new blocks, want 5", len(blocks))
+	}
+	for c := range blocks {
+		if has, _ := bs.Has(c); has {
+			t.Fatalf("new block %s was already stored", c)
+		}
+	}
+	root, _ := loaded.Root()
+	if want := "bafyreiavxaxdz7o7rbvr3zg2liox2yww46t7g6hkehx4i4h3lwudly7dhy"; root.String() != want {
+		t.Fatalf("got root %s, want %s", root, want)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		bs := blockstore.NewMemory()
+		before := genContents().Draw(t, "before")
+		after := maps.Clone(before)
+		for k, v := range genContents().Draw(t, "changes") {
+			if rapid.Bool().Draw(t, "delete") {
+				delete(after, k)
+			} else {
+				after[k] = v
+			}
+		}
+
+		from, to := mst.New(nil), mst.New(nil)
+		for k, v := range before {
+			from.Put(k, v)
+		}
+		for k, v := range after {
+			to.Put(k, v)
+		}
+		changes, err := mst.Diff(store(t, from, bs), store(t, to, bs))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		all := maps.Clone(before)
+		maps.Copy(all, after)
+		var want []mst.Change
+		for _, k := range slices.Sorted(maps.Keys(all)) {
+			if before[k] != after[k] {
+				want = append(want, mst.Change{Key: k, From: before[k], To: after[k]})
+			}
+		}
+		if !slices.Equal(changes, want) {
+			t.Fatalf("got %v, want %v", changes, want)
+		}
+	})
+}
+
+func TestLoadInvalid(t *testing.T) {
+	bs := blockstore.NewMemory()
+	entry := func(p int, k string, tree any) map[string]any {
+		return map[string]any{"p": p, "k": []byte(k), "v": testValue, "t": tree}
+	}
+	for _, tc := range []struct {
+		name string
+		node any
+	}{
+		{"not a node", []any{}},
+		{"missing value", map[string]any{"l": nil, "e": []any{map[string]any{"p": 0, "k": []byte("a/b")}}}},
+		{"unsorted keys", map[string]any{"l": nil, "e": []any{entry(0, "a/c", nil), entry(2, "b", nil)}}},
+		{"bad prefix", map[string]any{"l": nil, "e": []any{entry(4, "a/c", nil)}}},
+		{"subtree below layer 0", map[string]any{"l": nil, "e": []any{entry(0, "2653ae71", testValue)}}},
+		{"wrong layer", map[string]any{"l": nil, "e": []any{entry(0, "2653ae71", nil), entry(0, "blue", nil)}}},
+	} {
+		c, err := drisl.CidForValue(tc.node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := drisl.Marshal(tc.node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bs.Put(c, data); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := mst.Load(bs, c); !errors.Is(err, mst.ErrInvalidNode) {
+			t.Fatalf("%s: got %v, want ErrInvalidNode", tc.name, err)
+		}
+	}
+	if _, err := mst.Load(bs, testValue); !errors.Is(err, blockstore.ErrNotFound) {
+		t.Fatalf("missing root: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	bs := blockstore.NewMemory()
+	tree := mst.New(nil)
+	for i := range 100 {
+		tree.Put(fmt.Sprintf("com.example.record/%03d", i), testValue)
+	}
+	loaded := store(t, tree, bs)
+	root, _ := loaded.Root()
+
+	// Remove a node below the root
+	var missing cid.Cid
+	for c, err := range bs.AllKeys() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c != root {
+			missing = c
+			break
+		}
+	}
+	bs.Delete(missing)
+
+	loaded, err := mst.Load(bs, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	errs := loaded.Scan(func(e mst.Entry) {
+		keys = append(keys, e.Key)
+	})
+	if len(errs) != 1 || errs[0].Cid != missing || !errors.Is(errs[0], blockstore.ErrNotFound) {
+		t.Fatalf("got errors %v, want the missing node %s", errs, missing)
+	}
+	if len(keys) == 0 || len(keys) >= 100 || !slices.IsSorted(keys) {
+		t.Fatalf("got %d keys, want the sorted keys outside the missing node", len(keys))
+	}
+
+	if errs := store(t, tree, blockstore.NewMemory()).Scan(func(mst.Entry) {}); errs != nil {
+		t.Fatalf("got errors %v for a complete tree", errs)
+	}
+}
@@ -0,0 +1,179 @@
+package mst
+
+import (
+	"fmt"
+
+	"github.com/hyphacoop/go-dasl/cid"
+)
+
+// nodeData is the encoded form of a node.
+type nodeData struct {
+	Left    *cid.Cid    `cbor:"l"`
+	Entries []entryData `cbor:"e"`
+}
+
+// entryData is the encoded form of an entry. Keys are compressed by only storing
+// the part that is not shared with the previous key in the node.
+type entryData struct {
+	PrefixLen int      `cbor:"p"`
+	KeySuffix []byte   `cbor:"k"`
+	Value     cid.Cid  `cbor:"v"`
+	Right     *cid.Cid `cbor:"t"`
+}
+
+// node is a node of the tree. Nodes are never modified once they are part of a tree,
+// apart from being loaded or encoded.
+type node struct {
+	// cid is undefined for new nodes until they are encoded.
+	cid cid.Cid
+	// data is the encoding of nodes that were not loaded from the BlockGetter.
+	data []byte
+	// loaded is false for nodes only known by their CID.
+	loaded bool
+	// layer is -1 if it is unknown, which is only the case for a root to be loaded.
+	layer   int
+	left    *node
+	entries []entry
+}
+
+type entry struct {
+	key   string
+	value cid.Cid
+	right *node
+}
+
+func emptyNode() *node {
+	return &node{loaded: true}
+}
+
+// load loads a node from the BlockGetter if needed, checking that it is valid.
+// Errors are returned as a *NodeError for the node that could not be loaded.
+func (t *Tree) load(n *node) error {
+	if n.loaded {
+		return nil
+	}
+	err := t.loadNode(n)
+	if _, ok := err.(*NodeError); err == nil || ok {
+		// The left child of a root may have failed instead
+		return err
+	}
+	return &NodeError{Cid: n.cid, Err: err}
+}
+
+func (t *Tree) loadNode(n *node) error {
+	if t.bs == nil {
+		return fmt.Errorf("go-dasl/mst: no BlockGetter to load node %s", n.cid)
+	}
+	data, err := t.bs.Get(n.cid)
+	if err != nil {
+		return fmt.Errorf("go-dasl/mst: loading node %s: %w", n.cid, err)
+	}
+	if !n.cid.VerifyBytes(data) {
+		return fmt.Errorf("%w: %s: data does not match CID", ErrInvalidNode, n.cid)
+	}
+	var nd nodeData
+	if err := nd.UnmarshalCBOR(data); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidNode, n.cid, err)
+	}
+
+	entries := make([]entry, len(nd.Entries))
+	prev := ""
+	for i, ed := range nd.Entries {
+		if ed.PrefixLen < 0 || ed.PrefixLen > len(prev) {
+			return fmt.Errorf("%w: %s: invalid key prefix length %d", ErrInvalidNode, n.cid, ed.PrefixLen)
+		}
+		key := prev[:ed.PrefixLen] + string(ed.KeySuffix)
+		if i > 0 && key <= prev {
+			return fmt.Errorf("%w: %s: keys are not sorted", ErrInvalidNode, n.cid)
+		}
+		if n.layer == -1 {
+			n.layer = Layer(key)
+		}
+		if Layer(key) != n.layer {
+			return fmt.Errorf("%w: %s: key %q is not in layer %d", ErrInvalidNode, n.cid, key, n.layer)
+		}
+		if !ed.Value.Defined() {
+			return fmt.Errorf("%w: %s: missing value for key %q", ErrInvalidNode, n.cid, key)
+		}
+		entries[i] = entry{key: key, value: ed.Value}
+		prev = key
+	}
+
+	if n.layer == -1 {
+		// A root without entries is either empty, or has its layer set below
+		n.layer = 0
+		if nd.Left != nil {
+			left := &node{cid: *nd.Left, layer: -1}
+			if err := t.load(left); err != nil {
+				return err
+			}
+			n.layer = left.layer + 1
+			n.left = left
+		}
+	}
+	childNode := func(c *cid.Cid) (*node, error) {
+		if c == nil {
+			return nil, nil
+		}
+		if n.layer == 0 {
+			return nil, fmt.Errorf("%w: %s: subtree below layer 0", ErrInvalidNode, n.cid)
+		}
+		return &node{cid: *c, layer: n.layer - 1}, nil
+	}
+	if n.left == nil {
+		if n.left, err = childNode(nd.Left); err != nil {
+			return err
+		}
+	}
+	for i, ed := range nd.Entries {
+		if entries[i].right, err = childNode(ed.Right); err != nil {
+			return err
+		}
+	}
+	n.entries = entries
+	n.loaded = true
+	return nil
+}
+
+// encode encodes the node and its new children, setting their CIDs.
+func (n *node) encode() error {
+	if n.cid.Defined() {
+		return nil
+	}
+	childCid := func(c *node) (*cid.Cid, error) {
+		if c == nil {
+			return nil, nil
+		}
+		if err := c.encode(); err != nil {
+			return nil, err
+		}
+		return &c.cid, nil
+	}
+
+	var nd nodeData
+	var err error
+	if nd.Left, err = childCid(n.left); err != nil {
+		return err
+	}
+	nd.Entries = make([]entryData, len(n.entries))
+	prev := ""
+	for i, e := range n.entries {
+		p := 0
+		for p < len(prev) && p < len(e.key) && prev[p] == e.key[p] {
+			p++
+		}
+		nd.Entries[i] = entryData{PrefixLen: p, KeySuffix: []byte(e.key[p:]), Value: e.value}
+		if nd.Entries[i].Right, err = childCid(e.right); err != nil {
+			return err
+		}
+		prev = e.key
+	}
+
+	data, err := nd.MarshalCBOR()
+	if err != nil {
+		return err
+	}
+	n.cid, _ = cid.HashDrisl(data, cid.HashTypeSha256)
+	n.data = data
+	return nil
+}
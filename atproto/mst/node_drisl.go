@@ -0,0 +1,193 @@
+// Code generated by "drislgen -type nodeData,entryData -output node_drisl.go"; DO NOT EDIT.
+
+package mst
+
+import (
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+	"github.com/hyphacoop/go-dasl/drisl/gensupport"
+)
+
+// MarshalCBOR fulfills the drisl.Marshaler interface.
+func (v nodeData) MarshalCBOR() ([]byte, error) {
+	return v.appendDRISL(nil)
+}
+
+func (v nodeData) appendDRISL(b []byte) ([]byte, error) {
+	var err error
+	b = gensupport.AppendMapHead(b, 2)
+	b = gensupport.AppendString(b, "e")
+	if v.Entries == nil {
+		b = gensupport.AppendNull(b)
+	} else {
+		b = gensupport.AppendArrayHead(b, len(v.Entries))
+		for _, e1 := range v.Entries {
+			if b, err = e1.appendDRISL(b); err != nil {
+				return nil, err
+			}
+		}
+	}
+	b = gensupport.AppendString(b, "l")
+	if v.Left == nil {
+		b = gensupport.AppendNull(b)
+	} else {
+		if b, err = gensupport.AppendCid(b, (*v.Left)); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// UnmarshalCBOR fulfills the drisl.Unmarshaler interface.
+func (v *nodeData) UnmarshalCBOR(data []byte) error {
+	d, err := gensupport.NewDecoder(data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+// UnmarshalDRISL fulfills the drisl.ModeUnmarshaler interface.
+func (v *nodeData) UnmarshalDRISL(dm drisl.DecMode, data []byte) error {
+	d, err := gensupport.NewDecoderMode(dm, data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+var drislFieldsNodeData = []string{"l", "e"}
+
+func (v *nodeData) decodeDRISL(d *gensupport.Decoder) {
+	if d.SkipNull() {
+		return
+	}
+	n, err := d.MapHead("mst.nodeData")
+	if err != nil {
+		return
+	}
+	var seen [2]bool
+	for i := range n {
+		f, err := d.Field("mst.nodeData", drislFieldsNodeData, seen[:])
+		if err != nil {
+			d.SkipPairs(n - i - 1)
+			return
+		}
+		switch f {
+		case 0:
+			if d.SkipNull() {
+				v.Left = nil
+			} else {
+				if v.Left == nil {
+					v.Left = new(cid.Cid)
+				}
+				d.Cid(&(*v.Left))
+			}
+		case 1:
+			if d.SkipNull() {
+				v.Entries = nil
+			} else if n2, err := d.ArrayHead("[]entryData"); err == nil {
+				if n2 == 0 || cap(v.Entries) < n2 {
+					v.Entries = make([]entryData, n2)
+				} else {
+					v.Entries = v.Entries[:n2]
+				}
+				for i3 := range v.Entries {
+					v.Entries[i3].decodeDRISL(d)
+				}
+			}
+		}
+	}
+}
+
+// MarshalCBOR fulfills the drisl.Marshaler interface.
+func (v entryData) MarshalCBOR() ([]byte, error) {
+	return v.appendDRISL(nil)
+}
+
+func (v entryData) appendDRISL(b []byte) ([]byte, error) {
+	var err error
+	b = gensupport.AppendMapHead(b, 4)
+	b = gensupport.AppendString(b, "k")
+	b = gensupport.AppendBytes(b, v.KeySuffix)
+	b = gensupport.AppendString(b, "p")
+	b = gensupport.AppendInt(b, int64(v.PrefixLen))
+	b = gensupport.AppendString(b, "t")
+	if v.Right == nil {
+		b = gensupport.AppendNull(b)
+	} else {
+		if b, err = gensupport.AppendCid(b, (*v.Right)); err != nil {
+			return nil, err
+		}
+	}
+	b = gensupport.AppendString(b, "v")
+	if b, err = gensupport.AppendCid(b, v.Value); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// UnmarshalCBOR fulfills the drisl.Unmarshaler interface.
+func (v *entryData) UnmarshalCBOR(data []byte) error {
+	d, err := gensupport.NewDecoder(data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+// UnmarshalDRISL fulfills the drisl.ModeUnmarshaler interface.
+func (v *entryData) UnmarshalDRISL(dm drisl.DecMode, data []byte) error {
+	d, err := gensupport.NewDecoderMode(dm, data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+var drislFieldsEntryData = []string{"p", "k", "v", "t"}
+
+func (v *entryData) decodeDRISL(d *gensupport.Decoder) {
+	if d.SkipNull() {
+		return
+	}
+	n, err := d.MapHead("mst.entryData")
+	if err != nil {
+		return
+	}
+	var seen [4]bool
+	for i := range n {
+		f, err := d.Field("mst.entryData", drislFieldsEntryData, seen[:])
+		if err != nil {
+			d.SkipPairs(n - i - 1)
+			return
+		}
+		switch f {
+		case 0:
+			if !d.SkipNull() {
+				gensupport.Int(d, &v.PrefixLen)
+			}
+		case 1:
+			if d.SkipNull() {
+				v.KeySuffix = nil
+			} else {
+				d.Bytes(&v.KeySuffix)
+			}
+		case 2:
+			d.Cid(&v.Value)
+		case 3:
+			if d.SkipNull() {
+				v.Right = nil
+			} else {
+				if v.Right == nil {
+					v.Right = new(cid.Cid)
+				}
+				d.Cid(&(*v.Right))
+			}
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Code generated by "drislgen -type Commit -output commit_drisl.go"; DO NOT EDIT.
+
+package repo
+
+import (
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+	"github.com/hyphacoop/go-dasl/drisl/gensupport"
+)
+
+// MarshalCBOR fulfills the drisl.Marshaler interface.
+func (v Commit) MarshalCBOR() ([]byte, error) {
+	return v.appendDRISL(nil)
+}
+
+func (v Commit) appendDRISL(b []byte) ([]byte, error) {
+	var err error
+	b = gensupport.AppendMapHead(b, 6)
+	b = gensupport.AppendString(b, "did")
+	b = gensupport.AppendString(b, v.Did)
+	b = gensupport.AppendString(b, "rev")
+	b = gensupport.AppendString(b, v.Rev)
+	b = gensupport.AppendString(b, "sig")
+	b = gensupport.AppendBytes(b, v.Sig)
+	b = gensupport.AppendString(b, "data")
+	if b, err = gensupport.AppendCid(b, v.Data); err != nil {
+		return nil, err
+	}
+	b = gensupport.AppendString(b, "prev")
+	if v.Prev == nil {
+		b = gensupport.AppendNull(b)
+	} else {
+		if b, err = gensupport.AppendCid(b, (*v.Prev)); err != nil {
+			return nil, err
+		}
+	}
+	b = gensupport.AppendString(b, "version")
+	b = gensupport.AppendInt(b, int64(v.Version))
+	return b, nil
+}
+
+// UnmarshalCBOR fulfills the drisl.Unmarshaler interface.
+func (v *Commit) UnmarshalCBOR(data []byte) error {
+	d, err := gensupport.NewDecoder(data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+// UnmarshalDRISL fulfills the drisl.ModeUnmarshaler interface.
+func (v *Commit) UnmarshalDRISL(dm drisl.DecMode, data []byte) error {
+	d, err := gensupport.NewDecoderMode(dm, data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+var drislFieldsCommit = []string{"did", "version", "data", "rev", "prev", "sig"}
+
+func (v *Commit) decodeDRISL(d *gensupport.Decoder) {
+	if d.SkipNull() {
+		return
+	}
+	n, err := d.MapHead("repo.Commit")
+	if err != nil {
+		return
+	}
+	var seen [6]bool
+	for i := range n {
+		f, err := d.Field("repo.Commit", drislFieldsCommit, seen[:])
+		if err != nil {
+			d.SkipPairs(n - i - 1)
+			return
+		}
+		switch f {
+		case 0:
+			if !d.SkipNull() {
+				d.String(&v.Did)
+			}
+		case 1:
+			if !d.SkipNull() {
+				gensupport.Int(d, &v.Version)
+			}
+		case 2:
+			d.Cid(&v.Data)
+		case 3:
+			if !d.SkipNull() {
+				d.String(&v.Rev)
+			}
+		case 4:
+			if d.SkipNull() {
+				v.Prev = nil
+			} else {
+				if v.Prev == nil {
+					v.Prev = new(cid.Cid)
+				}
+				d.Cid(&(*v.Prev))
+			}
+		case 5:
+			if d.SkipNull() {
+				v.Sig = nil
+			} else {
+				d.Bytes(&v.Sig)
+			}
+		}
+	}
+}
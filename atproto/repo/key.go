@@ -0,0 +1,196 @@
+package repo
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// KeyType is the elliptic curve of a PublicKey.
+type KeyType int
+
+const (
+	// KeyTypeP256 is the NIST P-256 curve, also known as secp256r1.
+	KeyTypeP256 KeyType = iota + 1
+	// KeyTypeSecp256k1 is the secp256k1 curve, also known as K-256.
+	KeyTypeSecp256k1
+)
+
+func (kt KeyType) String() string {
+	switch kt {
+	case KeyTypeP256:
+		return "P-256"
+	case KeyTypeSecp256k1:
+		return "secp256k1"
+	}
+	return fmt.Sprintf("KeyType(%d)", int(kt))
+}
+
+// Multicodec prefixes of compressed public keys, as varints.
+var (
+	p256Prefix      = []byte{0x80, 0x24}
+	secp256k1Prefix = []byte{0xe7, 0x01}
+)
+
+// didKeyPrefix is the prefix of did:key identifiers, followed by a multibase key.
+const didKeyPrefix = "did:key:"
+
+// ErrInvalidKey is returned when a public key cannot be parsed.
+// It is wrapped with more details.
+var ErrInvalidKey = errors.New("go-dasl/atproto/repo: invalid public key")
+
+// PublicKey is an ATProto signing key, used to verify commit signatures.
+type PublicKey struct {
+	typ  KeyType
+	x, y *big.Int
+}
+
+// ParseDIDKey parses a did:key identifier, like "did:key:zQ3sh...".
+// P-256 and secp256k1 keys are supported.
+func ParseDIDKey(s string) (*PublicKey, error) {
+	mb, ok := strings.CutPrefix(s, didKeyPrefix)
+	if !ok {
+		return nil, fmt.Errorf("%w: not a did:key: %q", ErrInvalidKey, s)
+	}
+	return ParseMultibaseKey(mb)
+}
+
+// ParseMultibaseKey parses a multibase encoded public key with its multicodec prefix,
+// as found in the publicKeyMultibase field of DID documents.
+func ParseMultibaseKey(s string) (*PublicKey, error) {
+	b, ok := strings.CutPrefix(s, "z")
+	if !ok {
+		return nil, fmt.Errorf("%w: not base58btc multibase", ErrInvalidKey)
+	}
+	data, err := base58Decode(b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+	switch {
+	case len(data) > 2 && string(data[:2]) == string(p256Prefix):
+		return ParsePublicKey(KeyTypeP256, data[2:])
+	case len(data) > 2 && string(data[:2]) == string(secp256k1Prefix):
+		return ParsePublicKey(KeyTypeSecp256k1, data[2:])
+	}
+	return nil, fmt.Errorf("%w: unsupported key type", ErrInvalidKey)
+}
+
+// ParsePublicKey parses a public key in compressed or uncompressed SEC1 form.
+func ParsePublicKey(typ KeyType, b []byte) (*PublicKey, error) {
+	var x, y *big.Int
+	switch typ {
+	case KeyTypeP256:
+		if len(b) > 0 && b[0] == 4 {
+			x, y = elliptic.Unmarshal(elliptic.P256(), b)
+		} else {
+			x, y = elliptic.UnmarshalCompressed(elliptic.P256(), b)
+		}
+	case KeyTypeSecp256k1:
+		x, y = secp256k1.unmarshal(b)
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %v", ErrInvalidKey, typ)
+	}
+	if x == nil {
+		return nil, fmt.Errorf("%w: %v point is not on the curve", ErrInvalidKey, typ)
+	}
+	return &PublicKey{typ: typ, x: x, y: y}, nil
+}
+
+// Type returns the curve of the key.
+func (k *PublicKey) Type() KeyType {
+	return k.typ
+}
+
+// Bytes returns the key in compressed SEC1 form.
+func (k *PublicKey) Bytes() []byte {
+	b := make([]byte, 33)
+	b[0] = byte(2 + k.y.Bit(0))
+	k.x.FillBytes(b[1:])
+	return b
+}
+
+// Multibase returns the key in the format parsed by ParseMultibaseKey.
+func (k *PublicKey) Multibase() string {
+	prefix := p256Prefix
+	if k.typ == KeyTypeSecp256k1 {
+		prefix = secp256k1Prefix
+	}
+	return "z" + base58Encode(append(prefix, k.Bytes()...))
+}
+
+// DIDKey returns the key as a did:key identifier.
+func (k *PublicKey) DIDKey() string {
+	return didKeyPrefix + k.Multibase()
+}
+
+// Verify reports whether sig is a valid signature of msg by the key.
+// The signature is ECDSA over the SHA-256 hash of msg, in the 64 byte compact form
+// used by ATProto. As ATProto requires, only low-S signatures are valid.
+func (k *PublicKey) Verify(msg, sig []byte) bool {
+	if len(sig) != 64 {
+		return false
+	}
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	hash := sha256.Sum256(msg)
+
+	switch k.typ {
+	case KeyTypeP256:
+		n := elliptic.P256().Params().N
+		if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+			return false
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: k.x, Y: k.y}
+		return ecdsa.Verify(pub, hash[:], r, s)
+	case KeyTypeSecp256k1:
+		if s.Cmp(new(big.Int).Rsh(secp256k1.n, 1)) > 0 {
+			return false
+		}
+		return secp256k1.verify(k.x, k.y, hash[:], r, s)
+	}
+	return false
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base58Decode decodes a base58btc string, as used by multibase.
+func base58Decode(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	zeros := 0
+	for i := 0; i < len(s); i++ {
+		d := strings.IndexByte(base58Alphabet, s[i])
+		if d < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", s[i])
+		}
+		if d == 0 && zeros == i {
+			zeros++
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(d)))
+	}
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
+
+// base58Encode encodes bytes as a base58btc string.
+func base58Encode(b []byte) string {
+	n := new(big.Int).SetBytes(b)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for i := 0; i < len(b) && b[i] == 0; i++ {
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
@@ -0,0 +1,320 @@
+/*
+Package repo verifies ATProto repositories exported as CAR files.
+
+A repository export contains a signed commit, the nodes of the Merkle Search Tree
+it points to, and the records stored in the tree. A Verifier checks all of them
+offline, given the DID of the repository and its signing key:
+
+	key, err := repo.ParseDIDKey("did:key:zQ3sh...")
+	if err != nil {
+		return err
+	}
+	report, err := repo.Verifier{DID: did, Key: key}.VerifyCAR(f)
+	if err != nil {
+		return err
+	}
+	if !report.Valid() {
+		// Inspect report.Missing, report.Mismatched, ...
+	}
+
+https://atproto.com/specs/repository
+*/
+package repo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/hyphacoop/go-dasl/atproto/mst"
+	"github.com/hyphacoop/go-dasl/blockstore"
+	"github.com/hyphacoop/go-dasl/car"
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+)
+
+//go:generate go run ../../cmd/drislgen -type Commit -output commit_drisl.go
+
+// Version is the repository format version supported by this package.
+const Version = 3
+
+var (
+	// ErrInvalidCommit is returned when a commit object is not valid.
+	// It is wrapped with more details.
+	ErrInvalidCommit = errors.New("go-dasl/atproto/repo: invalid commit")
+
+	// ErrInvalidSignature is returned when the signature of a commit doesn't
+	// match the key it was verified with.
+	ErrInvalidSignature = errors.New("go-dasl/atproto/repo: invalid commit signature")
+)
+
+// Commit is the signed commit object at the root of a repository.
+type Commit struct {
+	// Did is the DID of the account the repository belongs to.
+	Did string `cbor:"did"`
+	// Version is the repository format version, which is always 3.
+	Version int64 `cbor:"version"`
+	// Data is the CID of the root node of the MST.
+	Data cid.Cid `cbor:"data"`
+	// Rev is the revision of the repository, a TID that increases with each commit.
+	Rev string `cbor:"rev"`
+	// Prev is the CID of the previous commit. It is usually nil.
+	Prev *cid.Cid `cbor:"prev"`
+	// Sig is the signature of the commit without its sig field.
+	Sig []byte `cbor:"sig"`
+}
+
+// UnsignedBytes returns the DRISL encoding of a commit block without its signature,
+// which are the bytes the signature is computed on.
+func UnsignedBytes(block []byte) ([]byte, error) {
+	var n drisl.Node
+	if err := n.UnmarshalCBOR(block); err != nil {
+		return nil, err
+	}
+	if n.Kind() != drisl.KindMap {
+		return nil, fmt.Errorf("%w: not a map", ErrInvalidCommit)
+	}
+	n.Delete("sig")
+	return n.MarshalCBOR()
+}
+
+// BlockKind is the role of a block in a repository.
+type BlockKind int
+
+const (
+	// BlockUnknown is a block that is not referenced by the repository.
+	BlockUnknown BlockKind = iota
+	// BlockCommit is the signed commit object.
+	BlockCommit
+	// BlockNode is a node of the MST.
+	BlockNode
+	// BlockRecord is a record stored in the MST.
+	BlockRecord
+)
+
+func (k BlockKind) String() string {
+	switch k {
+	case BlockUnknown:
+		return "unknown"
+	case BlockCommit:
+		return "commit"
+	case BlockNode:
+		return "node"
+	case BlockRecord:
+		return "record"
+	}
+	return "BlockKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Block identifies a block of a repository in a Report.
+type Block struct {
+	Cid  cid.Cid
+	Kind BlockKind
+	// Path is the record path for BlockRecord blocks, like "app.bsky.feed.post/3jqfcqzm3fo2j".
+	Path string
+}
+
+// Report is the result of verifying a repository.
+type Report struct {
+	// CommitCid is the CID of the commit, the root of the CAR file.
+	CommitCid cid.Cid
+	// Commit is the decoded commit, or nil if it could not be decoded.
+	Commit *Commit
+	// Records is the number of records in the MST, including missing ones.
+	Records int
+
+	// Missing are the blocks referenced by the repository that are not in the CAR file.
+	Missing []Block
+	// Mismatched are the blocks in the CAR file whose data doesn't match their CID.
+	Mismatched []Block
+	// InvalidNodes are the MST nodes that could be read but are not valid.
+	InvalidNodes []*mst.NodeError
+	// CommitErrors are the problems with the commit object: it wraps ErrInvalidCommit
+	// or ErrInvalidSignature. The MST is not verified if the commit could not be decoded.
+	CommitErrors []error
+}
+
+// Valid reports whether no problems were found.
+func (r *Report) Valid() bool {
+	return len(r.Missing) == 0 && len(r.Mismatched) == 0 &&
+		len(r.InvalidNodes) == 0 && len(r.CommitErrors) == 0
+}
+
+// Verifier verifies repositories.
+type Verifier struct {
+	// DID is the expected DID of the repository. It is not checked if empty.
+	DID string
+	// Key is the signing key of the repository. It is required.
+	Key *PublicKey
+	// Since is the revision of a previous commit of the repository, if known.
+	// The revision of the verified commit must be greater.
+	Since string
+}
+
+// VerifyCAR reads a repository export from r and verifies it. The single root of the
+// CAR file must be the commit. The blocks are read into memory.
+//
+// An error is only returned if the CAR file cannot be read: problems with the
+// repository itself are listed in the Report.
+func (v Verifier) VerifyCAR(r io.Reader) (*Report, error) {
+	if v.Key == nil {
+		return nil, errors.New("go-dasl/atproto/repo: no key to verify the commit")
+	}
+	cr, err := car.ReaderOptions{SkipVerify: true}.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	roots := cr.Header().Roots
+	if len(roots) != 1 {
+		return nil, fmt.Errorf("go-dasl/atproto/repo: CAR file has %d roots, want 1", len(roots))
+	}
+
+	bs := blockstore.NewMemory()
+	mismatched := make(map[cid.Cid]bool)
+	var mismatchedOrder []cid.Cid
+	for {
+		c, data, err := cr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !c.VerifyBytes(data) {
+			if !mismatched[c] {
+				mismatched[c] = true
+				mismatchedOrder = append(mismatchedOrder, c)
+			}
+			continue
+		}
+		if err := bs.Put(c, data); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &Report{CommitCid: roots[0]}
+	reported := make(map[cid.Cid]bool)
+	// notFound reports a block that is not in bs
+	notFound := func(b Block) {
+		reported[b.Cid] = true
+		if mismatched[b.Cid] {
+			report.Mismatched = append(report.Mismatched, b)
+		} else {
+			report.Missing = append(report.Missing, b)
+		}
+	}
+	defer func() {
+		for _, c := range mismatchedOrder {
+			if !reported[c] {
+				report.Mismatched = append(report.Mismatched, Block{Cid: c})
+			}
+		}
+	}()
+
+	block, err := bs.Get(report.CommitCid)
+	if err != nil {
+		notFound(Block{Cid: report.CommitCid, Kind: BlockCommit})
+		return report, nil
+	}
+	var commit Commit
+	if err := commit.UnmarshalCBOR(block); err != nil {
+		report.CommitErrors = append(report.CommitErrors, fmt.Errorf("%w: %w", ErrInvalidCommit, err))
+		return report, nil
+	}
+	report.Commit = &commit
+	report.CommitErrors = v.checkCommit(&commit, block)
+	if !commit.Data.Defined() {
+		return report, nil
+	}
+
+	tree, err := mst.Load(bs, commit.Data)
+	var ne *mst.NodeError
+	if errors.As(err, &ne) {
+		v.nodeError(report, ne, notFound)
+		return report, nil
+	} else if err != nil {
+		return nil, err
+	}
+	nodeErrs := tree.Scan(func(e mst.Entry) {
+		report.Records++
+		if ok, _ := bs.Has(e.Value); !ok {
+			notFound(Block{Cid: e.Value, Kind: BlockRecord, Path: e.Key})
+		}
+	})
+	for _, ne := range nodeErrs {
+		v.nodeError(report, ne, notFound)
+	}
+	return report, nil
+}
+
+// nodeError adds an MST node that could not be loaded to the report.
+func (v Verifier) nodeError(report *Report, ne *mst.NodeError, notFound func(Block)) {
+	if errors.Is(ne, blockstore.ErrNotFound) {
+		notFound(Block{Cid: ne.Cid, Kind: BlockNode})
+	} else {
+		report.InvalidNodes = append(report.InvalidNodes, ne)
+	}
+}
+
+// checkCommit checks the fields and signature of a commit.
+func (v Verifier) checkCommit(c *Commit, block []byte) []error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]any{ErrInvalidCommit}, args...)...))
+	}
+
+	if c.Version != Version {
+		invalid("unsupported version %d", c.Version)
+	}
+	if !strings.HasPrefix(c.Did, "did:") {
+		invalid("invalid DID %q", c.Did)
+	} else if v.DID != "" && c.Did != v.DID {
+		invalid("DID is %q, want %q", c.Did, v.DID)
+	}
+	if !ValidTID(c.Rev) {
+		invalid("invalid revision %q", c.Rev)
+	} else if v.Since != "" && c.Rev <= v.Since {
+		invalid("revision %q is not after %q", c.Rev, v.Since)
+	}
+	if !c.Data.Defined() {
+		invalid("missing data")
+	} else if c.Data.Codec() != cid.CodecDrisl {
+		invalid("data %s is not a DRISL CID", c.Data)
+	}
+	if c.Prev != nil && c.Prev.Codec() != cid.CodecDrisl {
+		invalid("prev %s is not a DRISL CID", c.Prev)
+	}
+
+	unsigned, err := UnsignedBytes(block)
+	if err != nil {
+		invalid("%w", err)
+	} else if !v.Key.Verify(unsigned, c.Sig) {
+		errs = append(errs, fmt.Errorf("%w: not signed by %s", ErrInvalidSignature, v.Key.DIDKey()))
+	}
+	return errs
+}
+
+// tidAlphabet is the base32-sortable alphabet of TIDs.
+const tidAlphabet = "234567abcdefghijklmnopqrstuvwxyz"
+
+// ValidTID reports whether s is a valid TID, the timestamp identifiers used
+// for revisions and record keys.
+//
+// https://atproto.com/specs/tid
+func ValidTID(s string) bool {
+	if len(s) != 13 {
+		return false
+	}
+	// The top bit is always 0
+	if strings.IndexByte(tidAlphabet[:16], s[0]) < 0 {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if strings.IndexByte(tidAlphabet, s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,453 @@
+package repo
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/hyphacoop/go-dasl/atproto/mst"
+	"github.com/hyphacoop/go-dasl/car"
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+)
+
+const testDID = "did:plc:ewvi7nxzyoun6zhxrhs64oiz"
+
+// Examples from https://atproto.com/specs/cryptography
+var testDIDKeys = []struct {
+	did string
+	typ KeyType
+}{
+	{"did:key:zDnaembgSGUhZULN2Caob4HLJPaxBh92N7rtH21TErzqf8HQo", KeyTypeP256},
+	{"did:key:zQ3shqwJEJyMBsBXCWyCBpUBMqxcon9oHB7mCvx4sSpMdLJwc", KeyTypeSecp256k1},
+}
+
+func TestParseDIDKey(t *testing.T) {
+	for _, tt := range testDIDKeys {
+		key, err := ParseDIDKey(tt.did)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.did, err)
+		}
+		if key.Type() != tt.typ {
+			t.Errorf("%s: got type %v, want %v", tt.did, key.Type(), tt.typ)
+		}
+		if got := key.DIDKey(); got != tt.did {
+			t.Errorf("got %s, want %s", got, tt.did)
+		}
+	}
+
+	for _, s := range []string{
+		"",
+		"did:plc:ewvi7nxzyoun6zhxrhs64oiz",
+		"did:key:",
+		"did:key:mDnaembgSGUhZULN2Caob4HLJPaxBh92N7rtH21TErzqf8HQo",
+		"did:key:z6MkhaXgBZDvotDkL5257faiztiGiC2QtKLGpbnnEGta2doK", // Ed25519
+		"did:key:zQ3shqwJEJyMBsBXCWyCBpUBMqxcon9oHB7mCvx4sSpMdLJw0",
+	} {
+		if _, err := ParseDIDKey(s); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("%q: got %v, want ErrInvalidKey", s, err)
+		}
+	}
+}
+
+// Vectors from crypto/signature-fixtures.json in the ATProto interop tests, signing
+// the DRISL encoding of {"hello": "world"}. The high-S signatures are the low-S ones
+// with s replaced by n - s, which ATProto doesn't allow.
+//
+// https://github.com/bluesky-social/atproto-interop-tests
+var signatureFixtures = []struct {
+	name string
+	did  string
+	// multibase is the compressed key without a multicodec prefix, as found
+	// in legacy DID documents
+	multibase string
+	sig       string
+	valid     bool
+}{
+	{
+		"P-256 low-S",
+		"did:key:zDnaembgSGUhZULN2Caob4HLJPaxBh92N7rtH21TErzqf8HQo",
+		"zxdM8dSstjrpZaRUwBmDvjGXweKuEMVN95A9oJBFjkWMh",
+		"2vZNsG3UKvvO/CDlrdvyZRISOFylinBh0Jupc6KcWoJWExHptCfduPleDbG3rko3YZnn9Lw0IjpixVmexJDegg",
+		true,
+	},
+	{
+		"secp256k1 low-S",
+		"did:key:zQ3shqwJEJyMBsBXCWyCBpUBMqxcon9oHB7mCvx4sSpMdLJwc",
+		"z25z9DTpsiYYJKGsWmSPJK2NFN8PcJtZig12K59UgW7q5t",
+		"5WpdIuEUUfVUYaozsi8G0B3cWO09cgZbIIwg1t2YKdUn/FEznOndsz/qgiYb89zwxYCbB71f7yQK5Lr7NasfoA",
+		true,
+	},
+	{
+		"P-256 high-S",
+		"did:key:zDnaembgSGUhZULN2Caob4HLJPaxBh92N7rtH21TErzqf8HQo",
+		"zxdM8dSstjrpZaRUwBmDvjGXweKuEMVN95A9oJBFjkWMh",
+		"2vZNsG3UKvvO/CDlrdvyZRISOFylinBh0Jupc6KcWoKp7O4VS9giSAah8k5IUbXIW00SuOrjfEqQ9HEkN9JGzw",
+		false,
+	},
+	{
+		"secp256k1 high-S",
+		"did:key:zQ3shqwJEJyMBsBXCWyCBpUBMqxcon9oHB7mCvx4sSpMdLJwc",
+		"z25z9DTpsiYYJKGsWmSPJK2NFN8PcJtZig12K59UgW7q5t",
+		"5WpdIuEUUfVUYaozsi8G0B3cWO09cgZbIIwg1t2YKdXYA67MYxYiTMAVfdnkDCMN9S5B3vHosRe07aORmoshoQ",
+		false,
+	},
+}
+
+func TestSignatureFixtures(t *testing.T) {
+	msg, err := base64.RawStdEncoding.DecodeString("oWVoZWxsb2V3b3JsZA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range signatureFixtures {
+		t.Run(f.name, func(t *testing.T) {
+			key, err := ParseDIDKey(f.did)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := base58Decode(f.multibase[1:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			legacy, err := ParsePublicKey(key.Type(), b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(legacy.Bytes(), key.Bytes()) {
+				t.Fatalf("multibase key %x doesn't match did:key %x", legacy.Bytes(), key.Bytes())
+			}
+
+			sig, err := base64.RawStdEncoding.DecodeString(f.sig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := key.Verify(msg, sig); got != f.valid {
+				t.Fatalf("Verify = %v, want %v", got, f.valid)
+			}
+
+			// The same signature in a commit block: the fields of the commit are
+			// invalid, but the signature covers the block without its sig field
+			block, err := drisl.Marshal(map[string]any{"hello": "world", "sig": sig})
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := cid.MustHashDrisl(block, cid.HashTypeSha256)
+			var buf bytes.Buffer
+			if err := car.Write(&buf, []cid.Cid{c}, maps.All(map[cid.Cid][]byte{c: block})); err != nil {
+				t.Fatal(err)
+			}
+			report, err := Verifier{Key: key}.VerifyCAR(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			invalidSig := slices.ContainsFunc(report.CommitErrors, func(err error) bool {
+				return errors.Is(err, ErrInvalidSignature)
+			})
+			if invalidSig == f.valid {
+				t.Fatalf("got commit errors %v, want valid signature %v", report.CommitErrors, f.valid)
+			}
+		})
+	}
+}
+
+// testKey is a private key for signing test commits.
+type testKey struct {
+	typ KeyType
+	d   *big.Int
+	pub *PublicKey
+}
+
+func newTestKey(t *testing.T, typ KeyType) *testKey {
+	t.Helper()
+	switch typ {
+	case KeyTypeP256:
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &testKey{typ, priv.D, &PublicKey{typ, priv.X, priv.Y}}
+	case KeyTypeSecp256k1:
+		d, err := rand.Int(rand.Reader, new(big.Int).Sub(secp256k1.n, big.NewInt(1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.Add(d, big.NewInt(1))
+		x, y := secp256k1.scalarMult(secp256k1.gx, secp256k1.gy, d)
+		return &testKey{typ, d, &PublicKey{typ, x, y}}
+	}
+	panic("unknown key type")
+}
+
+// sign returns a low-S compact signature of msg.
+func (k *testKey) sign(t *testing.T, msg []byte) []byte {
+	t.Helper()
+	hash := sha256.Sum256(msg)
+	var r, s, n *big.Int
+	switch k.typ {
+	case KeyTypeP256:
+		priv := &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{Curve: elliptic.P256(), X: k.pub.x, Y: k.pub.y},
+			D:         k.d,
+		}
+		var err error
+		if r, s, err = ecdsa.Sign(rand.Reader, priv, hash[:]); err != nil {
+			t.Fatal(err)
+		}
+		n = elliptic.P256().Params().N
+	case KeyTypeSecp256k1:
+		n = secp256k1.n
+		nonce, err := rand.Int(rand.Reader, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rx, _ := secp256k1.scalarMult(secp256k1.gx, secp256k1.gy, nonce)
+		r = rx.Mod(rx, n)
+		// s = (e + r*d) / k
+		s = new(big.Int).Mul(r, k.d)
+		s.Add(s, secp256k1.hashToInt(hash[:]))
+		s.Mul(s, new(big.Int).ModInverse(nonce, n))
+		s.Mod(s, n)
+	}
+	if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		s.Sub(n, s)
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return sig
+}
+
+// testRepo is the blocks of a repository.
+type testRepo struct {
+	commit cid.Cid
+	data   cid.Cid
+	nodes  []cid.Cid
+	blocks map[cid.Cid][]byte
+}
+
+// newTestRepo creates a repository with n records, signed by key.
+func newTestRepo(t *testing.T, key *testKey, n int) *testRepo {
+	t.Helper()
+	repo := &testRepo{blocks: make(map[cid.Cid][]byte)}
+	tree := mst.New(nil)
+	for i := range n {
+		record, err := drisl.Marshal(map[string]any{"text": fmt.Sprintf("post %d", i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := cid.MustHashDrisl(record, cid.HashTypeSha256)
+		repo.blocks[c] = record
+		if err := tree.Put(fmt.Sprintf("app.bsky.feed.post/%03d", i), c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	nodes, err := tree.NewBlocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.data, err = tree.Root(); err != nil {
+		t.Fatal(err)
+	}
+	for c, data := range nodes {
+		repo.blocks[c] = data
+		if c != repo.data {
+			repo.nodes = append(repo.nodes, c)
+		}
+	}
+
+	commit := Commit{Did: testDID, Version: Version, Data: repo.data, Rev: "3l3qo2vutsw2b"}
+	unsigned, err := drisl.Marshal(struct {
+		Did     string   `cbor:"did"`
+		Version int64    `cbor:"version"`
+		Data    cid.Cid  `cbor:"data"`
+		Rev     string   `cbor:"rev"`
+		Prev    *cid.Cid `cbor:"prev"`
+	}{commit.Did, commit.Version, commit.Data, commit.Rev, commit.Prev})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit.Sig = key.sign(t, unsigned)
+	block, err := commit.MarshalCBOR()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.commit = cid.MustHashDrisl(block, cid.HashTypeSha256)
+	repo.blocks[repo.commit] = block
+	return repo
+}
+
+// car returns the repository as a CAR file.
+func (repo *testRepo) car(t *testing.T) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := car.Write(&buf, []cid.Cid{repo.commit}, maps.All(repo.blocks)); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestVerifyCAR(t *testing.T) {
+	for _, typ := range []KeyType{KeyTypeP256, KeyTypeSecp256k1} {
+		t.Run(typ.String(), func(t *testing.T) {
+			key := newTestKey(t, typ)
+			repo := newTestRepo(t, key, 50)
+
+			// Parse the key like it would be from a DID document
+			pub, err := ParseDIDKey(key.pub.DIDKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+			report, err := Verifier{DID: testDID, Key: pub}.VerifyCAR(repo.car(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !report.Valid() {
+				t.Fatalf("got invalid report %+v", report)
+			}
+			if report.Records != 50 || report.CommitCid != repo.commit || report.Commit.Data != repo.data {
+				t.Fatalf("got report %+v", report)
+			}
+
+			other := newTestKey(t, typ)
+			report, err = Verifier{Key: other.pub}.VerifyCAR(repo.car(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report.CommitErrors) != 1 || !errors.Is(report.CommitErrors[0], ErrInvalidSignature) {
+				t.Fatalf("got commit errors %v, want ErrInvalidSignature", report.CommitErrors)
+			}
+		})
+	}
+}
+
+func TestVerifyCARCommit(t *testing.T) {
+	key := newTestKey(t, KeyTypeP256)
+	repo := newTestRepo(t, key, 10)
+
+	for _, v := range []Verifier{
+		{DID: "did:plc:someoneelse", Key: key.pub},
+		{Since: "3l3qo2vutsw2b", Key: key.pub},
+	} {
+		report, err := v.VerifyCAR(repo.car(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.CommitErrors) != 1 || !errors.Is(report.CommitErrors[0], ErrInvalidCommit) {
+			t.Errorf("%+v: got commit errors %v, want ErrInvalidCommit", v, report.CommitErrors)
+		}
+	}
+
+	// A commit whose data was changed after signing
+	var commit Commit
+	if err := commit.UnmarshalCBOR(repo.blocks[repo.commit]); err != nil {
+		t.Fatal(err)
+	}
+	commit.Rev = "3l3qo2vutsw2c"
+	block, err := commit.MarshalCBOR()
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(repo.blocks, repo.commit)
+	repo.commit = cid.MustHashDrisl(block, cid.HashTypeSha256)
+	repo.blocks[repo.commit] = block
+	report, err := Verifier{Key: key.pub}.VerifyCAR(repo.car(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.CommitErrors) != 1 || !errors.Is(report.CommitErrors[0], ErrInvalidSignature) {
+		t.Fatalf("got commit errors %v, want ErrInvalidSignature", report.CommitErrors)
+	}
+
+	// Without the commit
+	delete(repo.blocks, repo.commit)
+	report, err = Verifier{Key: key.pub}.VerifyCAR(repo.car(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Block{Cid: repo.commit, Kind: BlockCommit}); len(report.Missing) != 1 || report.Missing[0] != want {
+		t.Fatalf("got missing %v, want the commit", report.Missing)
+	}
+}
+
+func TestVerifyCARBlocks(t *testing.T) {
+	key := newTestKey(t, KeyTypeSecp256k1)
+	repo := newTestRepo(t, key, 100)
+	tree, err := mst.Load(mapGetter(repo.blocks), repo.data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []mst.Entry
+	for e, err := range tree.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+
+	// Remove a record, corrupt another one, and add an unreferenced corrupted block
+	unknown := cid.MustHashDrisl([]byte("unreferenced"), cid.HashTypeSha256)
+	delete(repo.blocks, entries[0].Value)
+	repo.blocks[entries[1].Value] = []byte("corrupted")
+	repo.blocks[unknown] = []byte("corrupted")
+
+	report, err := Verifier{DID: testDID, Key: key.pub}.VerifyCAR(repo.car(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.CommitErrors) != 0 || len(report.InvalidNodes) != 0 || report.Records != 100 {
+		t.Fatalf("got report %+v", report)
+	}
+	want := []Block{{Cid: entries[0].Value, Kind: BlockRecord, Path: entries[0].Key}}
+	if !slices.Equal(report.Missing, want) {
+		t.Fatalf("got missing %v, want %v", report.Missing, want)
+	}
+	want = []Block{
+		{Cid: entries[1].Value, Kind: BlockRecord, Path: entries[1].Key},
+		{Cid: unknown, Kind: BlockUnknown},
+	}
+	if !slices.Equal(report.Mismatched, want) {
+		t.Fatalf("got mismatched %v, want %v", report.Mismatched, want)
+	}
+
+	// Corrupt a node below the root, whose records are then skipped
+	repo = newTestRepo(t, key, 100)
+	node := repo.nodes[0]
+	repo.blocks[node] = []byte("corrupted")
+	report, err = Verifier{DID: testDID, Key: key.pub}.VerifyCAR(repo.car(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []Block{{Cid: node, Kind: BlockNode}}
+	if !slices.Equal(report.Mismatched, want) || len(report.Missing) != 0 {
+		t.Fatalf("got mismatched %v and missing %v, want %v", report.Mismatched, report.Missing, want)
+	}
+	if report.Records == 0 || report.Records >= 100 {
+		t.Fatalf("got %d records, want the records of the corrupted node skipped", report.Records)
+	}
+
+	// Remove the root node
+	delete(repo.blocks, repo.data)
+	report, err = Verifier{DID: testDID, Key: key.pub}.VerifyCAR(repo.car(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []Block{{Cid: repo.data, Kind: BlockNode}}
+	if !slices.Equal(report.Missing, want) || report.Records != 0 {
+		t.Fatalf("got missing %v, want %v", report.Missing, want)
+	}
+}
+
+type mapGetter map[cid.Cid][]byte
+
+func (m mapGetter) Get(c cid.Cid) ([]byte, error) {
+	return m[c], nil
+}
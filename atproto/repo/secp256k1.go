@@ -0,0 +1,156 @@
+package repo
+
+import (
+	"math/big"
+)
+
+// curve is a short Weierstrass curve y² = x³ + b with a = 0, like secp256k1,
+// which crypto/elliptic doesn't support. Only what signature verification needs
+// is implemented, so it is not constant time: all its inputs are public.
+type curve struct {
+	p, n, b, gx, gy *big.Int
+}
+
+func hexInt(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("invalid hex integer " + s)
+	}
+	return n
+}
+
+var secp256k1 = &curve{
+	p:  hexInt("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f"),
+	n:  hexInt("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141"),
+	b:  big.NewInt(7),
+	gx: hexInt("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"),
+	gy: hexInt("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"),
+}
+
+// onCurve reports whether the point is on the curve. The point at infinity is not.
+func (c *curve) onCurve(x, y *big.Int) bool {
+	if x.Sign() < 0 || x.Cmp(c.p) >= 0 || y.Sign() < 0 || y.Cmp(c.p) >= 0 {
+		return false
+	}
+	return c.rhs(x).Cmp(new(big.Int).Exp(y, big.NewInt(2), c.p)) == 0
+}
+
+// rhs returns x³ + b.
+func (c *curve) rhs(x *big.Int) *big.Int {
+	r := new(big.Int).Exp(x, big.NewInt(3), c.p)
+	r.Add(r, c.b)
+	return r.Mod(r, c.p)
+}
+
+// unmarshal parses a point in compressed or uncompressed SEC1 form.
+// It returns nil if the point is invalid.
+func (c *curve) unmarshal(b []byte) (*big.Int, *big.Int) {
+	var x, y *big.Int
+	switch {
+	case len(b) == 65 && b[0] == 4:
+		x = new(big.Int).SetBytes(b[1:33])
+		y = new(big.Int).SetBytes(b[33:])
+	case len(b) == 33 && (b[0] == 2 || b[0] == 3):
+		x = new(big.Int).SetBytes(b[1:])
+		if x.Cmp(c.p) >= 0 {
+			return nil, nil
+		}
+		// p ≡ 3 mod 4, so the square root is rhs^((p+1)/4)
+		exp := new(big.Int).Add(c.p, big.NewInt(1))
+		exp.Rsh(exp, 2)
+		y = new(big.Int).Exp(c.rhs(x), exp, c.p)
+		if y.Bit(0) != uint(b[0]&1) {
+			y.Sub(c.p, y)
+		}
+	default:
+		return nil, nil
+	}
+	if !c.onCurve(x, y) {
+		return nil, nil
+	}
+	return x, y
+}
+
+// add returns the sum of two points, with nil x for the point at infinity.
+func (c *curve) add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	if x1 == nil {
+		return x2, y2
+	}
+	if x2 == nil {
+		return x1, y1
+	}
+	var lambda *big.Int
+	if x1.Cmp(x2) == 0 {
+		if y1.Cmp(y2) != 0 || y1.Sign() == 0 {
+			return nil, nil
+		}
+		// λ = 3x² / 2y
+		num := new(big.Int).Mul(x1, x1)
+		num.Mul(num, big.NewInt(3))
+		den := new(big.Int).Lsh(y1, 1)
+		lambda = num.Mul(num, c.inverse(den))
+	} else {
+		// λ = (y2 - y1) / (x2 - x1)
+		num := new(big.Int).Sub(y2, y1)
+		den := new(big.Int).Sub(x2, x1)
+		lambda = num.Mul(num, c.inverse(den))
+	}
+	lambda.Mod(lambda, c.p)
+
+	x3 := new(big.Int).Mul(lambda, lambda)
+	x3.Sub(x3, x1)
+	x3.Sub(x3, x2)
+	x3.Mod(x3, c.p)
+	y3 := new(big.Int).Sub(x1, x3)
+	y3.Mul(y3, lambda)
+	y3.Sub(y3, y1)
+	y3.Mod(y3, c.p)
+	return x3, y3
+}
+
+// inverse returns the inverse of a modulo p.
+func (c *curve) inverse(a *big.Int) *big.Int {
+	return new(big.Int).ModInverse(new(big.Int).Mod(a, c.p), c.p)
+}
+
+// scalarMult returns k times the point.
+func (c *curve) scalarMult(x, y, k *big.Int) (*big.Int, *big.Int) {
+	var rx, ry *big.Int
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		rx, ry = c.add(rx, ry, rx, ry)
+		if k.Bit(i) == 1 {
+			rx, ry = c.add(rx, ry, x, y)
+		}
+	}
+	return rx, ry
+}
+
+// verify verifies an ECDSA signature of a hash by the public key (x, y).
+func (c *curve) verify(x, y *big.Int, hash []byte, r, s *big.Int) bool {
+	if r.Sign() <= 0 || r.Cmp(c.n) >= 0 || s.Sign() <= 0 || s.Cmp(c.n) >= 0 {
+		return false
+	}
+	e := c.hashToInt(hash)
+	w := new(big.Int).ModInverse(s, c.n)
+	u1 := e.Mul(e, w)
+	u1.Mod(u1, c.n)
+	u2 := w.Mul(r, w)
+	u2.Mod(u2, c.n)
+
+	x1, y1 := c.scalarMult(c.gx, c.gy, u1)
+	x2, y2 := c.scalarMult(x, y, u2)
+	rx, _ := c.add(x1, y1, x2, y2)
+	if rx == nil {
+		return false
+	}
+	return rx.Mod(rx, c.n).Cmp(r) == 0
+}
+
+// hashToInt converts a hash to an integer, truncated to the bit length of n.
+func (c *curve) hashToInt(hash []byte) *big.Int {
+	e := new(big.Int).SetBytes(hash)
+	if excess := len(hash)*8 - c.n.BitLen(); excess > 0 {
+		e.Rsh(e, uint(excess))
+	}
+	return e
+}
@@ -0,0 +1,288 @@
+/*
+Package bao implements BLAKE3 verified streaming for BLAKE3 CIDs, using the Bao format.
+
+The BLAKE3 hash of some data is the root of a Merkle tree over its chunks. An outboard
+stores the inner nodes of that tree apart from the data, so any byte range of the data
+can be verified against the CID without hashing the rest of it. A slice is a byte range
+of the data along with the tree nodes needed to verify it, and is what a server sends
+for a range request.
+
+Chunks are grouped to keep outboards small: with the DefaultGroup of 16 KiB, an outboard
+is 1/256 of the size of the data, and data is verified 16 KiB at a time.
+
+	o, err := bao.NewOutboardFile("video.mp4", bao.DefaultGroup)
+	if err != nil {
+		return err
+	}
+	// Server side
+	err = o.WriteSlice(w, file, offset, length)
+	// Client side
+	r, err := bao.NewSliceReader(resp.Body, c, bao.DefaultGroup, offset, length)
+
+https://github.com/oconnor663/bao/blob/master/docs/spec.md
+*/
+package bao
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"lukechampine.com/blake3/bao"
+	"lukechampine.com/blake3/guts"
+)
+
+const (
+	// DefaultGroup is the chunk group size used by default, as a power of two of
+	// 1 KiB BLAKE3 chunks: 2^4 chunks is 16 KiB.
+	DefaultGroup = 4
+
+	// MaxGroup is the maximum chunk group size, 2^16 chunks or 64 MiB.
+	MaxGroup = 16
+
+	// headerSize is the size of the length header of outboards and slices.
+	headerSize = 8
+)
+
+var (
+	// ErrNotBlake3 is returned when a CID doesn't use BLAKE3 hashing,
+	// so it can't be verified in slices.
+	ErrNotBlake3 = errors.New("go-dasl/bao: CID is not BLAKE3")
+
+	// ErrInvalidOutboard is returned when an outboard is malformed, or doesn't
+	// match its CID. It is wrapped with more details.
+	ErrInvalidOutboard = errors.New("go-dasl/bao: invalid outboard")
+
+	// ErrInvalidRange is returned when a byte range starts after the end of the data.
+	ErrInvalidRange = errors.New("go-dasl/bao: invalid range")
+)
+
+// Outboard is the BLAKE3 tree of some data, stored apart from the data.
+type Outboard struct {
+	cid   cid.Cid
+	size  int64
+	group int
+	// tree is the Bao outboard encoding, including the length header
+	tree []byte
+}
+
+// NewOutboard computes the outboard of size bytes of data read from r, with chunk
+// groups of 2^group KiB. The CID of the data is available with Outboard.Cid.
+func NewOutboard(r io.Reader, size int64, group int) (*Outboard, error) {
+	if group < 0 || group > MaxGroup {
+		return nil, fmt.Errorf("go-dasl/bao: invalid group %d", group)
+	}
+	if size < 0 {
+		return nil, fmt.Errorf("go-dasl/bao: invalid size %d", size)
+	}
+	buf := &bufferAt{make([]byte, bao.EncodedSize(int(size), group, true))}
+	digest, err := bao.Encode(buf, r, size, group, true)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	c, _ := cid.NewCidFromInfo(cid.CodecRaw, cid.HashTypeBlake3, digest)
+	return &Outboard{cid: c, size: size, group: group, tree: buf.b}, nil
+}
+
+// NewOutboardFile computes the outboard of a file. See NewOutboard.
+func NewOutboardFile(path string, group int) (*Outboard, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return NewOutboard(f, fi.Size(), group)
+}
+
+// ParseOutboard parses an outboard returned by Outboard.Bytes, for the data
+// identified by c. The group must be the one the outboard was computed with.
+//
+// The top of the tree is checked against the CID, but the rest of it can only be
+// verified along with the data.
+func ParseOutboard(c cid.Cid, group int, b []byte) (*Outboard, error) {
+	if c.HashType() != cid.HashTypeBlake3 {
+		return nil, ErrNotBlake3
+	}
+	if group < 0 || group > MaxGroup {
+		return nil, fmt.Errorf("go-dasl/bao: invalid group %d", group)
+	}
+	if len(b) < headerSize {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidOutboard)
+	}
+	size := binary.LittleEndian.Uint64(b)
+	if size > 1<<62 || len(b) != bao.EncodedSize(int(size), group, true) {
+		return nil, fmt.Errorf("%w: length doesn't match data size %d", ErrInvalidOutboard, size)
+	}
+	if len(b) > headerSize {
+		root := guts.ChainingValue(guts.ParentNode(bytesToCV(b[8:40]), bytesToCV(b[40:72]), &guts.IV, guts.FlagRoot))
+		if cvToBytes(root) != c.Digest() {
+			return nil, fmt.Errorf("%w: doesn't match CID %s", ErrInvalidOutboard, c)
+		}
+	}
+	return &Outboard{cid: c, size: int64(size), group: group, tree: bytes.Clone(b)}, nil
+}
+
+// Cid returns the CID of the data. It is a raw CID for outboards created with
+// NewOutboard, but the outboard can be used for any BLAKE3 CID with the same digest.
+func (o *Outboard) Cid() cid.Cid {
+	return o.cid
+}
+
+// Size returns the size of the data in bytes.
+func (o *Outboard) Size() int64 {
+	return o.size
+}
+
+// Group returns the chunk group size of the outboard, as a power of two of 1 KiB chunks.
+func (o *Outboard) Group() int {
+	return o.group
+}
+
+// Bytes returns the outboard encoding, to be stored and loaded with ParseOutboard.
+// It must not be modified.
+func (o *Outboard) Bytes() []byte {
+	return o.tree
+}
+
+// clamp checks that a range starts within the data, and limits its length to the
+// end of the data. A negative length means the rest of the data.
+func clamp(size, offset, length int64) (int64, error) {
+	if offset < 0 || offset > size {
+		return 0, fmt.Errorf("%w: offset %d for size %d", ErrInvalidRange, offset, size)
+	}
+	if length < 0 || length > size-offset {
+		length = size - offset
+	}
+	return length, nil
+}
+
+// WriteSlice writes the slice encoding of a byte range of the data to w, reading the
+// data from r. A negative length, or one going past the end of the data, is limited to
+// the end of the data. ErrInvalidRange is returned if offset is past the end.
+//
+// Only the chunk groups overlapping the range are read from r.
+func (o *Outboard) WriteSlice(w io.Writer, r io.ReaderAt, offset, length int64) error {
+	length, err := clamp(o.size, offset, length)
+	if err != nil {
+		return err
+	}
+	groupSize := int64(guts.ChunkSize) << o.group
+	start := offset / groupSize * groupSize
+	end := min((offset+length+groupSize-1)/groupSize*groupSize, o.size)
+	data := io.NewSectionReader(r, start, end-start)
+	err = bao.ExtractSlice(w, data, bytes.NewReader(o.tree), o.group, uint64(offset), uint64(length))
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// VerifyRange checks that a byte range of the data read from r matches the CID.
+// cid.ErrCidMismatch is returned if it doesn't.
+func (o *Outboard) VerifyRange(r io.ReaderAt, offset, length int64) error {
+	slice := o.pipeSlice(r, offset, length)
+	defer slice.Close()
+	sr, err := NewSliceReader(slice, o.cid, o.group, offset, length)
+	if err != nil {
+		return err
+	}
+	defer sr.Close()
+	_, err = io.Copy(io.Discard, sr)
+	return err
+}
+
+// pipeSlice returns a reader of the slice encoding written by WriteSlice.
+func (o *Outboard) pipeSlice(r io.ReaderAt, offset, length int64) *io.PipeReader {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(o.WriteSlice(pw, r, offset, length))
+	}()
+	return pr
+}
+
+// NewSliceReader returns a reader of a byte range of the data identified by c, decoded
+// from the slice encoding read from r. The group must be the one the slice was created
+// with. The range is limited to the end of the data like in Outboard.WriteSlice, where
+// a negative length means the rest of the data.
+//
+// Data is verified one chunk group at a time as it is read from r, and only returned
+// once verified. If it doesn't match the CID, cid.ErrCidMismatch is returned instead.
+//
+// Close the reader to stop decoding before the end of the range.
+func NewSliceReader(r io.Reader, c cid.Cid, group int, offset, length int64) (io.ReadCloser, error) {
+	if c.HashType() != cid.HashTypeBlake3 {
+		return nil, ErrNotBlake3
+	}
+	if group < 0 || group > MaxGroup {
+		return nil, fmt.Errorf("go-dasl/bao: invalid group %d", group)
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(decodeSlice(pw, r, c, group, offset, length))
+	}()
+	return pr, nil
+}
+
+func decodeSlice(w io.Writer, r io.Reader, c cid.Cid, group int, offset, length int64) error {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	size := binary.LittleEndian.Uint64(header)
+	if size > 1<<62 {
+		return cid.ErrCidMismatch
+	}
+	length, err := clamp(int64(size), offset, length)
+	if err != nil {
+		return err
+	}
+	ok, err := bao.DecodeSlice(w, io.MultiReader(bytes.NewReader(header), r),
+		group, uint64(offset), uint64(length), c.Digest())
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	if !ok {
+		return cid.ErrCidMismatch
+	}
+	return nil
+}
+
+// bufferAt is an io.WriterAt for a buffer of a known size.
+type bufferAt struct {
+	b []byte
+}
+
+func (b *bufferAt) WriteAt(p []byte, off int64) (int, error) {
+	return copy(b.b[off:], p), nil
+}
+
+func bytesToCV(b []byte) (cv [8]uint32) {
+	for i := range cv {
+		cv[i] = binary.LittleEndian.Uint32(b[4*i:])
+	}
+	return cv
+}
+
+func cvToBytes(cv [8]uint32) (b [32]byte) {
+	for i, w := range cv {
+		binary.LittleEndian.PutUint32(b[4*i:], w)
+	}
+	return b
+}
@@ -0,0 +1,180 @@
+package bao_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyphacoop/go-dasl/bao"
+	"github.com/hyphacoop/go-dasl/cid"
+)
+
+func testData(size int) []byte {
+	data := make([]byte, size)
+	rng := rand.NewChaCha8([32]byte{})
+	rng.Read(data)
+	return data
+}
+
+var testRanges = []struct{ offset, length int64 }{
+	{0, 0},
+	{0, 1},
+	{0, -1},
+	{1000, 50000},
+	{16384, 16384},
+	{100000, 1000},
+	{100000, -1},
+	{99999, 5000}, // Past the end
+	{100001, 0},
+}
+
+func TestSlices(t *testing.T) {
+	for _, size := range []int{0, 1, 1024, 16384, 16385, 100001} {
+		data := testData(size)
+		o, err := bao.NewOutboard(bytes.NewReader(data), int64(size), bao.DefaultGroup)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o.Cid() != cid.HashBytesBlake3(data) {
+			t.Fatalf("size %d: got CID %s, want %s", size, o.Cid(), cid.HashBytesBlake3(data))
+		}
+
+		for _, rg := range testRanges {
+			if rg.offset > int64(size) {
+				continue
+			}
+			want := data[rg.offset:]
+			if rg.length >= 0 && rg.offset+rg.length < int64(size) {
+				want = want[:rg.length]
+			}
+
+			var slice bytes.Buffer
+			if err := o.WriteSlice(&slice, bytes.NewReader(data), rg.offset, rg.length); err != nil {
+				t.Fatal(err)
+			}
+			r, err := bao.NewSliceReader(&slice, o.Cid(), bao.DefaultGroup, rg.offset, rg.length)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("size %d, range %v: %v", size, rg, err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("size %d, range %v: got %d bytes, want %d", size, rg, len(got), len(want))
+			}
+			if err := o.VerifyRange(bytes.NewReader(data), rg.offset, rg.length); err != nil {
+				t.Fatalf("size %d, range %v: %v", size, rg, err)
+			}
+		}
+	}
+}
+
+func TestCorruptedData(t *testing.T) {
+	data := testData(100000)
+	o, err := bao.NewOutboard(bytes.NewReader(data), int64(len(data)), bao.DefaultGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupted := bytes.Clone(data)
+	corrupted[50000] ^= 1
+
+	// Only ranges including the corrupted chunk group fail
+	if err := o.VerifyRange(bytes.NewReader(corrupted), 0, 20000); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.VerifyRange(bytes.NewReader(corrupted), 49000, 2000); err != cid.ErrCidMismatch {
+		t.Fatalf("got %v, want ErrCidMismatch", err)
+	}
+
+	// Data before the corrupted group is returned before the error
+	var slice bytes.Buffer
+	if err := o.WriteSlice(&slice, bytes.NewReader(corrupted), 0, -1); err != nil {
+		t.Fatal(err)
+	}
+	r, err := bao.NewSliceReader(&slice, o.Cid(), bao.DefaultGroup, 0, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	if err != cid.ErrCidMismatch {
+		t.Fatalf("got %v, want ErrCidMismatch", err)
+	}
+	if len(got) != 49152 || !bytes.Equal(got, data[:len(got)]) {
+		t.Fatalf("got %d bytes before the error, want the first 3 groups", len(got))
+	}
+
+	// Truncated slice
+	slice.Reset()
+	if err := o.WriteSlice(&slice, bytes.NewReader(data), 0, -1); err != nil {
+		t.Fatal(err)
+	}
+	r, _ = bao.NewSliceReader(bytes.NewReader(slice.Bytes()[:slice.Len()-10]), o.Cid(), bao.DefaultGroup, 0, -1)
+	if _, err := io.ReadAll(r); err != io.ErrUnexpectedEOF {
+		t.Fatalf("got %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestInvalidRange(t *testing.T) {
+	data := testData(1000)
+	o, err := bao.NewOutboard(bytes.NewReader(data), int64(len(data)), bao.DefaultGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.WriteSlice(io.Discard, bytes.NewReader(data), 1001, 1); !errors.Is(err, bao.ErrInvalidRange) {
+		t.Fatalf("got %v, want ErrInvalidRange", err)
+	}
+	if _, err := bao.NewSliceReader(nil, cid.HashBytes(data), bao.DefaultGroup, 0, 1); err != bao.ErrNotBlake3 {
+		t.Fatalf("got %v, want ErrNotBlake3", err)
+	}
+}
+
+func TestParseOutboard(t *testing.T) {
+	data := testData(100000)
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	o, err := bao.NewOutboardFile(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 8 + (25*2-2)*32; len(o.Bytes()) != want {
+		t.Fatalf("got outboard of %d bytes, want %d", len(o.Bytes()), want)
+	}
+
+	// The outboard works for DRISL CIDs with the same digest
+	c, _ := cid.NewCidFromInfo(cid.CodecDrisl, cid.HashTypeBlake3, o.Cid().Digest())
+	parsed, err := bao.ParseOutboard(c, 2, o.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Size() != int64(len(data)) || parsed.Group() != 2 || parsed.Cid() != c {
+		t.Fatalf("got size %d and group %d", parsed.Size(), parsed.Group())
+	}
+	if err := parsed.VerifyRange(bytes.NewReader(data), 5000, 5000); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		c     cid.Cid
+		group int
+		b     []byte
+	}{
+		{cid.HashBytesBlake3(nil), 2, o.Bytes()},
+		{o.Cid(), 3, o.Bytes()},
+		{o.Cid(), 2, o.Bytes()[:100]},
+		{o.Cid(), 2, nil},
+	} {
+		if _, err := bao.ParseOutboard(tc.c, tc.group, tc.b); !errors.Is(err, bao.ErrInvalidOutboard) {
+			t.Errorf("got %v, want ErrInvalidOutboard", err)
+		}
+	}
+	if _, err := bao.ParseOutboard(cid.HashBytes(data), 2, o.Bytes()); err != bao.ErrNotBlake3 {
+		t.Errorf("got %v, want ErrNotBlake3", err)
+	}
+}
@@ -0,0 +1,75 @@
+/*
+Package blockstore provides storage for blocks of data, keyed by their CID.
+
+Two implementations are provided: Memory, which stores blocks in memory, and Directory,
+which stores blocks as files on disk. Both verify that data matches its CID when
+it is stored.
+
+A Blockstore can be served over RASL using Handler.
+*/
+package blockstore
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"iter"
+	"net/http"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/rasl"
+)
+
+var (
+	// ErrNotFound is returned when a block is not in the Blockstore.
+	ErrNotFound = errors.New("go-dasl/blockstore: block not found")
+
+	// ErrCidMismatch is returned when data does not match the CID it is stored under.
+	ErrCidMismatch = errors.New("go-dasl/blockstore: data does not match CID")
+
+	// ErrUndefinedCid is returned when a method is called with the zero cid.Cid.
+	ErrUndefinedCid = errors.New("go-dasl/blockstore: undefined CID")
+)
+
+// Blockstore stores blocks of data keyed by their CID.
+//
+// Implementations must be safe for concurrent use. Methods that take a CID
+// return ErrUndefinedCid if it is the zero cid.Cid.
+type Blockstore interface {
+	// Get returns the data for the CID, or ErrNotFound.
+	// The returned slice is owned by the caller.
+	Get(c cid.Cid) ([]byte, error)
+
+	// Put stores the data under the CID. Data that is already stored is not rewritten.
+	// ErrCidMismatch is returned if the data does not match the CID.
+	Put(c cid.Cid, data []byte) error
+
+	// Has reports whether the CID is stored.
+	Has(c cid.Cid) (bool, error)
+
+	// Delete removes the CID. Deleting a CID that is not stored is not an error.
+	Delete(c cid.Cid) error
+
+	// GetSize returns the size of the data for the CID in bytes, or ErrNotFound.
+	GetSize(c cid.Cid) (int, error)
+
+	// AllKeys iterates over every stored CID, in no particular order.
+	// Iteration stops after the first error.
+	AllKeys() iter.Seq2[cid.Cid, error]
+}
+
+// Handler serves the blocks in a Blockstore over RASL.
+// It follows the same semantics as rasl.FuncHandler: CIDs that are not stored
+// result in status code 404, and other errors in status code 500.
+func Handler(bs Blockstore) http.Handler {
+	return rasl.FuncHandler(func(c cid.Cid) (io.Reader, error) {
+		data, err := bs.Get(c)
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(data), nil
+	})
+}
@@ -0,0 +1,167 @@
+package blockstore_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyphacoop/go-dasl/blockstore"
+	"github.com/hyphacoop/go-dasl/cid"
+)
+
+func testBlockstore(t *testing.T, bs blockstore.Blockstore) {
+	data := []byte("hello")
+	c := cid.HashBytes(data)
+	c2 := cid.HashBytesBlake3([]byte("world"))
+
+	// The zero CID is rejected by every method instead of panicking
+	var zero cid.Cid
+	if _, err := bs.Get(zero); !errors.Is(err, blockstore.ErrUndefinedCid) {
+		t.Fatalf("Get with zero CID: got %v, want ErrUndefinedCid", err)
+	}
+	if err := bs.Put(zero, data); !errors.Is(err, blockstore.ErrUndefinedCid) {
+		t.Fatalf("Put with zero CID: got %v, want ErrUndefinedCid", err)
+	}
+	if _, err := bs.Has(zero); !errors.Is(err, blockstore.ErrUndefinedCid) {
+		t.Fatalf("Has with zero CID: got %v, want ErrUndefinedCid", err)
+	}
+	if err := bs.Delete(zero); !errors.Is(err, blockstore.ErrUndefinedCid) {
+		t.Fatalf("Delete with zero CID: got %v, want ErrUndefinedCid", err)
+	}
+	if _, err := bs.GetSize(zero); !errors.Is(err, blockstore.ErrUndefinedCid) {
+		t.Fatalf("GetSize with zero CID: got %v, want ErrUndefinedCid", err)
+	}
+
+	if _, err := bs.Get(c); !errors.Is(err, blockstore.ErrNotFound) {
+		t.Fatalf("Get before Put: got %v, want ErrNotFound", err)
+	}
+	if _, err := bs.GetSize(c); !errors.Is(err, blockstore.ErrNotFound) {
+		t.Fatalf("GetSize before Put: got %v, want ErrNotFound", err)
+	}
+	if err := bs.Put(c, []byte("wrong")); !errors.Is(err, blockstore.ErrCidMismatch) {
+		t.Fatalf("Put with wrong data: got %v, want ErrCidMismatch", err)
+	}
+	if err := bs.Put(c, data); err != nil {
+		t.Fatal(err)
+	}
+	// Putting twice is fine
+	if err := bs.Put(c, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.Put(c2, []byte("world")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := bs.Get(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "hello" {
+		t.Fatalf("got %q, want %q", got, "hello")
+	}
+	if has, err := bs.Has(c); err != nil || !has {
+		t.Fatalf("Has = %v, %v, want true", has, err)
+	}
+	if size, err := bs.GetSize(c); err != nil || size != 5 {
+		t.Fatalf("GetSize = %d, %v, want 5", size, err)
+	}
+
+	keys := make(map[cid.Cid]bool)
+	for k, err := range bs.AllKeys() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[k] = true
+	}
+	if len(keys) != 2 || !keys[c] || !keys[c2] {
+		t.Fatalf("AllKeys returned %v, want %v and %v", keys, c, c2)
+	}
+
+	if err := bs.Delete(c); err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.Delete(c); err != nil {
+		t.Fatalf("second Delete = %v, want nil", err)
+	}
+	if has, err := bs.Has(c); err != nil || has {
+		t.Fatalf("Has after Delete = %v, %v, want false", has, err)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testBlockstore(t, blockstore.NewMemory())
+}
+
+func TestDirectory(t *testing.T) {
+	dir := t.TempDir()
+	bs, err := blockstore.NewDirectory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testBlockstore(t, bs)
+}
+
+func TestDirectorySharding(t *testing.T) {
+	dir := t.TempDir()
+	bs, err := blockstore.NewDirectory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e
+	c := cid.HashBytes([]byte("hello world"))
+	if err := bs.Put(c, []byte("hello world")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "n5", c.String())); err != nil {
+		t.Fatal(err)
+	}
+	// Stray files are ignored
+	if err := os.WriteFile(filepath.Join(dir, "n5", ".tmp-123"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, err := range bs.AllKeys() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if n != 1 {
+		t.Fatalf("got %d keys, want 1", n)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	bs := blockstore.NewMemory()
+	data := []byte("hello world")
+	c := cid.HashBytes(data)
+	if err := bs.Put(c, data); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(blockstore.Handler(bs))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/.well-known/rasl/" + c.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "hello world" {
+		t.Fatalf("got %d %q, want 200 %q", resp.StatusCode, body, data)
+	}
+
+	missing := cid.HashBytes([]byte("missing"))
+	resp, err = http.Get(srv.URL + "/.well-known/rasl/" + missing.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("got status %d, want 404", resp.StatusCode)
+	}
+}
@@ -0,0 +1,165 @@
+package blockstore
+
+import (
+	"errors"
+	"io/fs"
+	"iter"
+	"os"
+	"path/filepath"
+
+	"github.com/hyphacoop/go-dasl/cid"
+)
+
+// Directory is a Blockstore that stores each block as a file on disk.
+//
+// Files are named after their CID, and sharded into subdirectories named after
+// the next-to-last two characters of the CID string, like the flatfs datastore.
+// For example, bafkreifn5yxi7nkftsn46b6x26grda57ict7md2xuvfbsgkiahe2e7vnq4 is stored
+// at "nq/bafkreifn5yxi7nkftsn46b6x26grda57ict7md2xuvfbsgkiahe2e7vnq4".
+//
+// Files are written atomically, so a block is never partially visible.
+type Directory struct {
+	dir string
+}
+
+var _ Blockstore = &Directory{}
+
+// NewDirectory returns a Directory that stores blocks in dir.
+// The directory is created if it doesn't exist.
+func NewDirectory(dir string) (*Directory, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Directory{dir: dir}, nil
+}
+
+// path returns the shard directory and the file path for the CID,
+// or ErrUndefinedCid.
+func (d *Directory) path(c cid.Cid) (string, string, error) {
+	if !c.Defined() {
+		return "", "", ErrUndefinedCid
+	}
+	s := c.String()
+	shard := filepath.Join(d.dir, s[len(s)-3:len(s)-1])
+	return shard, filepath.Join(shard, s), nil
+}
+
+func (d *Directory) Get(c cid.Cid) ([]byte, error) {
+	_, p, err := d.path(c)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (d *Directory) Put(c cid.Cid, data []byte) error {
+	shard, p, err := d.path(c)
+	if err != nil {
+		return err
+	}
+	if !c.VerifyBytes(data) {
+		return ErrCidMismatch
+	}
+	if _, err := os.Stat(p); err == nil {
+		// Already stored
+		return nil
+	}
+	if err := os.MkdirAll(shard, 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename it into place
+	f, err := os.CreateTemp(shard, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
+
+func (d *Directory) Has(c cid.Cid) (bool, error) {
+	_, p, err := d.path(c)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (d *Directory) Delete(c cid.Cid) error {
+	_, p, err := d.path(c)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (d *Directory) GetSize(c cid.Cid) (int, error) {
+	_, p, err := d.path(c)
+	if err != nil {
+		return 0, err
+	}
+	fi, err := os.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int(fi.Size()), nil
+}
+
+// AllKeys iterates over the CIDs stored on disk. Files that are not named after
+// a CID, such as temporary files, are skipped.
+func (d *Directory) AllKeys() iter.Seq2[cid.Cid, error] {
+	return func(yield func(cid.Cid, error) bool) {
+		shards, err := os.ReadDir(d.dir)
+		if err != nil {
+			yield(cid.Cid{}, err)
+			return
+		}
+		for _, shard := range shards {
+			if !shard.IsDir() {
+				continue
+			}
+			entries, err := os.ReadDir(filepath.Join(d.dir, shard.Name()))
+			if err != nil {
+				yield(cid.Cid{}, err)
+				return
+			}
+			for _, e := range entries {
+				if !e.Type().IsRegular() {
+					continue
+				}
+				c, err := cid.NewCidFromString(e.Name())
+				if err != nil {
+					continue
+				}
+				if !yield(c, nil) {
+					return
+				}
+			}
+		}
+	}
+}
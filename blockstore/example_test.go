@@ -0,0 +1,24 @@
+package blockstore_test
+
+import (
+	"fmt"
+
+	"github.com/hyphacoop/go-dasl/blockstore"
+	"github.com/hyphacoop/go-dasl/cid"
+)
+
+func Example() {
+	bs := blockstore.NewMemory()
+	data := []byte("hello world")
+	c := cid.HashBytes(data)
+	if err := bs.Put(c, data); err != nil {
+		panic(err)
+	}
+	got, err := bs.Get(c)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(c, string(got))
+	// Output:
+	// bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e hello world
+}
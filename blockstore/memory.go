@@ -0,0 +1,105 @@
+package blockstore
+
+import (
+	"iter"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/hyphacoop/go-dasl/cid"
+)
+
+// Memory is a Blockstore that keeps blocks in memory.
+// The zero value is an empty Memory ready to use.
+type Memory struct {
+	mu     sync.RWMutex
+	blocks map[cid.Cid][]byte
+}
+
+var _ Blockstore = &Memory{}
+
+// NewMemory returns an empty Memory.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Get(c cid.Cid) ([]byte, error) {
+	if !c.Defined() {
+		return nil, ErrUndefinedCid
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.blocks[c]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return slices.Clone(data), nil
+}
+
+// Put stores the data under the CID. The data is copied.
+func (m *Memory) Put(c cid.Cid, data []byte) error {
+	if !c.Defined() {
+		return ErrUndefinedCid
+	}
+	if !c.VerifyBytes(data) {
+		return ErrCidMismatch
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.blocks == nil {
+		m.blocks = make(map[cid.Cid][]byte)
+	}
+	if _, ok := m.blocks[c]; !ok {
+		// Never store nil, so Get always returns a non-nil slice
+		m.blocks[c] = append(make([]byte, 0, len(data)), data...)
+	}
+	return nil
+}
+
+func (m *Memory) Has(c cid.Cid) (bool, error) {
+	if !c.Defined() {
+		return false, ErrUndefinedCid
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.blocks[c]
+	return ok, nil
+}
+
+func (m *Memory) Delete(c cid.Cid) error {
+	if !c.Defined() {
+		return ErrUndefinedCid
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.blocks, c)
+	return nil
+}
+
+func (m *Memory) GetSize(c cid.Cid) (int, error) {
+	if !c.Defined() {
+		return 0, ErrUndefinedCid
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.blocks[c]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	return len(data), nil
+}
+
+// AllKeys iterates over a snapshot of the stored CIDs, taken when iteration starts.
+// It never yields an error.
+func (m *Memory) AllKeys() iter.Seq2[cid.Cid, error] {
+	return func(yield func(cid.Cid, error) bool) {
+		m.mu.RLock()
+		keys := slices.Collect(maps.Keys(m.blocks))
+		m.mu.RUnlock()
+		for _, c := range keys {
+			if !yield(c, nil) {
+				return
+			}
+		}
+	}
+}
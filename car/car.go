@@ -0,0 +1,360 @@
+/*
+Package car implements reading and writing CAR (Content Addressable aRchive) v1 files.
+
+A CAR file is a DRISL header followed by a stream of blocks. Each block is
+length-prefixed and made up of a binary CID and the data it addresses.
+
+By default only DASL CIDs are accepted, and every block read is verified against its CID.
+Reading non-DASL CIDs can be enabled with ReaderOptions.UseRawCid.
+
+https://dasl.ing/car.html
+*/
+package car
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+	"lukechampine.com/blake3"
+)
+
+const (
+	// DefaultMaxHeaderSize is the default maximum size of the CAR header in bytes.
+	DefaultMaxHeaderSize = 32 << 20
+
+	// DefaultMaxBlockSize is the default maximum size of a single block section
+	// (CID and data) in bytes.
+	DefaultMaxBlockSize = 8 << 20
+)
+
+var (
+	ErrCidMismatch        = errors.New("go-dasl/car: block data doesn't match CID")
+	ErrUnsupportedVersion = errors.New("go-dasl/car: unsupported CAR version")
+	ErrHeaderTooLarge     = errors.New("go-dasl/car: header is too large")
+	ErrBlockTooLarge      = errors.New("go-dasl/car: block is too large")
+	ErrRawCidDisabled     = errors.New("go-dasl/car: raw CIDs are not enabled")
+
+	errInvalidCid = errors.New("go-dasl/car: invalid CID in block")
+
+	rawDecMode drisl.DecMode
+)
+
+func init() {
+	var err error
+	rawDecMode, err = drisl.DecOptions{UseRawCid: true}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Header is the CAR v1 header.
+type Header struct {
+	Version int       `cbor:"version"`
+	Roots   []cid.Cid `cbor:"roots"`
+}
+
+type rawHeader struct {
+	Version int          `cbor:"version"`
+	Roots   []cid.RawCid `cbor:"roots"`
+}
+
+// ReaderOptions specifies options for reading CAR files.
+type ReaderOptions struct {
+	// UseRawCid allows CIDs that are not DASL CIDs, such as CIDv0 or CIDs using
+	// other codecs. Use NextRaw or RawBlocks to read them, and RawRoots to get
+	// the header roots.
+	//
+	// Blocks with non-DASL CIDs are verified if they use a 32-byte SHA-256 or BLAKE3
+	// hash, and otherwise are returned unverified.
+	// Only do this if you are not working in a DASL-compliant ecosystem!
+	UseRawCid bool
+
+	// MaxHeaderSize is the maximum size of the header in bytes.
+	// Default is DefaultMaxHeaderSize.
+	MaxHeaderSize int
+
+	// MaxBlockSize is the maximum size of a block section in bytes,
+	// including the CID. Default is DefaultMaxBlockSize.
+	MaxBlockSize int
+
+	// SkipVerify returns blocks without checking that their data matches their CID.
+	// It is up to the caller to verify them, for example to report every mismatched
+	// block instead of stopping at the first one.
+	SkipVerify bool
+}
+
+// Reader reads blocks from a CAR v1 stream.
+type Reader struct {
+	br       *bufio.Reader
+	opts     ReaderOptions
+	header   Header
+	rawHead  []byte
+	rawRoots []cid.RawCid
+	err      error
+}
+
+// NewReader reads the CAR header from r using the default options, and returns a
+// Reader that can be used to read the blocks that follow.
+func NewReader(r io.Reader) (*Reader, error) {
+	return ReaderOptions{}.NewReader(r)
+}
+
+// NewReader reads the CAR header from r, and returns a Reader that can be used
+// to read the blocks that follow.
+func (opts ReaderOptions) NewReader(r io.Reader) (*Reader, error) {
+	if opts.MaxHeaderSize <= 0 {
+		opts.MaxHeaderSize = DefaultMaxHeaderSize
+	}
+	if opts.MaxBlockSize <= 0 {
+		opts.MaxBlockSize = DefaultMaxBlockSize
+	}
+	cr := &Reader{
+		br:   bufio.NewReader(r),
+		opts: opts,
+	}
+
+	size, err := binary.ReadUvarint(cr.br)
+	if err != nil {
+		return nil, fixEOF(err)
+	}
+	if size == 0 {
+		return nil, errors.New("go-dasl/car: empty header")
+	}
+	if size > uint64(opts.MaxHeaderSize) {
+		return nil, ErrHeaderTooLarge
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(cr.br, b); err != nil {
+		return nil, fixEOF(err)
+	}
+
+	if opts.UseRawCid {
+		var h rawHeader
+		if err := rawDecMode.Unmarshal(b, &h); err != nil {
+			return nil, fmt.Errorf("go-dasl/car: invalid header: %w", err)
+		}
+		cr.header.Version = h.Version
+		cr.rawRoots = h.Roots
+	} else {
+		if err := drisl.Unmarshal(b, &cr.header); err != nil {
+			return nil, fmt.Errorf("go-dasl/car: invalid header: %w", err)
+		}
+	}
+	if cr.header.Version != 1 {
+		return nil, ErrUnsupportedVersion
+	}
+	cr.rawHead = b
+	return cr, nil
+}
+
+// Header returns the CAR header.
+//
+// If the reader was created with UseRawCid, the Roots field is nil and RawRoots
+// should be used instead.
+func (cr *Reader) Header() Header {
+	return cr.header
+}
+
+// RawHeader returns the encoded header, which can be decoded to get any
+// extra fields it has. It has been validated as DRISL, using the CID options of the reader.
+func (cr *Reader) RawHeader() []byte {
+	return cr.rawHead
+}
+
+// RawRoots returns the header roots as raw CIDs.
+// It is only set if the reader was created with UseRawCid.
+func (cr *Reader) RawRoots() []cid.RawCid {
+	return cr.rawRoots
+}
+
+// Next reads the next block, returning its CID and data.
+// The data has been verified against the CID, unless SkipVerify is set.
+//
+// io.EOF is returned when there are no more blocks. If the block CID is not
+// a DASL CID, a cid.ForbiddenCidError is returned.
+func (cr *Reader) Next() (cid.Cid, []byte, error) {
+	cidBytes, data, err := cr.next()
+	if err != nil {
+		return cid.Cid{}, nil, err
+	}
+	c, err := cid.NewCidFromBytes(cidBytes)
+	if err != nil {
+		return cid.Cid{}, nil, err
+	}
+	return c, data, nil
+}
+
+// NextRaw reads the next block, returning its raw CID and data.
+// It can only be used if the reader was created with UseRawCid.
+//
+// io.EOF is returned when there are no more blocks.
+func (cr *Reader) NextRaw() (cid.RawCid, []byte, error) {
+	if !cr.opts.UseRawCid {
+		return nil, nil, ErrRawCidDisabled
+	}
+	cidBytes, data, err := cr.next()
+	if err != nil {
+		return nil, nil, err
+	}
+	return cid.RawCid(cidBytes), data, nil
+}
+
+// Blocks returns an iterator over the remaining blocks.
+// Iteration stops at the end of the CAR or at the first error, which can be
+// retrieved by calling Err afterward.
+func (cr *Reader) Blocks() iter.Seq2[cid.Cid, []byte] {
+	return func(yield func(cid.Cid, []byte) bool) {
+		for {
+			c, data, err := cr.Next()
+			if err != nil {
+				if err != io.EOF {
+					cr.err = err
+				}
+				return
+			}
+			if !yield(c, data) {
+				return
+			}
+		}
+	}
+}
+
+// RawBlocks is the same as Blocks, but for raw CIDs.
+// It can only be used if the reader was created with UseRawCid.
+func (cr *Reader) RawBlocks() iter.Seq2[cid.RawCid, []byte] {
+	return func(yield func(cid.RawCid, []byte) bool) {
+		for {
+			c, data, err := cr.NextRaw()
+			if err != nil {
+				if err != io.EOF {
+					cr.err = err
+				}
+				return
+			}
+			if !yield(c, data) {
+				return
+			}
+		}
+	}
+}
+
+// Err returns the first error encountered by Blocks or RawBlocks, if any.
+func (cr *Reader) Err() error {
+	return cr.err
+}
+
+// next reads a block section and returns the CID bytes and data, after verifying them
+// unless SkipVerify is set.
+func (cr *Reader) next() ([]byte, []byte, error) {
+	size, err := binary.ReadUvarint(cr.br)
+	if err != nil {
+		if err == io.EOF {
+			// Clean end of the CAR
+			return nil, nil, io.EOF
+		}
+		return nil, nil, fixEOF(err)
+	}
+	if size == 0 {
+		return nil, nil, errors.New("go-dasl/car: empty block section")
+	}
+	if size > uint64(cr.opts.MaxBlockSize) {
+		return nil, nil, ErrBlockTooLarge
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(cr.br, b); err != nil {
+		return nil, nil, fixEOF(err)
+	}
+
+	var cidLen int
+	if cr.opts.UseRawCid {
+		cidLen, err = rawCidLen(b)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		if _, err := cid.NewCidFromBytes(b[:min(len(b), cid.CidBinaryLength)]); err != nil {
+			return nil, nil, err
+		}
+		cidLen = cid.CidBinaryLength
+	}
+
+	cidBytes, data := b[:cidLen:cidLen], b[cidLen:]
+	if !cr.opts.SkipVerify && !verify(cidBytes, data) {
+		return nil, nil, ErrCidMismatch
+	}
+	return cidBytes, data, nil
+}
+
+// verify checks the data against the binary CID, if the hash function is supported.
+func verify(cidBytes, data []byte) bool {
+	if len(cidBytes) == cid.CidBinaryLength {
+		if c, err := cid.NewCidFromBytes(cidBytes); err == nil {
+			return c.VerifyBytes(data)
+		}
+	}
+
+	// Raw CID, look at the multihash directly
+	var mh []byte
+	if len(cidBytes) == 34 && cidBytes[0] == 0x12 && cidBytes[1] == 0x20 {
+		// CIDv0
+		mh = cidBytes
+	} else {
+		// Skip version and codec varints
+		_, n1 := binary.Uvarint(cidBytes)
+		_, n2 := binary.Uvarint(cidBytes[n1:])
+		mh = cidBytes[n1+n2:]
+	}
+	if len(mh) != 2+cid.HashSize || mh[1] != cid.HashSize {
+		// Unknown digest size, can't verify
+		return true
+	}
+	var digest [cid.HashSize]byte
+	switch cid.HashType(mh[0]) {
+	case cid.HashTypeSha256:
+		digest = sha256.Sum256(data)
+	case cid.HashTypeBlake3:
+		digest = blake3.Sum256(data)
+	default:
+		// Unknown hash function, can't verify
+		return true
+	}
+	return bytes.Equal(mh[2:], digest[:])
+}
+
+// rawCidLen returns the length of the binary CID at the start of b.
+func rawCidLen(b []byte) (int, error) {
+	if len(b) >= 34 && b[0] == 0x12 && b[1] == 0x20 {
+		// CIDv0 is a bare SHA-256 multihash
+		return 34, nil
+	}
+	// CIDv1: version, codec, hash type, and digest length varints, then the digest
+	i := 0
+	var v uint64
+	for j := range 4 {
+		var n int
+		v, n = binary.Uvarint(b[i:])
+		if n <= 0 || (j == 0 && v != 1) {
+			return 0, errInvalidCid
+		}
+		i += n
+	}
+	if v > uint64(len(b)-i) {
+		return 0, errInvalidCid
+	}
+	return i + int(v), nil
+}
+
+func fixEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,278 @@
+package car_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"maps"
+	"testing"
+
+	"github.com/hyphacoop/go-dasl/car"
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+)
+
+func testBlocks(t *testing.T) map[cid.Cid][]byte {
+	blocks := make(map[cid.Cid][]byte)
+	for _, s := range []string{"hello", "world", ""} {
+		blocks[cid.HashBytes([]byte(s))] = []byte(s)
+	}
+	blocks[cid.HashBytesBlake3([]byte("blake3"))] = []byte("blake3")
+	b, err := drisl.Marshal(map[string]any{"a": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := drisl.CidForValue(map[string]any{"a": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks[c] = b
+	return blocks
+}
+
+// section creates a length-prefixed CAR section.
+func section(parts ...[]byte) []byte {
+	data := bytes.Join(parts, nil)
+	return append(binary.AppendUvarint(nil, uint64(len(data))), data...)
+}
+
+func TestRoundtrip(t *testing.T) {
+	blocks := testBlocks(t)
+	root := cid.HashBytes([]byte("hello"))
+
+	var buf bytes.Buffer
+	if err := car.Write(&buf, []cid.Cid{root}, maps.All(blocks)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	cr, err := car.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	h := cr.Header()
+	if h.Version != 1 {
+		t.Fatalf("got version %d, want 1", h.Version)
+	}
+	if len(h.Roots) != 1 || !h.Roots[0].Equal(root) {
+		t.Fatalf("got roots %v, want [%s]", h.Roots, root)
+	}
+
+	got := make(map[cid.Cid][]byte)
+	for c, data := range cr.Blocks() {
+		got[c] = data
+	}
+	if err := cr.Err(); err != nil {
+		t.Fatalf("Blocks failed: %v", err)
+	}
+	if !maps.EqualFunc(got, blocks, bytes.Equal) {
+		t.Fatalf("got blocks %v, want %v", got, blocks)
+	}
+}
+
+func TestNoRoots(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := car.NewWriter(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	cr, err := car.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	if len(cr.Header().Roots) != 0 {
+		t.Fatalf("got roots %v, want none", cr.Header().Roots)
+	}
+	if _, _, err := cr.Next(); err != io.EOF {
+		t.Fatalf("got %v, want io.EOF", err)
+	}
+}
+
+func TestCidMismatch(t *testing.T) {
+	var buf bytes.Buffer
+	cw, err := car.NewWriter(&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.WriteBlock(cid.HashBytes([]byte("hello")), []byte("goodbye")); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	cr, err := car.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cr.Next(); err != car.ErrCidMismatch {
+		t.Fatalf("got %v, want ErrCidMismatch", err)
+	}
+
+	cr, err = car.ReaderOptions{SkipVerify: true}.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, got, err := cr.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != cid.HashBytes([]byte("hello")) || string(got) != "goodbye" {
+		t.Fatalf("got %s %q with SkipVerify", c, got)
+	}
+}
+
+func TestUnsupportedVersion(t *testing.T) {
+	b, err := drisl.Marshal(map[string]any{"version": 2, "roots": []cid.Cid{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := car.NewReader(bytes.NewReader(section(b))); err != car.ErrUnsupportedVersion {
+		t.Fatalf("got %v, want ErrUnsupportedVersion", err)
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	cw, err := car.NewWriter(&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.WriteBlock(cid.HashBytes([]byte("hello")), []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	cr, err := car.NewReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cr.Next(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("got %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestBlockTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	cw, err := car.NewWriter(&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 100)
+	if err := cw.WriteBlock(cid.HashBytes(data), data); err != nil {
+		t.Fatal(err)
+	}
+
+	cr, err := car.ReaderOptions{MaxBlockSize: 64}.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cr.Next(); err != car.ErrBlockTooLarge {
+		t.Fatalf("got %v, want ErrBlockTooLarge", err)
+	}
+}
+
+func TestRawCid(t *testing.T) {
+	data := []byte("hello")
+	digest := sha256.Sum256(data)
+	// CIDv0 and a dag-pb CIDv1, neither are DASL
+	v0 := append([]byte{0x12, 0x20}, digest[:]...)
+	dagPb := append([]byte{0x01, 0x70, 0x12, 0x20}, digest[:]...)
+
+	var buf bytes.Buffer
+	cw, err := car.NewWriter(&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []cid.RawCid{v0, dagPb} {
+		if err := cw.WriteRawBlock(c, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	carBytes := buf.Bytes()
+
+	// Rejected by default
+	cr, err := car.NewReader(bytes.NewReader(carBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fce *cid.ForbiddenCidError
+	if _, _, err := cr.Next(); !errors.As(err, &fce) {
+		t.Fatalf("got %v, want ForbiddenCidError", err)
+	}
+	if _, _, err := cr.NextRaw(); err != car.ErrRawCidDisabled {
+		t.Fatalf("got %v, want ErrRawCidDisabled", err)
+	}
+
+	// Allowed with option
+	cr, err = car.ReaderOptions{UseRawCid: true}.NewReader(bytes.NewReader(carBytes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []cid.RawCid
+	for c, d := range cr.RawBlocks() {
+		if !bytes.Equal(d, data) {
+			t.Fatalf("got data %q, want %q", d, data)
+		}
+		got = append(got, c)
+	}
+	if err := cr.Err(); err != nil {
+		t.Fatalf("RawBlocks failed: %v", err)
+	}
+	if len(got) != 2 || !bytes.Equal(got[0], v0) || !bytes.Equal(got[1], dagPb) {
+		t.Fatalf("got CIDs %x, want [%x %x]", got, v0, dagPb)
+	}
+}
+
+func TestRawCidMismatch(t *testing.T) {
+	digest := sha256.Sum256([]byte("hello"))
+	var buf bytes.Buffer
+	cw, err := car.NewWriter(&buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.WriteRawBlock(append([]byte{0x12, 0x20}, digest[:]...), []byte("goodbye")); err != nil {
+		t.Fatal(err)
+	}
+
+	cr, err := car.ReaderOptions{UseRawCid: true}.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cr.NextRaw(); err != car.ErrCidMismatch {
+		t.Fatalf("got %v, want ErrCidMismatch", err)
+	}
+}
+
+func TestCustomHeader(t *testing.T) {
+	type header struct {
+		Version int       `cbor:"version"`
+		Roots   []cid.Cid `cbor:"roots"`
+		Name    string    `cbor:"name"`
+	}
+	root := cid.HashBytes([]byte("hello"))
+
+	var buf bytes.Buffer
+	cw, err := car.NewWriterWithHeader(&buf, header{1, []cid.Cid{root}, "extra"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.WriteBlock(root, []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	cr, err := car.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	if h := cr.Header(); len(h.Roots) != 1 || !h.Roots[0].Equal(root) {
+		t.Fatalf("got roots %v, want [%s]", h.Roots, root)
+	}
+	var h header
+	if err := drisl.Unmarshal(cr.RawHeader(), &h); err != nil {
+		t.Fatal(err)
+	}
+	if h.Name != "extra" {
+		t.Fatalf("got name %q, want %q", h.Name, "extra")
+	}
+	if _, data, err := cr.Next(); err != nil || string(data) != "hello" {
+		t.Fatalf("Next = %q, %v", data, err)
+	}
+}
@@ -0,0 +1,40 @@
+package car_test
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/hyphacoop/go-dasl/car"
+	"github.com/hyphacoop/go-dasl/cid"
+)
+
+func Example() {
+	data := []byte("hello world")
+	c := cid.HashBytes(data)
+
+	// Write a CAR with a single block
+	var buf bytes.Buffer
+	cw, err := car.NewWriter(&buf, []cid.Cid{c})
+	if err != nil {
+		panic(err)
+	}
+	if err := cw.WriteBlock(c, data); err != nil {
+		panic(err)
+	}
+
+	// Read it back
+	cr, err := car.NewReader(&buf)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("Roots: %v\n", cr.Header().Roots)
+	for c, data := range cr.Blocks() {
+		fmt.Printf("%s: %s\n", c, data)
+	}
+	if err := cr.Err(); err != nil {
+		panic(err)
+	}
+	// Output:
+	// Roots: [bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e]
+	// bafkreifzjut3te2nhyekklss27nh3k72ysco7y32koao5eei66wof36n5e: hello world
+}
@@ -0,0 +1,94 @@
+package car
+
+import (
+	"encoding/binary"
+	"io"
+	"iter"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+)
+
+// Writer writes blocks to a CAR v1 stream.
+//
+// It is up to the caller to make sure the block data actually matches the
+// hash digest of the CID it's written with.
+type Writer struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewWriter writes a CAR v1 header with the given roots to w, and returns a
+// Writer that can be used to write blocks after it.
+func NewWriter(w io.Writer, roots []cid.Cid) (*Writer, error) {
+	if roots == nil {
+		// Encode as an empty array, not null
+		roots = []cid.Cid{}
+	}
+	return NewWriterWithHeader(w, Header{Version: 1, Roots: roots})
+}
+
+// NewWriterWithHeader is like NewWriter, but writes a custom header. The header is
+// encoded with DRISL, and can contain extra fields as long as it has the version
+// and roots fields of a CAR v1 header. This is used to store MASL documents as headers.
+//
+// The header fields are not checked.
+func NewWriterWithHeader(w io.Writer, header any) (*Writer, error) {
+	b, err := drisl.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	cw := &Writer{w: w}
+	if err := cw.writeSection(b); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// WriteBlock writes a single block.
+func (cw *Writer) WriteBlock(c cid.Cid, data []byte) error {
+	if !c.Defined() {
+		return cid.ErrUndefinedCid
+	}
+	cidBytes, _ := c.MarshalBinary()
+	return cw.writeSection(cidBytes, data)
+}
+
+// WriteRawBlock writes a single block with a raw CID.
+// Only do this if you are not working in a DASL-compliant ecosystem!
+func (cw *Writer) WriteRawBlock(c cid.RawCid, data []byte) error {
+	return cw.writeSection(c, data)
+}
+
+// writeSection writes the varint length prefix and then all the provided parts.
+func (cw *Writer) writeSection(parts ...[]byte) error {
+	size := 0
+	for _, p := range parts {
+		size += len(p)
+	}
+	cw.buf = binary.AppendUvarint(cw.buf[:0], uint64(size))
+	if _, err := cw.w.Write(cw.buf); err != nil {
+		return err
+	}
+	for _, p := range parts {
+		if _, err := cw.w.Write(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write writes a complete CAR v1 file to w, with the given roots and all the blocks
+// from the iterator.
+func Write(w io.Writer, roots []cid.Cid, blocks iter.Seq2[cid.Cid, []byte]) error {
+	cw, err := NewWriter(w, roots)
+	if err != nil {
+		return err
+	}
+	for c, data := range blocks {
+		if err := cw.WriteBlock(c, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package cid
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"io"
+
+	"lukechampine.com/blake3"
+)
+
+// ErrCidMismatch is returned by a VerifyingReader when the data doesn't match the CID.
+var ErrCidMismatch = errors.New("go-dasl/cid: data doesn't match CID")
+
+// Builder computes a CID incrementally from data written to it, for when the data
+// is not available as a single io.Reader, like chunks received over time.
+//
+// Builder implements io.Writer, so it can be used with io.MultiWriter or io.TeeReader
+// to compute a CID while the data is written elsewhere. Writes never return an error.
+type Builder struct {
+	codec    Codec
+	hashType HashType
+	hasher   hash.Hash
+	size     int64
+}
+
+// NewBuilder returns a Builder for CIDs with the given codec and hash type.
+// An error is only returned if the codec or hashType provided don't conform to DASL.
+func NewBuilder(codec Codec, hashType HashType) (*Builder, error) {
+	if codec != CodecRaw && codec != CodecDrisl {
+		return nil, &ForbiddenCidError{"invalid codec"}
+	}
+	if hashType != HashTypeSha256 && hashType != HashTypeBlake3 {
+		return nil, &ForbiddenCidError{"invalid hash type"}
+	}
+	b := &Builder{codec: codec, hashType: hashType}
+	if hashType == HashTypeSha256 {
+		b.hasher = sha256.New()
+	} else {
+		b.hasher = blake3.New(HashSize, nil)
+	}
+	return b, nil
+}
+
+// Write adds data to the hash. It always returns len(p), nil.
+func (b *Builder) Write(p []byte) (int, error) {
+	b.size += int64(len(p))
+	return b.hasher.Write(p)
+}
+
+// Sum returns the CID of the data written so far.
+// It does not change the state of the Builder, so more data can be written after.
+func (b *Builder) Sum() Cid {
+	cid := Cid{[CidBinaryLength]byte{CidVersion, byte(b.codec), byte(b.hashType), HashSize}}
+	copy(cid.b[dgIdx:], b.hasher.Sum(nil))
+	return cid
+}
+
+// Size returns the number of bytes written so far.
+func (b *Builder) Size() int64 {
+	return b.size
+}
+
+// Reset discards the data written so far.
+func (b *Builder) Reset() {
+	b.hasher.Reset()
+	b.size = 0
+}
+
+// VerifyingReader reads data while checking that it matches a CID.
+// The CID can only be confirmed once all the data has been read out: if it doesn't
+// match, ErrCidMismatch is returned by the last Read call instead of io.EOF.
+type VerifyingReader struct {
+	cid    Cid
+	r      io.Reader
+	hasher hash.Hash
+}
+
+// NewVerifyingReader returns a VerifyingReader that reads from r and verifies the data
+// against c. The codec of c is not checked.
+func NewVerifyingReader(r io.Reader, c Cid) *VerifyingReader {
+	return &VerifyingReader{cid: c, r: r, hasher: c.Hasher()}
+}
+
+func (vr *VerifyingReader) Read(p []byte) (n int, err error) {
+	n, err = vr.r.Read(p)
+	if n > 0 {
+		vr.hasher.Write(p[:n])
+	}
+	if err == io.EOF {
+		// All bytes have been read
+		// Check hash and report
+		if !bytes.Equal(vr.cid.b[dgIdx:], vr.hasher.Sum(nil)) {
+			return 0, ErrCidMismatch
+		}
+	}
+	return
+}
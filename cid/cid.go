@@ -240,6 +240,34 @@ func HashBytesBlake3(b []byte) Cid {
 	return cid
 }
 
+// HashDrisl creates a DRISL CID by hashing the provided DRISL-encoded bytes with the
+// given hash type. The bytes are not checked to be valid DRISL.
+// An error is only returned if hashType is not HashTypeSha256 or HashTypeBlake3.
+func HashDrisl(b []byte, hashType HashType) (Cid, error) {
+	var digest [HashSize]byte
+	switch hashType {
+	case HashTypeSha256:
+		digest = sha256.Sum256(b)
+	case HashTypeBlake3:
+		digest = blake3.Sum256(b)
+	default:
+		return Cid{}, &ForbiddenCidError{"invalid hash type"}
+	}
+	// Quick version of NewCidFromInfo
+	cid := Cid{[CidBinaryLength]byte{CidVersion, byte(CodecDrisl), byte(hashType), HashSize}}
+	copy(cid.b[dgIdx:], digest[:])
+	return cid, nil
+}
+
+// MustHashDrisl calls HashDrisl and panics if it returns an error.
+func MustHashDrisl(b []byte, hashType HashType) Cid {
+	c, err := HashDrisl(b, hashType)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
 // HashReader creates a raw SHA-256 CID by hashing all the data in the reader.
 // Any error returned comes from the reader.
 func HashReader(r io.Reader) (Cid, error) {
@@ -5,8 +5,10 @@ import (
 	"bytes"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"io"
 	"os"
+	"slices"
 	"testing"
 
 	"github.com/hyphacoop/go-dasl/cid"
@@ -220,3 +222,85 @@ func TestVerifyBytes(t *testing.T) {
 		t.Errorf("HashBytes -> VerifyBytes is broken")
 	}
 }
+
+func TestHashDrisl(t *testing.T) {
+	data := []byte{0xa0} // Empty map
+	for _, ht := range []cid.HashType{cid.HashTypeSha256, cid.HashTypeBlake3} {
+		c, err := cid.HashDrisl(data, ht)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Codec() != cid.CodecDrisl || c.HashType() != ht || !c.VerifyBytes(data) {
+			t.Errorf("got %s for hash type %#x", c, ht)
+		}
+	}
+	if cid.MustHashDrisl(data, cid.HashTypeBlake3).Digest() != cid.HashBytesBlake3(data).Digest() {
+		t.Errorf("BLAKE3 digest doesn't match HashBytesBlake3")
+	}
+	var forbidden *cid.ForbiddenCidError
+	if _, err := cid.HashDrisl(data, 0x11); !errors.As(err, &forbidden) {
+		t.Errorf("got %v for invalid hash type, want ForbiddenCidError", err)
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	data := []byte("hello world, in chunks")
+	for _, ht := range []cid.HashType{cid.HashTypeSha256, cid.HashTypeBlake3} {
+		b, err := cid.NewBuilder(cid.CodecRaw, ht)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for chunk := range slices.Chunk(data, 5) {
+			b.Write(chunk)
+		}
+		want, empty := cid.HashBytes(data), cid.HashBytes(nil)
+		if ht == cid.HashTypeBlake3 {
+			want, empty = cid.HashBytesBlake3(data), cid.HashBytesBlake3(nil)
+		}
+		if got := b.Sum(); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+		if b.Size() != int64(len(data)) {
+			t.Errorf("got size %d, want %d", b.Size(), len(data))
+		}
+
+		b.Reset()
+		if got := b.Sum(); got != empty {
+			t.Errorf("got %s after Reset, want %s", got, empty)
+		}
+	}
+
+	b, err := cid.NewBuilder(cid.CodecDrisl, cid.HashTypeSha256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Write([]byte{0xa0})
+	if got, want := b.Sum(), cid.MustHashDrisl([]byte{0xa0}, cid.HashTypeSha256); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	if _, err := cid.NewBuilder(0x70, cid.HashTypeSha256); err == nil {
+		t.Errorf("expected error for invalid codec")
+	}
+	if _, err := cid.NewBuilder(cid.CodecRaw, 0x13); err == nil {
+		t.Errorf("expected error for invalid hash type")
+	}
+}
+
+func TestVerifyingReader(t *testing.T) {
+	data := []byte("hello world")
+	for _, c := range []cid.Cid{cid.HashBytes(data), cid.HashBytesBlake3(data)} {
+		got, err := io.ReadAll(cid.NewVerifyingReader(bytes.NewReader(data), c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("got %q, want %q", got, data)
+		}
+	}
+
+	r := cid.NewVerifyingReader(bytes.NewReader(data), cid.HashBytes([]byte("goodbye")))
+	if _, err := io.ReadAll(r); err != cid.ErrCidMismatch {
+		t.Errorf("got %v, want ErrCidMismatch", err)
+	}
+}
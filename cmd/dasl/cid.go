@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+)
+
+func cidHash(e *env, args []string) error {
+	fs := e.newFlagSet("cid hash", "[-blake3] [-drisl] FILE")
+	blake3 := fs.Bool("blake3", false, "hash with BLAKE3 instead of SHA-256")
+	drislCodec := fs.Bool("drisl", false, "use the DRISL codec instead of raw, after checking the input is valid DRISL")
+	if err := parse(fs, args, 0, 1); err != nil {
+		return err
+	}
+
+	var r io.Reader
+	if *drislCodec {
+		// DRISL has to be read fully to be validated
+		data, err := e.readInput(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		if err := drisl.Validate(data); err != nil {
+			return fmt.Errorf("input is not valid DRISL: %w", err)
+		}
+		r = bytes.NewReader(data)
+	} else {
+		f, err := e.openInput(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	var c cid.Cid
+	var err error
+	if *blake3 {
+		c, err = cid.HashReaderBlake3(r)
+	} else {
+		c, err = cid.HashReader(r)
+	}
+	if err != nil {
+		return err
+	}
+	if *drislCodec {
+		c, err = cid.NewCidFromInfo(cid.CodecDrisl, c.HashType(), c.Digest())
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintln(e.stdout, c)
+	return nil
+}
+
+func cidInspect(e *env, args []string) error {
+	fs := e.newFlagSet("cid inspect", "CID")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	c, err := cid.NewCidFromString(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	codec := "raw"
+	if c.Codec() == cid.CodecDrisl {
+		codec = "drisl"
+	}
+	hashType := "sha2-256"
+	if c.HashType() == cid.HashTypeBlake3 {
+		hashType = "blake3"
+	}
+	digest := c.Digest()
+	fmt.Fprintf(e.stdout, "codec:  %s (0x%02x)\n", codec, byte(c.Codec()))
+	fmt.Fprintf(e.stdout, "hash:   %s (0x%02x)\n", hashType, byte(c.HashType()))
+	fmt.Fprintf(e.stdout, "digest: %x\n", digest)
+	fmt.Fprintf(e.stdout, "binary: %x\n", c.Bytes())
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/hyphacoop/go-dasl/dasljson"
+	"github.com/hyphacoop/go-dasl/drisl"
+)
+
+func drislDecode(e *env, args []string) error {
+	fs := e.newFlagSet("drisl decode", "[-format json|diag] [-pretty] [-cids] [FILE]")
+	format := fs.String("format", "json", `output format: "json" or "diag" (CBOR diagnostic notation)`)
+	pretty := fs.Bool("pretty", false, "indent diagnostic notation output")
+	cidStrings := fs.Bool("cids", false, "print CIDs as strings in diagnostic notation")
+	if err := parse(fs, args, 0, 1); err != nil {
+		return err
+	}
+	data, err := e.readInput(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "json":
+		j, err := dasljson.FromDrisl(data)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(e.stdout, "%s\n", j)
+	case "diag":
+		opts := drisl.DiagOptions{CidStrings: *cidStrings}
+		if *pretty {
+			opts.Indent = "  "
+		}
+		diag, err := opts.Diagnose(data)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(e.stdout, diag)
+	default:
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+func drislEncode(e *env, args []string) error {
+	fs := e.newFlagSet("drisl encode", "[-format json|diag] [-hex] [FILE]")
+	format := fs.String("format", "json", `input format: "json" or "diag" (CBOR diagnostic notation)`)
+	asHex := fs.Bool("hex", false, "output hex instead of binary")
+	if err := parse(fs, args, 0, 1); err != nil {
+		return err
+	}
+	data, err := e.readInput(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var b []byte
+	switch *format {
+	case "json":
+		b, err = dasljson.ToDrisl(data)
+	case "diag":
+		b, err = drisl.ParseDiagnostic(string(data))
+	default:
+		fs.Usage()
+		return errUsage
+	}
+	if err != nil {
+		return err
+	}
+	if *asHex {
+		_, err = fmt.Fprintln(e.stdout, hex.EncodeToString(b))
+	} else {
+		_, err = e.stdout.Write(b)
+	}
+	return err
+}
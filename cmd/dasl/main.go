@@ -0,0 +1,112 @@
+// Command dasl is a tool for everyday CID, DRISL, MASL and RASL tasks.
+//
+// Usage:
+//
+//	dasl cid hash [-blake3] [-drisl] FILE
+//	dasl cid inspect CID
+//	dasl drisl decode [-format json|diag] [-pretty] [-cids] [FILE]
+//	dasl drisl encode [-format json|diag] [-hex] [FILE]
+//	dasl masl validate [FILE]
+//	dasl masl build [-blake3] [-car OUT] DIR
+//	dasl rasl fetch [-timeout DURATION] URL
+//	dasl rasl serve [-addr ADDR] [-blake3] [-drisl] DIR
+//
+// Wherever a FILE is accepted, "-" or no FILE means stdin.
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `Usage:
+  dasl cid hash [-blake3] [-drisl] FILE
+  dasl cid inspect CID
+  dasl drisl decode [-format json|diag] [-pretty] [-cids] [FILE]
+  dasl drisl encode [-format json|diag] [-hex] [FILE]
+  dasl masl validate [FILE]
+  dasl masl build [-blake3] [-car OUT] DIR
+  dasl rasl fetch [-timeout DURATION] URL
+  dasl rasl serve [-addr ADDR] [-blake3] [-drisl] DIR
+
+Run "dasl COMMAND SUBCOMMAND -h" for the options of a subcommand.
+`
+
+// errUsage is returned when the command line is invalid.
+// The usage has already been printed.
+var errUsage = errors.New("invalid usage")
+
+// env is what commands use to interact with the outside world, so they can be tested.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type command func(e *env, args []string) error
+
+var commands = map[string]map[string]command{
+	"cid": {
+		"hash":    cidHash,
+		"inspect": cidInspect,
+	},
+	"drisl": {
+		"decode": drislDecode,
+		"encode": drislEncode,
+	},
+	"masl": {
+		"validate": maslValidate,
+		"build":    maslBuild,
+	},
+	"rasl": {
+		"fetch": raslFetch,
+		"serve": raslServe,
+	},
+}
+
+func main() {
+	e := &env{os.Stdin, os.Stdout, os.Stderr}
+	if err := run(e, os.Args[1:]); err != nil {
+		if err != errUsage {
+			fmt.Fprintln(os.Stderr, "dasl:", err)
+			os.Exit(1)
+		}
+		os.Exit(2)
+	}
+}
+
+func run(e *env, args []string) error {
+	if len(args) < 2 {
+		fmt.Fprint(e.stderr, usage)
+		return errUsage
+	}
+	sub, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprint(e.stderr, usage)
+		return errUsage
+	}
+	cmd, ok := sub[args[1]]
+	if !ok {
+		fmt.Fprint(e.stderr, usage)
+		return errUsage
+	}
+	return cmd(e, args[2:])
+}
+
+// readInput reads the named file, or stdin if the name is "-" or empty.
+func (e *env) readInput(name string) ([]byte, error) {
+	if name == "" || name == "-" {
+		return io.ReadAll(e.stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// openInput opens the named file, or stdin if the name is "-" or empty.
+func (e *env) openInput(name string) (io.ReadCloser, error) {
+	if name == "" || name == "-" {
+		return io.NopCloser(e.stdin), nil
+	}
+	return os.Open(name)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyphacoop/go-dasl/cid"
+)
+
+func runTest(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	e := &env{strings.NewReader(stdin), &stdout, &stderr}
+	err := run(e, args)
+	return stdout.String(), err
+}
+
+func TestCidHash(t *testing.T) {
+	out, err := runTest(t, "hello world", "cid", "hash", "-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := cid.HashBytes([]byte("hello world")).String()
+	if strings.TrimSpace(out) != want {
+		t.Fatalf("got %q, want %q", out, want)
+	}
+
+	out, err = runTest(t, "", "cid", "inspect", want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "sha2-256") {
+		t.Fatalf("inspect output missing hash type:\n%s", out)
+	}
+}
+
+func TestCidHashDrisl(t *testing.T) {
+	data := string([]byte{0xa1, 0x61, 0x61, 0x01}) // {"a": 1}
+	out, err := runTest(t, data, "cid", "hash", "-drisl", "-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := cid.HashBytes([]byte(data))
+	want, _ = cid.NewCidFromInfo(cid.CodecDrisl, want.HashType(), want.Digest())
+	if strings.TrimSpace(out) != want.String() {
+		t.Fatalf("got %q, want %q", out, want)
+	}
+
+	if out, err := runTest(t, "hello world", "cid", "hash", "-drisl", "-"); err == nil {
+		t.Fatalf("got %q for invalid DRISL, want error", out)
+	}
+}
+
+func TestDrislRoundtrip(t *testing.T) {
+	out, err := runTest(t, `{"b":[1,"x"],"a":true}`, "drisl", "encode", "-hex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a26161f5616282016178\n"; out != want {
+		t.Fatalf("got %q, want %q", out, want)
+	}
+
+	bin, err := runTest(t, `{"b":[1,"x"],"a":true}`, "drisl", "encode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = runTest(t, bin, "drisl", "decode", "-format", "diag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"a": true, "b": [1, "x"]}` + "\n"; out != want {
+		t.Fatalf("got %q, want %q", out, want)
+	}
+	out, err = runTest(t, `{"b": [1, "x"], "a": true}`, "drisl", "encode", "-format", "diag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != bin {
+		t.Fatalf("got %x, want %x", out, bin)
+	}
+	out, err = runTest(t, bin, "drisl", "decode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"a":true,"b":[1,"x"]}` + "\n"; out != want {
+		t.Fatalf("got %q, want %q", out, want)
+	}
+}
+
+func TestMaslBuildValidate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("<p>hi</p>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := runTest(t, "", "masl", "build", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := runTest(t, doc, "masl", "validate")
+	if err != nil {
+		t.Fatalf("validate failed: %v\n%s", err, out)
+	}
+
+	car := filepath.Join(t.TempDir(), "out.car")
+	if _, err := runTest(t, "", "masl", "build", "-car", car, dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(car); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"cid"},
+		{"nope", "hash"},
+		{"cid", "nope"},
+		{"cid", "inspect"},
+		{"drisl", "decode", "-format", "yaml"},
+	} {
+		if _, err := runTest(t, "", args...); err != errUsage {
+			t.Errorf("%q: got %v, want errUsage", args, err)
+		}
+	}
+}
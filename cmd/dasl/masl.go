@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/hyphacoop/go-dasl/drisl"
+	"github.com/hyphacoop/go-dasl/masl"
+)
+
+func maslValidate(e *env, args []string) error {
+	fs := e.newFlagSet("masl validate", "[FILE]")
+	if err := parse(fs, args, 0, 1); err != nil {
+		return err
+	}
+	data, err := e.readInput(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var doc masl.Document
+	if err := drisl.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	var errs masl.ValidationErrors
+	if err := doc.Validate(); errors.As(err, &errs) {
+		for _, ve := range errs {
+			fmt.Fprintln(e.stdout, ve)
+		}
+		return fmt.Errorf("document has %d violations", len(errs))
+	} else if err != nil {
+		return err
+	}
+	fmt.Fprintln(e.stdout, "valid")
+	return nil
+}
+
+func maslBuild(e *env, args []string) error {
+	fs := e.newFlagSet("masl build", "[-blake3] [-car OUT] DIR")
+	blake3 := fs.Bool("blake3", false, "hash with BLAKE3 instead of SHA-256")
+	carPath := fs.String("car", "", "write a MASL CAR with the document and all resources to this file")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	doc, blocks, err := masl.BundleFromDir(fs.Arg(0), masl.BundleOptions{HashBlake3: *blake3})
+	if err != nil {
+		return err
+	}
+
+	if *carPath == "" {
+		// Just output the document
+		data, err := drisl.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		_, err = e.stdout.Write(data)
+		return err
+	}
+
+	f, err := os.Create(*carPath)
+	if err != nil {
+		return err
+	}
+	if err := masl.WriteCar(f, doc, blocks); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stdout, "wrote %d resources to %s\n", len(doc.Resources), *carPath)
+	return nil
+}
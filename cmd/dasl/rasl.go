@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/hyphacoop/go-dasl/masl"
+	"github.com/hyphacoop/go-dasl/rasl"
+)
+
+func raslFetch(e *env, args []string) error {
+	fs := e.newFlagSet("rasl fetch", "[-timeout DURATION] URL")
+	timeout := fs.Duration("timeout", 30*time.Second, "timeout for each hint to respond")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	u, err := rasl.Parse(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	opts := rasl.FetchOptions{HintTimeout: *timeout}
+
+	var body io.ReadCloser
+	if u.Path != "" {
+		var resource *masl.Resource
+		resource, body, err = masl.Resolve(ctx, u, opts)
+		if err == nil && resource.ContentType != "" {
+			fmt.Fprintln(e.stderr, "content-type:", resource.ContentType)
+		}
+	} else {
+		body, err = u.FetchContext(ctx, opts)
+	}
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	_, err = io.Copy(e.stdout, body)
+	return err
+}
+
+func raslServe(e *env, args []string) error {
+	fs := e.newFlagSet("rasl serve", "[-addr ADDR] [-blake3] [-drisl] DIR")
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	blake3 := fs.Bool("blake3", false, "also serve files by BLAKE3 CID")
+	drisl := fs.Bool("drisl", false, "also serve DRISL files by DRISL CID")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	handler, err := rasl.DirectoryHandlerWithOptions(fs.Arg(0), rasl.DirectoryOptions{
+		HashBlake3: *blake3,
+		IndexDrisl: *drisl,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stderr, "serving %s on http://%s/.well-known/rasl/\n", fs.Arg(0), *addr)
+	return http.ListenAndServe(*addr, handler)
+}
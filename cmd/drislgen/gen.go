@@ -0,0 +1,633 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/types"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type kind int
+
+const (
+	kindOther kind = iota // handled by drisl.Marshal and drisl.Unmarshal
+	kindBool
+	kindString
+	kindInt
+	kindUint
+	kindFloat
+	kindBytes
+	kindCid
+	kindRaw
+	kindStruct // a generated type
+	kindPtr
+	kindSlice
+	kindMap
+)
+
+type fieldType struct {
+	kind kind
+	// goType is the type as written in the source.
+	goType string
+	elem   *fieldType
+}
+
+type field struct {
+	goName    string
+	name      string
+	typ       *fieldType
+	omitEmpty bool
+	omitZero  bool
+}
+
+type structInfo struct {
+	name    string
+	fields  []*field
+	toArray bool
+}
+
+// generator holds the state for generating one file.
+type generator struct {
+	pkg   *pkgInfo
+	types map[string]bool
+	// imports of the file being processed, by local name
+	imports map[string]string
+	// used are the imports needed by the output, by local name
+	used map[string]string
+	buf  bytes.Buffer
+	// varNum is used to create unique variable names
+	varNum int
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// generate returns the formatted source for the generated methods of the types.
+func generate(pkg *pkgInfo, typeNames []string, args []string) ([]byte, error) {
+	g := &generator{
+		pkg:   pkg,
+		types: make(map[string]bool),
+		used: map[string]string{
+			"drisl":      "github.com/hyphacoop/go-dasl/drisl",
+			"gensupport": "github.com/hyphacoop/go-dasl/drisl/gensupport",
+		},
+	}
+	for name := range pkg.generated {
+		g.types[name] = true
+	}
+	for _, name := range typeNames {
+		g.types[name] = true
+	}
+
+	var structs []*structInfo
+	for _, name := range typeNames {
+		decl, ok := pkg.types[name]
+		if !ok {
+			return nil, fmt.Errorf("type %s not found", name)
+		}
+		g.imports = fileImports(decl.file)
+		s, err := g.parseStruct(decl.spec)
+		if err != nil {
+			return nil, fmt.Errorf("type %s: %w", name, err)
+		}
+		structs = append(structs, s)
+	}
+
+	for _, s := range structs {
+		g.genStruct(s)
+	}
+	body := g.buf.Bytes()
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by \"drislgen %s\"; DO NOT EDIT.\n\n", strings.Join(args, " "))
+	fmt.Fprintf(&out, "package %s\n\nimport (\n", pkg.name)
+	names := make([]string, 0, len(g.used))
+	for name := range g.used {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int { return strings.Compare(g.used[a], g.used[b]) })
+	for _, name := range names {
+		p := g.used[name]
+		if name == defaultImportName(p) {
+			fmt.Fprintf(&out, "\t%q\n", p)
+		} else {
+			fmt.Fprintf(&out, "\t%s %q\n", name, p)
+		}
+	}
+	out.WriteString(")\n")
+	out.Write(body)
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting output: %w\n%s", err, out.Bytes())
+	}
+	return src, nil
+}
+
+func defaultImportName(p string) string {
+	name := p[strings.LastIndexByte(p, '/')+1:]
+	if versionSuffix.MatchString(name) {
+		p = p[:strings.LastIndexByte(p, '/')]
+		name = p[strings.LastIndexByte(p, '/')+1:]
+	}
+	return name
+}
+
+func (g *generator) parseStruct(spec *ast.TypeSpec) (*structInfo, error) {
+	if spec.TypeParams != nil {
+		return nil, fmt.Errorf("generic types are not supported")
+	}
+	st, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		return nil, fmt.Errorf("not a struct type")
+	}
+	s := &structInfo{name: spec.Name.Name}
+	seen := make(map[string]bool)
+	for _, f := range st.Fields.List {
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			t, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return nil, err
+			}
+			tag = reflect.StructTag(t)
+		}
+		cborTag, isCbor := tag.Lookup("cbor")
+		if !isCbor {
+			cborTag = tag.Get("json")
+		}
+		if cborTag == "-" {
+			continue
+		}
+		tagName, opts, _ := strings.Cut(cborTag, ",")
+		options := strings.Split(opts, ",")
+
+		if len(f.Names) == 0 {
+			return nil, fmt.Errorf("embedded field %s is not supported", types.ExprString(f.Type))
+		}
+		for _, name := range f.Names {
+			if name.Name == "_" {
+				if slices.Contains(options, "toarray") {
+					s.toArray = true
+				}
+				continue
+			}
+			if r, _ := utf8.DecodeRuneInString(name.Name); !unicode.IsUpper(r) {
+				continue
+			}
+			if slices.Contains(options, "keyasint") || slices.Contains(options, "unknown") {
+				return nil, fmt.Errorf("field %s: keyasint and unknown options are not supported", name.Name)
+			}
+			fld := &field{
+				goName:    name.Name,
+				name:      tagName,
+				typ:       g.parseType(f.Type),
+				omitEmpty: slices.Contains(options, "omitempty"),
+				omitZero:  slices.Contains(options, "omitzero"),
+			}
+			if fld.name == "" {
+				fld.name = name.Name
+			}
+			if seen[fld.name] {
+				return nil, fmt.Errorf("field %s: duplicate name %q", name.Name, fld.name)
+			}
+			seen[fld.name] = true
+			s.fields = append(s.fields, fld)
+		}
+	}
+	return s, nil
+}
+
+// parseType classifies a field type. Composite types of types that aren't handled
+// directly are handled as a whole by drisl.Marshal and drisl.Unmarshal.
+func (g *generator) parseType(expr ast.Expr) *fieldType {
+	t := &fieldType{goType: types.ExprString(expr)}
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		switch expr.Name {
+		case "bool":
+			t.kind = kindBool
+		case "string":
+			t.kind = kindString
+		case "int", "int8", "int16", "int32", "int64":
+			t.kind = kindInt
+		case "uint", "uint8", "uint16", "uint32", "uint64", "byte":
+			t.kind = kindUint
+		case "float32", "float64":
+			t.kind = kindFloat
+		default:
+			if g.types[expr.Name] {
+				t.kind = kindStruct
+			}
+		}
+	case *ast.SelectorExpr:
+		pkgName, ok := expr.X.(*ast.Ident)
+		if !ok {
+			break
+		}
+		switch p := g.imports[pkgName.Name]; {
+		case p == cidPath && expr.Sel.Name == "Cid":
+			t.kind = kindCid
+		case (p == drislPath || p == cborPath) && expr.Sel.Name == "RawMessage":
+			t.kind = kindRaw
+		}
+	case *ast.StarExpr:
+		t.elem = g.parseType(expr.X)
+		t.kind = kindPtr
+	case *ast.ArrayType:
+		if expr.Len != nil {
+			break
+		}
+		if id, ok := expr.Elt.(*ast.Ident); ok && (id.Name == "byte" || id.Name == "uint8") {
+			t.kind = kindBytes
+			break
+		}
+		t.elem = g.parseType(expr.Elt)
+		t.kind = kindSlice
+	case *ast.MapType:
+		if id, ok := expr.Key.(*ast.Ident); !ok || id.Name != "string" {
+			break
+		}
+		t.elem = g.parseType(expr.Value)
+		t.kind = kindMap
+	}
+	if t.elem != nil && t.elem.kind == kindOther {
+		t.kind = kindOther
+		t.elem = nil
+	}
+	return t
+}
+
+// useType records the imports needed to write a type in the output.
+func (g *generator) useType(t *fieldType) string {
+	expr, err := parser.ParseExpr(t.goType)
+	if err == nil {
+		ast.Inspect(expr, func(n ast.Node) bool {
+			if sel, ok := n.(*ast.SelectorExpr); ok {
+				if id, ok := sel.X.(*ast.Ident); ok {
+					if p, ok := g.imports[id.Name]; ok {
+						g.used[id.Name] = p
+					}
+				}
+			}
+			return true
+		})
+	}
+	return t.goType
+}
+
+func (g *generator) newVar(prefix string) string {
+	g.varNum++
+	return prefix + strconv.Itoa(g.varNum)
+}
+
+// typeName is the name of a struct type in errors, matching reflect.Type.String.
+func (g *generator) typeName(name string) string {
+	return g.pkg.name + "." + name
+}
+
+func (g *generator) genStruct(s *structInfo) {
+	g.varNum = 0
+	g.printf("\n// MarshalCBOR fulfills the drisl.Marshaler interface.\n")
+	g.printf("func (v %s) MarshalCBOR() ([]byte, error) {\n", s.name)
+	g.printf("return v.appendDRISL(nil)\n}\n")
+
+	g.printf("\nfunc (v %s) appendDRISL(b []byte) ([]byte, error) {\n", s.name)
+	g.genAppendStruct(s)
+	g.printf("return b, nil\n}\n")
+
+	g.printf("\n// UnmarshalCBOR fulfills the drisl.Unmarshaler interface.\n")
+	g.printf("func (v *%s) UnmarshalCBOR(data []byte) error {\n", s.name)
+	g.printf("d, err := gensupport.NewDecoder(data)\n")
+	g.printf("if err != nil {\nreturn err\n}\n")
+	g.printf("v.decodeDRISL(d)\nreturn d.Err()\n}\n")
+
+	g.printf("\n// UnmarshalDRISL fulfills the drisl.ModeUnmarshaler interface.\n")
+	g.printf("func (v *%s) UnmarshalDRISL(dm drisl.DecMode, data []byte) error {\n", s.name)
+	g.printf("d, err := gensupport.NewDecoderMode(dm, data)\n")
+	g.printf("if err != nil {\nreturn err\n}\n")
+	g.printf("v.decodeDRISL(d)\nreturn d.Err()\n}\n")
+
+	if !s.toArray {
+		g.printf("\nvar %s = []string{", fieldsVar(s.name))
+		for i, f := range s.fields {
+			if i > 0 {
+				g.printf(", ")
+			}
+			g.printf("%q", f.name)
+		}
+		g.printf("}\n")
+	}
+
+	g.printf("\nfunc (v *%s) decodeDRISL(d *gensupport.Decoder) {\n", s.name)
+	g.printf("if d.SkipNull() {\nreturn\n}\n")
+	g.genDecodeStruct(s)
+	g.printf("}\n")
+}
+
+// fieldsVar is the name of the variable holding the field names of a type.
+func fieldsVar(typeName string) string {
+	r, size := utf8.DecodeRuneInString(typeName)
+	return "drislFields" + string(unicode.ToUpper(r)) + typeName[size:]
+}
+
+func (g *generator) genAppendStruct(s *structInfo) {
+	// Check if the err variable is needed
+	needsErr := false
+	for _, f := range s.fields {
+		if f.typ.fallible() && !g.usesMarshalField(f, s) {
+			needsErr = true
+		}
+	}
+	if needsErr {
+		g.printf("var err error\n")
+	}
+
+	if s.toArray {
+		g.printf("b = gensupport.AppendArrayHead(b, %d)\n", len(s.fields))
+		for _, f := range s.fields {
+			g.genAppend(f.typ, "v."+f.goName)
+		}
+		return
+	}
+
+	// Fields whose omission is decided by drisl.Marshal are encoded first
+	encoded := make(map[*field]string)
+	present := make(map[*field]string)
+	for i, f := range s.fields {
+		if !g.usesMarshalField(f, s) {
+			present[f] = g.presentCond(f)
+			continue
+		}
+		tagOpts := ""
+		if f.omitEmpty {
+			tagOpts += ",omitempty"
+		}
+		if f.omitZero {
+			tagOpts += ",omitzero"
+		}
+		val, ok, err := "f"+strconv.Itoa(i), "ok"+strconv.Itoa(i), "err"+strconv.Itoa(i)
+		g.printf("%s, %s, %s := gensupport.MarshalField(struct {\nV %s `cbor:\"V%s\"`\n}{v.%s})\n",
+			val, ok, err, g.useType(f.typ), tagOpts, f.goName)
+		g.printf("if %s != nil {\nreturn nil, %s\n}\n", err, err)
+		encoded[f] = val
+		present[f] = ok
+	}
+
+	omittable := 0
+	for _, f := range s.fields {
+		if present[f] != "" {
+			omittable++
+		}
+	}
+	if omittable == 0 {
+		g.printf("b = gensupport.AppendMapHead(b, %d)\n", len(s.fields))
+	} else {
+		g.printf("n := %d\n", len(s.fields))
+		for _, f := range s.fields {
+			if cond := present[f]; cond != "" {
+				g.printf("if !(%s) {\nn--\n}\n", cond)
+			}
+		}
+		g.printf("b = gensupport.AppendMapHead(b, n)\n")
+	}
+
+	// Keys are sorted by their encoding, which puts shorter keys first
+	sorted := slices.Clone(s.fields)
+	slices.SortStableFunc(sorted, func(a, b *field) int {
+		if len(a.name) != len(b.name) {
+			return len(a.name) - len(b.name)
+		}
+		return strings.Compare(a.name, b.name)
+	})
+	for _, f := range sorted {
+		cond := present[f]
+		if cond != "" {
+			g.printf("if %s {\n", cond)
+		}
+		g.printf("b = gensupport.AppendString(b, %q)\n", f.name)
+		if val, ok := encoded[f]; ok {
+			g.printf("b = append(b, %s...)\n", val)
+		} else {
+			g.genAppend(f.typ, "v."+f.goName)
+		}
+		if cond != "" {
+			g.printf("}\n")
+		}
+	}
+}
+
+// usesMarshalField reports whether the omission of a field can only be decided
+// by drisl.Marshal.
+func (g *generator) usesMarshalField(f *field, s *structInfo) bool {
+	if s.toArray || (!f.omitEmpty && !f.omitZero) {
+		return false
+	}
+	switch f.typ.kind {
+	case kindOther:
+		return true
+	case kindStruct:
+		return f.omitZero
+	}
+	return false
+}
+
+// presentCond returns the condition for a field to be encoded, or "" if it always is.
+func (g *generator) presentCond(f *field) string {
+	e := "v." + f.goName
+	var conds []string
+	if f.omitEmpty {
+		switch f.typ.kind {
+		case kindBool:
+			conds = append(conds, e)
+		case kindInt, kindUint, kindFloat:
+			conds = append(conds, e+" != 0")
+		case kindString:
+			conds = append(conds, e+` != ""`)
+		case kindBytes, kindRaw, kindSlice, kindMap:
+			conds = append(conds, "len("+e+") != 0")
+		case kindPtr:
+			conds = append(conds, e+" != nil")
+		}
+		// Types implementing drisl.Marshaler and structs are never empty
+	}
+	if f.omitZero {
+		switch f.typ.kind {
+		case kindBool:
+			conds = append(conds, e)
+		case kindInt, kindUint, kindFloat, kindString:
+			conds = append(conds, g.presentCond(&field{goName: f.goName, typ: f.typ, omitEmpty: true}))
+		case kindBytes, kindRaw, kindSlice, kindMap, kindPtr:
+			conds = append(conds, e+" != nil")
+		case kindCid:
+			conds = append(conds, e+".Defined()")
+		}
+	}
+	conds = slices.Compact(conds)
+	return strings.Join(conds, " && ")
+}
+
+// fallible reports whether encoding the type can return an error.
+func (t *fieldType) fallible() bool {
+	switch t.kind {
+	case kindBool, kindString, kindInt, kindUint, kindBytes:
+		return false
+	case kindPtr, kindSlice, kindMap:
+		return t.elem.fallible()
+	}
+	return true
+}
+
+// appendErr writes a call that appends to b and can fail.
+func (g *generator) appendErr(call string) {
+	g.printf("if b, err = %s; err != nil {\nreturn nil, err\n}\n", call)
+}
+
+// genAppend writes code that appends the encoded value of the expression e to b.
+func (g *generator) genAppend(t *fieldType, e string) {
+	switch t.kind {
+	case kindBool:
+		g.printf("b = gensupport.AppendBool(b, %s)\n", e)
+	case kindString:
+		g.printf("b = gensupport.AppendString(b, %s)\n", e)
+	case kindInt:
+		g.printf("b = gensupport.AppendInt(b, int64(%s))\n", e)
+	case kindUint:
+		g.printf("b = gensupport.AppendUint(b, uint64(%s))\n", e)
+	case kindFloat:
+		g.appendErr("gensupport.AppendFloat(b, float64(" + e + "))")
+	case kindBytes:
+		g.printf("b = gensupport.AppendBytes(b, %s)\n", e)
+	case kindCid:
+		g.appendErr("gensupport.AppendCid(b, " + e + ")")
+	case kindRaw:
+		g.appendErr("gensupport.AppendRaw(b, " + e + ")")
+	case kindStruct:
+		g.appendErr(e + ".appendDRISL(b)")
+	case kindPtr:
+		g.printf("if %s == nil {\nb = gensupport.AppendNull(b)\n} else {\n", e)
+		g.genAppend(t.elem, "(*"+e+")")
+		g.printf("}\n")
+	case kindSlice:
+		elem := g.newVar("e")
+		g.printf("if %s == nil {\nb = gensupport.AppendNull(b)\n} else {\n", e)
+		g.printf("b = gensupport.AppendArrayHead(b, len(%s))\n", e)
+		g.printf("for _, %s := range %s {\n", elem, e)
+		g.genAppend(t.elem, elem)
+		g.printf("}\n}\n")
+	case kindMap:
+		key := g.newVar("k")
+		g.printf("if %s == nil {\nb = gensupport.AppendNull(b)\n} else {\n", e)
+		g.printf("b = gensupport.AppendMapHead(b, len(%s))\n", e)
+		g.printf("for _, %s := range gensupport.SortedKeys(%s) {\n", key, e)
+		g.printf("b = gensupport.AppendString(b, %s)\n", key)
+		g.genAppend(t.elem, e+"["+key+"]")
+		g.printf("}\n}\n")
+	default:
+		g.appendErr("gensupport.AppendValue(b, " + e + ")")
+	}
+}
+
+// genDecodeStruct writes code that decodes a struct. Like drisl.Unmarshal, decoding
+// the struct stops at the first unknown or duplicate field, while decoding continues
+// after other errors, which are recorded by the Decoder.
+func (g *generator) genDecodeStruct(s *structInfo) {
+	if s.toArray {
+		g.printf("if d.ArrayHeadN(%q, %d) != nil {\nreturn\n}\n", g.typeName(s.name), len(s.fields))
+		for _, f := range s.fields {
+			g.genDecode(f.typ, "v."+f.goName)
+		}
+		return
+	}
+	g.printf("n, err := d.MapHead(%q)\n", g.typeName(s.name))
+	g.printf("if err != nil {\nreturn\n}\n")
+	g.printf("var seen [%d]bool\n", len(s.fields))
+	g.printf("for i := range n {\n")
+	g.printf("f, err := d.Field(%q, %s, seen[:])\n", g.typeName(s.name), fieldsVar(s.name))
+	g.printf("if err != nil {\nd.SkipPairs(n - i - 1)\nreturn\n}\n")
+	if len(s.fields) == 0 {
+		g.printf("_ = f\n}\n")
+		return
+	}
+	g.printf("switch f {\n")
+	for i, f := range s.fields {
+		g.printf("case %d:\n", i)
+		g.genDecode(f.typ, "v."+f.goName)
+	}
+	g.printf("}\n}\n")
+}
+
+// genDecode writes code that decodes the next item into the lvalue e.
+// Like drisl.Unmarshal, null sets pointers, slices, and maps to nil,
+// and leaves other types unchanged.
+func (g *generator) genDecode(t *fieldType, e string) {
+	switch t.kind {
+	case kindBool, kindString, kindInt, kindUint, kindFloat:
+		g.printf("if !d.SkipNull() {\n")
+		switch t.kind {
+		case kindBool:
+			g.printf("d.Bool(&%s)\n", e)
+		case kindString:
+			g.printf("d.String(&%s)\n", e)
+		case kindInt, kindUint:
+			g.printf("gensupport.Int(d, &%s)\n", e)
+		case kindFloat:
+			g.printf("gensupport.Float(d, &%s)\n", e)
+		}
+		g.printf("}\n")
+	case kindBytes:
+		g.printf("if d.SkipNull() {\n%s = nil\n} else {\nd.Bytes(&%s)\n}\n", e, e)
+	case kindCid:
+		g.printf("d.Cid(&%s)\n", e)
+	case kindRaw:
+		g.printf("%s = d.RawCopy()\n", e)
+	case kindStruct:
+		g.printf("%s.decodeDRISL(d)\n", e)
+	case kindPtr:
+		g.printf("if d.SkipNull() {\n%s = nil\n} else {\n", e)
+		g.printf("if %s == nil {\n%s = new(%s)\n}\n", e, e, g.useType(t.elem))
+		g.genDecode(t.elem, "(*"+e+")")
+		g.printf("}\n")
+	case kindSlice:
+		n, i := g.newVar("n"), g.newVar("i")
+		g.printf("if d.SkipNull() {\n%s = nil\n} else if %s, err := d.ArrayHead(%q); err == nil {\n", e, n, t.goType)
+		g.printf("if %s == 0 || cap(%s) < %s {\n%s = make(%s, %s)\n} else {\n%s = %s[:%s]\n}\n",
+			n, e, n, e, g.useType(t), n, e, e, n)
+		g.printf("for %s := range %s {\n", i, e)
+		g.genDecode(t.elem, e+"["+i+"]")
+		g.printf("}\n}\n")
+	case kindMap:
+		n, k, elem, errs := g.newVar("n"), g.newVar("k"), g.newVar("e"), g.newVar("errs")
+		g.printf("if d.SkipNull() {\n%s = nil\n} else if %s, err := d.MapHead(%q); err == nil {\n", e, n, t.goType)
+		g.printf("if %s == nil {\n%s = make(%s, %s)\n}\n", e, e, g.useType(t), n)
+		// Like drisl.Unmarshal, basic values are not reset between entries,
+		// so a null value repeats the previous one
+		basic := t.elem.kind == kindBool || t.elem.kind == kindString ||
+			t.elem.kind == kindInt || t.elem.kind == kindUint || t.elem.kind == kindFloat
+		if basic {
+			g.printf("var %s %s\n", elem, g.useType(t.elem))
+		}
+		g.printf("for range %s {\n", n)
+		// Keys are always text strings in valid DRISL
+		g.printf("var %s string\nd.String(&%s)\n", k, k)
+		if !basic {
+			g.printf("var %s %s\n", elem, g.useType(t.elem))
+		}
+		g.printf("%s := d.Errors()\n", errs)
+		g.genDecode(t.elem, elem)
+		// Like drisl.Unmarshal, values that fail to decode are not added
+		g.printf("if d.Errors() == %s {\n%s[%s] = %s\n}\n", errs, e, k, elem)
+		g.printf("}\n}\n")
+	default:
+		g.printf("d.Value(&%s)\n", e)
+	}
+}
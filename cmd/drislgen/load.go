@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Import paths of the types handled directly.
+const (
+	cidPath   = "github.com/hyphacoop/go-dasl/cid"
+	drislPath = "github.com/hyphacoop/go-dasl/drisl"
+	cborPath  = "github.com/hyphacoop/cbor/v2"
+)
+
+// pkgInfo is what the generator needs to know about the package.
+type pkgInfo struct {
+	name string
+	// test is true if the types are declared in test files.
+	test bool
+	// types are the type declarations, with the file they are in.
+	types map[string]typeDecl
+	// generated are the types that already have generated methods.
+	generated map[string]bool
+}
+
+type typeDecl struct {
+	spec *ast.TypeSpec
+	file *ast.File
+}
+
+// loadPackage parses the Go files in dir that belong to the same package as firstType.
+// The output file is skipped, so it can be regenerated when it is out of date.
+func loadPackage(dir, firstType, output string) (*pkgInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var outputAbs string
+	if output != "" {
+		outputAbs, _ = filepath.Abs(output)
+	}
+
+	fset := token.NewFileSet()
+	type parsed struct {
+		file *ast.File
+		test bool
+	}
+	var files []parsed
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		fullPath := filepath.Join(dir, name)
+		if abs, _ := filepath.Abs(fullPath); abs == outputAbs {
+			continue
+		}
+		f, err := parser.ParseFile(fset, fullPath, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, parsed{f, strings.HasSuffix(name, "_test.go")})
+	}
+
+	// Find the package of the first type
+	pkg := &pkgInfo{types: make(map[string]typeDecl), generated: make(map[string]bool)}
+	for _, p := range files {
+		if findType(p.file, firstType) != nil {
+			pkg.name = p.file.Name.Name
+			pkg.test = p.test
+			break
+		}
+	}
+	if pkg.name == "" {
+		return nil, fmt.Errorf("type %s not found in %s", firstType, dir)
+	}
+
+	for _, p := range files {
+		if p.file.Name.Name != pkg.name {
+			continue
+		}
+		for _, decl := range p.file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					if ts, ok := spec.(*ast.TypeSpec); ok {
+						pkg.types[ts.Name.Name] = typeDecl{ts, p.file}
+					}
+				}
+			case *ast.FuncDecl:
+				if decl.Recv != nil && decl.Name.Name == "appendDRISL" {
+					pkg.generated[recvName(decl.Recv.List[0].Type)] = true
+				}
+			}
+		}
+	}
+	return pkg, nil
+}
+
+func findType(f *ast.File, name string) *ast.TypeSpec {
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			if ts := spec.(*ast.TypeSpec); ts.Name.Name == name {
+				return ts
+			}
+		}
+	}
+	return nil
+}
+
+func recvName(expr ast.Expr) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if id, ok := expr.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+var versionSuffix = regexp.MustCompile(`^v[0-9]+$`)
+
+// fileImports returns the import paths of a file by their local name.
+// Without type checking, the package name is assumed to be the last
+// element of the path, ignoring major version suffixes.
+func fileImports(f *ast.File) map[string]string {
+	imports := make(map[string]string)
+	for _, spec := range f.Imports {
+		p, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		var name string
+		if spec.Name != nil {
+			name = spec.Name.Name
+		} else {
+			name = path.Base(p)
+			if versionSuffix.MatchString(name) {
+				name = path.Base(path.Dir(p))
+			}
+			name = strings.TrimPrefix(name, "go-")
+		}
+		if name == "_" || name == "." {
+			continue
+		}
+		imports[name] = p
+	}
+	return imports
+}
@@ -0,0 +1,91 @@
+// Command drislgen generates MarshalCBOR and UnmarshalCBOR methods for struct types,
+// so they can be encoded to and decoded from DRISL without reflection.
+//
+// It is meant to be used with go generate:
+//
+//	//go:generate go run github.com/hyphacoop/go-dasl/cmd/drislgen -type Post,Like
+//
+// The generated code honours the same struct tags as drisl.Marshal and drisl.Unmarshal:
+// field names from the "cbor" or "json" keys, "-", "omitempty", "omitzero", and "toarray".
+// The output is byte-identical to what drisl.Marshal returns for the same value.
+// Decoding gives the same results as drisl.Unmarshal too, including for invalid input:
+// map keys that match the same field, like "a" and "A", are an error, and decoding
+// continues after a value of the wrong type, returning the first error at the end.
+//
+// Fields of type bool, string, []byte, integers, floats, cid.Cid, drisl.RawMessage,
+// and other generated types are handled directly, as well as pointers, slices, and maps
+// with string keys of those. Fields of any other type are encoded and decoded with
+// drisl.Marshal and drisl.Unmarshal, so they still work but are not any faster.
+//
+// An UnmarshalDRISL method is generated as well, which drisl.DecMode.Unmarshal calls
+// instead of UnmarshalCBOR so that the decoding options apply to generated types.
+//
+// Embedded struct fields, and the "keyasint" and "unknown" options, are not supported.
+//
+// Usage:
+//
+//	drislgen -type T1,T2 [-output FILE] [DIR]
+//
+// DIR is the package directory, by default the current one. The types can be declared
+// in test files, in which case the output is a test file as well. By default the output
+// is written to t1_drisl.go, or t1_drisl_test.go, named after the first type.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	if err := run(os.Stderr, os.Args[1:]); err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintln(os.Stderr, "drislgen:", err)
+		}
+		os.Exit(2)
+	}
+}
+
+func run(stderr io.Writer, args []string) error {
+	fs := flag.NewFlagSet("drislgen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: drislgen -type T1,T2 [-output FILE] [DIR]")
+		fs.PrintDefaults()
+	}
+	typeNames := fs.String("type", "", "comma-separated list of struct type names; required")
+	output := fs.String("output", "", "output file name; default DIR/<type>_drisl.go")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *typeNames == "" || fs.NArg() > 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	types := strings.Split(*typeNames, ",")
+
+	pkg, err := loadPackage(dir, types[0], *output)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		name := strings.ToLower(types[0]) + "_drisl.go"
+		if pkg.test {
+			name = strings.ToLower(types[0]) + "_drisl_test.go"
+		}
+		*output = filepath.Join(dir, name)
+	}
+
+	src, err := generate(pkg, types, args)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*output, src, 0o644)
+}
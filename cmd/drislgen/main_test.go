@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The generated test types in the drisl package must be up to date.
+func TestGeneratedUpToDate(t *testing.T) {
+	const dir = "../../drisl"
+	const output = "drisl_gen_types_test.go"
+	types := []string{"GenT1", "GenT3", "GenRecord", "GenRef", "GenFacet"}
+
+	pkg, err := loadPackage(dir, types[0], filepath.Join(dir, output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.name != "drisl_test" || !pkg.test {
+		t.Fatalf("got package %s (test %v), want drisl_test", pkg.name, pkg.test)
+	}
+	got, err := generate(pkg, types, []string{"-type", strings.Join(types, ","), "-output", output})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join(dir, output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("%s is out of date, run go generate in the drisl directory", output)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	src := "package p\n\ntype Post struct {\n\tText string `cbor:\"text\"`\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "post.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(io.Discard, []string{"-type", "Post", dir}); err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.ReadFile(filepath.Join(dir, "post_drisl.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out, []byte("func (v Post) MarshalCBOR() ([]byte, error) {")) {
+		t.Fatalf("output has no MarshalCBOR method:\n%s", out)
+	}
+	// Running again replaces the output
+	if err := run(io.Discard, []string{"-type", "Post", dir}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	dir := t.TempDir()
+	src := `package p
+
+import "time"
+
+type Embedded struct {
+	time.Time
+}
+
+type KeyAsInt struct {
+	A int ` + "`cbor:\"1,keyasint\"`" + `
+}
+
+type Duplicate struct {
+	A int ` + "`cbor:\"a\"`" + `
+	B int ` + "`json:\"a\"`" + `
+}
+
+type NotStruct int
+`
+	if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"-type", "Embedded", dir},
+		{"-type", "KeyAsInt", dir},
+		{"-type", "Duplicate", dir},
+		{"-type", "NotStruct", dir},
+		{"-type", "Missing", dir},
+		{"-type", "Embedded,Missing", dir},
+		{dir},
+	} {
+		if err := run(io.Discard, args); err == nil {
+			t.Fatalf("%v: got no error", args)
+		}
+	}
+}
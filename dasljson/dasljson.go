@@ -0,0 +1,288 @@
+/*
+Package dasljson converts between DRISL and JSON, following the ATProto JSON
+representation of the data model.
+
+CIDs are represented as {"$link": "bafy..."} objects, and byte strings as
+{"$bytes": "..."} objects containing unpadded standard base64. Padded or otherwise
+non-canonical base64 is rejected. Floats are not part of the ATProto data model and
+are rejected in both directions.
+
+JSON is output in a canonical form: no whitespace, with object keys in the same order
+as DRISL map keys. This means JSON output by this package will survive a roundtrip
+through DRISL exactly.
+
+https://atproto.com/specs/data-model
+*/
+package dasljson
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+)
+
+var (
+	ErrFloat        = errors.New("go-dasl/dasljson: floats are not allowed")
+	ErrReservedKey  = errors.New("go-dasl/dasljson: map is ambiguous with a $link or $bytes object")
+	ErrDuplicateKey = errors.New("go-dasl/dasljson: duplicate object key")
+	ErrInvalidUTF8  = errors.New("go-dasl/dasljson: invalid UTF-8")
+)
+
+const hexDigits = "0123456789abcdef"
+
+// FromDrisl converts DRISL data into canonical JSON.
+//
+// An error is returned if the data is not valid DRISL, or contains floats.
+// It is also returned if the data contains a map that would be mistaken for a CID
+// or byte string in JSON: any map with a "$link" or "$bytes" key.
+func FromDrisl(data []byte) ([]byte, error) {
+	var v any
+	if err := drisl.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return appendValue(nil, v)
+}
+
+func appendValue(b []byte, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, "null"...), nil
+	case bool:
+		return strconv.AppendBool(b, v), nil
+	case uint64:
+		return strconv.AppendUint(b, v, 10), nil
+	case int64:
+		return strconv.AppendInt(b, v, 10), nil
+	case big.Int:
+		return v.Append(b, 10), nil
+	case float64:
+		return nil, ErrFloat
+	case string:
+		return appendString(b, v), nil
+	case []byte:
+		b = append(b, `{"$bytes":"`...)
+		b = base64.RawStdEncoding.AppendEncode(b, v)
+		return append(b, `"}`...), nil
+	case cid.Cid:
+		link, err := v.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		return append(b, link...), nil
+	case []any:
+		b = append(b, '[')
+		for i, elem := range v {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			var err error
+			b, err = appendValue(b, elem)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(b, ']'), nil
+	case map[string]any:
+		if _, ok := v["$link"]; ok {
+			return nil, ErrReservedKey
+		}
+		if _, ok := v["$bytes"]; ok {
+			return nil, ErrReservedKey
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.SortFunc(keys, compareKeys)
+		b = append(b, '{')
+		for i, k := range keys {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendString(b, k)
+			b = append(b, ':')
+			var err error
+			b, err = appendValue(b, v[k])
+			if err != nil {
+				return nil, err
+			}
+		}
+		return append(b, '}'), nil
+	default:
+		return nil, fmt.Errorf("go-dasl/dasljson: unexpected type %T", v)
+	}
+}
+
+// compareKeys sorts map keys the same way DRISL does: shorter keys first,
+// then bytewise.
+func compareKeys(a, b string) int {
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
+
+// appendString appends a JSON string. Unlike encoding/json, it does not escape
+// HTML characters, and only escapes what's required.
+func appendString(b []byte, s string) []byte {
+	b = append(b, '"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b = append(b, '\\', c)
+		case c == '\n':
+			b = append(b, '\\', 'n')
+		case c == '\r':
+			b = append(b, '\\', 'r')
+		case c == '\t':
+			b = append(b, '\\', 't')
+		case c < 0x20:
+			b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+		default:
+			b = append(b, c)
+		}
+	}
+	return append(b, '"')
+}
+
+// ToDrisl converts JSON into DRISL.
+//
+// Objects with a "$link" or "$bytes" key are converted into CIDs and byte strings.
+// These objects must have no other keys.
+//
+// Numbers must be integers, written without a fraction or exponent. Duplicate
+// object keys are not allowed, and the input must be valid UTF-8.
+func ToDrisl(data []byte) ([]byte, error) {
+	// encoding/json silently replaces invalid UTF-8, so check up front
+	if !utf8.Valid(data) {
+		return nil, ErrInvalidUTF8
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	v, err := decodeValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("go-dasl/dasljson: extraneous data after JSON value")
+	}
+	return drisl.Marshal(v)
+}
+
+// decodeValue reads the next JSON value into a Go value that drisl can marshal.
+func decodeValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	switch tok := tok.(type) {
+	case nil, bool:
+		return tok, nil
+	case string:
+		if !utf8.ValidString(tok) {
+			return nil, errors.New("go-dasl/dasljson: invalid UTF-8 string")
+		}
+		return tok, nil
+	case json.Number:
+		return parseInt(tok.String())
+	case json.Delim:
+		if tok == '[' {
+			list := make([]any, 0)
+			for dec.More() {
+				v, err := decodeValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+			// Closing bracket
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return list, nil
+		}
+		return decodeObject(dec)
+	default:
+		return nil, fmt.Errorf("go-dasl/dasljson: unexpected token %v", tok)
+	}
+}
+
+func decodeObject(dec *json.Decoder) (any, error) {
+	m := make(map[string]any)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := tok.(string)
+		if _, ok := m[key]; ok {
+			return nil, ErrDuplicateKey
+		}
+		v, err := decodeValue(dec)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	// Closing brace
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	if link, ok := m["$link"]; ok {
+		s, isStr := link.(string)
+		if len(m) != 1 || !isStr {
+			return nil, errors.New(`go-dasl/dasljson: invalid $link object`)
+		}
+		return cid.NewCidFromString(s)
+	}
+	if data, ok := m["$bytes"]; ok {
+		s, isStr := data.(string)
+		if len(m) != 1 || !isStr {
+			return nil, errors.New(`go-dasl/dasljson: invalid $bytes object`)
+		}
+		// Only accept canonical unpadded base64, so the conversion is lossless
+		return base64.RawStdEncoding.Strict().DecodeString(s)
+	}
+	return m, nil
+}
+
+// parseInt parses a JSON number that must be an integer.
+func parseInt(s string) (any, error) {
+	if strings.ContainsAny(s, ".eE") {
+		return nil, ErrFloat
+	}
+	if s[0] != '-' {
+		u, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("go-dasl/dasljson: integer out of range: %s", s)
+		}
+		return u, nil
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, nil
+	}
+	// Negative integers in CBOR go down to -(2^64)
+	bi, ok := new(big.Int).SetString(s, 10)
+	if !ok || bi.Cmp(minInt) < 0 {
+		return nil, fmt.Errorf("go-dasl/dasljson: integer out of range: %s", s)
+	}
+	return bi, nil
+}
+
+var minInt = new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 64))
@@ -0,0 +1,223 @@
+package dasljson_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/dasljson"
+	"github.com/hyphacoop/go-dasl/drisl"
+	"pgregory.net/rapid"
+)
+
+var roundtripTests = []struct {
+	name string
+	json string
+}{
+	{"null", `null`},
+	{"bools", `[true,false]`},
+	{"ints", `[0,1,-1,18446744073709551615,-9223372036854775808,-18446744073709551616]`},
+	{"string", `"hello \"world\"\n\u0001 <é>"`},
+	{"bytes", `{"$bytes":"AQID"}`},
+	{"empty bytes", `{"$bytes":""}`},
+	{"link", `{"$link":"bafkreifn5yxi7nkftsn46b6x26grda57ict7md2xuvfbsgkiahe2e7vnq4"}`},
+	{"sorted keys", `{"b":1,"aa":2,"$type":"app.bsky.feed.post"}`},
+	{"nested", `{"a":[{"b":{"$link":"bafyreidykglsfhoixmivffc5uwhcgshx4j465xwqntbmu43nb2dzqwfvae"}}],"c":{}}`},
+	{"link with other keys", `{"$link2":"b","$linkx":"a"}`},
+}
+
+func TestRoundtrip(t *testing.T) {
+	for _, tt := range roundtripTests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := dasljson.ToDrisl([]byte(tt.json))
+			if err != nil {
+				t.Fatalf("ToDrisl failed: %v", err)
+			}
+			j, err := dasljson.FromDrisl(d)
+			if err != nil {
+				t.Fatalf("FromDrisl failed: %v", err)
+			}
+			if string(j) != tt.json {
+				t.Fatalf("got %s, want %s", j, tt.json)
+			}
+		})
+	}
+}
+
+func TestToDrisl(t *testing.T) {
+	link := cid.MustNewCidFromString("bafkreifn5yxi7nkftsn46b6x26grda57ict7md2xuvfbsgkiahe2e7vnq4")
+	want, err := drisl.Marshal(map[string]any{
+		"link":  link,
+		"bytes": []byte{1, 2, 3},
+		"int":   -5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Non-canonical input is accepted
+	got, err := dasljson.ToDrisl([]byte(` { "bytes" : {"$bytes": "AQID"}, "link": {"$link": "` + link.String() + `"}, "int": -5 } `))
+	if err != nil {
+		t.Fatalf("ToDrisl failed: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("got %x, want %x", got, want)
+	}
+}
+
+func TestToDrislInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"float", `1.5`},
+		{"exponent", `1e3`},
+		{"out of range", `18446744073709551616`},
+		{"negative out of range", `-18446744073709551617`},
+		{"duplicate key", `{"a":1,"a":2}`},
+		{"link with other keys", `{"$link":"bafkreifn5yxi7nkftsn46b6x26grda57ict7md2xuvfbsgkiahe2e7vnq4","a":1}`},
+		{"link not string", `{"$link":1}`},
+		{"invalid link", `{"$link":"bafkrei"}`},
+		{"bytes with other keys", `{"$bytes":"AQID","a":1}`},
+		{"invalid bytes", `{"$bytes":"!!"}`},
+		{"padded bytes", `{"$bytes":"AQI="}`},
+		{"only padding", `{"$bytes":"="}`},
+		{"non-canonical bytes", `{"$bytes":"AQJ"}`},
+		{"invalid UTF-8 string", "\"a\xffb\""},
+		{"invalid UTF-8 key", "{\"\xff\":1}"},
+		{"extra data", `1 2`},
+		{"truncated", `[1,`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if b, err := dasljson.ToDrisl([]byte(tt.json)); err == nil {
+				t.Fatalf("ToDrisl(%s) = %x, want error", tt.json, b)
+			}
+		})
+	}
+	if _, err := dasljson.ToDrisl([]byte(`[1.5]`)); !errors.Is(err, dasljson.ErrFloat) {
+		t.Fatalf("got %v, want ErrFloat", err)
+	}
+	if _, err := dasljson.ToDrisl([]byte("[\"\xc3\"]")); !errors.Is(err, dasljson.ErrInvalidUTF8) {
+		t.Fatalf("got %v, want ErrInvalidUTF8", err)
+	}
+}
+
+func TestFromDrislInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		err  error
+	}{
+		{"float", map[string]any{"a": 1.5}, dasljson.ErrFloat},
+		{"$link map", map[string]any{"$link": "a"}, dasljson.ErrReservedKey},
+		{"$bytes map", map[string]any{"$bytes": "a"}, dasljson.ErrReservedKey},
+		{"$link map with other keys", map[string]any{"a": 1, "$link": "x"}, dasljson.ErrReservedKey},
+		{"nested $bytes map", []any{map[string]any{"$bytes": "a", "b": 2}}, dasljson.ErrReservedKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := drisl.Marshal(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if j, err := dasljson.FromDrisl(b); !errors.Is(err, tt.err) {
+				t.Fatalf("FromDrisl = %s, %v, want %v", j, err, tt.err)
+			}
+		})
+	}
+}
+
+// TestReservedKeyRoundtrip checks that both directions reject maps with
+// reserved keys the same way, so that any JSON output can be converted back.
+func TestReservedKeyRoundtrip(t *testing.T) {
+	for _, v := range []map[string]any{
+		{"$link": "x"},
+		{"$bytes": "x"},
+		{"a": 1, "$link": "x"},
+		{"a": 1, "$bytes": "x"},
+		{"a": 1, "$links": "x"},
+	} {
+		d, err := drisl.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		j, fromErr := dasljson.FromDrisl(d)
+		jsonBytes, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, toErr := dasljson.ToDrisl(jsonBytes)
+		if (fromErr == nil) != (toErr == nil) {
+			t.Fatalf("%v: FromDrisl error %v, ToDrisl error %v", v, fromErr, toErr)
+		}
+		if fromErr != nil {
+			continue
+		}
+		d2, err := dasljson.ToDrisl(j)
+		if err != nil {
+			t.Fatalf("ToDrisl(%s) failed: %v", j, err)
+		}
+		if !bytes.Equal(d, d2) {
+			t.Fatalf("got %x, want %x (JSON %s)", d2, d, j)
+		}
+	}
+}
+
+// TestDrislRoundtrip checks that DRISL survives a roundtrip through JSON.
+func TestDrislRoundtrip(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		v := valueGenerator(3).Draw(t, "value")
+		d, err := drisl.Marshal(v)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		j, err := dasljson.FromDrisl(d)
+		if err != nil {
+			t.Fatalf("FromDrisl(%x) failed: %v", d, err)
+		}
+		d2, err := dasljson.ToDrisl(j)
+		if err != nil {
+			t.Fatalf("ToDrisl(%s) failed: %v", j, err)
+		}
+		if !bytes.Equal(d, d2) {
+			t.Fatalf("got %x, want %x (JSON %s)", d2, d, j)
+		}
+	})
+}
+
+// valueGenerator generates values in the ATProto data model.
+func valueGenerator(depth int) *rapid.Generator[any] {
+	return rapid.Custom(func(t *rapid.T) any {
+		max := 9
+		if depth == 0 {
+			max = 7
+		}
+		switch rapid.IntRange(0, max).Draw(t, "kind") {
+		case 0:
+			return nil
+		case 1:
+			return rapid.Bool().Draw(t, "bool")
+		case 2:
+			return rapid.Uint64().Draw(t, "uint")
+		case 3:
+			return rapid.Int64().Draw(t, "int")
+		case 4:
+			// Below int64
+			n := new(big.Int).SetUint64(rapid.Uint64().Draw(t, "bigint"))
+			return n.Neg(n).Sub(n, big.NewInt(1))
+		case 5:
+			return rapid.String().Draw(t, "string")
+		case 6:
+			return rapid.SliceOf(rapid.Byte()).Draw(t, "bytes")
+		case 7:
+			return cid.HashBytes(rapid.SliceOf(rapid.Byte()).Draw(t, "cid"))
+		case 8:
+			return rapid.SliceOfN(valueGenerator(depth-1), 0, 4).Draw(t, "list")
+		default:
+			return rapid.MapOfN(rapid.String(), valueGenerator(depth-1), 0, 4).Draw(t, "map")
+		}
+	})
+}
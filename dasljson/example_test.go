@@ -0,0 +1,26 @@
+package dasljson_test
+
+import (
+	"fmt"
+
+	"github.com/hyphacoop/go-dasl/dasljson"
+)
+
+func Example() {
+	data, err := dasljson.ToDrisl([]byte(`{
+  "text": "hello",
+  "image": {"$link": "bafkreifn5yxi7nkftsn46b6x26grda57ict7md2xuvfbsgkiahe2e7vnq4"},
+  "sig": {"$bytes": "aGVsbG8"}
+}`))
+	if err != nil {
+		panic(err)
+	}
+
+	json, err := dasljson.FromDrisl(data)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(json))
+	// Output:
+	// {"sig":{"$bytes":"aGVsbG8"},"text":"hello","image":{"$link":"bafkreifn5yxi7nkftsn46b6x26grda57ict7md2xuvfbsgkiahe2e7vnq4"}}
+}
@@ -0,0 +1,368 @@
+package drisl
+
+import (
+	"encoding/binary"
+	"math"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/hyphacoop/go-dasl/cid"
+)
+
+// CanonOptions specifies options for Canonicalize.
+type CanonOptions struct {
+	// MaxNestedLevels specifies the max nested levels allowed for any combination of
+	// CBOR arrays, maps, and tags. Default is 32 levels, like DecOptions.
+	MaxNestedLevels int
+
+	// AllowUndefined turns CBOR's 'undefined' simple value into null,
+	// instead of returning an error.
+	AllowUndefined bool
+
+	// OnViolation, if not nil, is called for each DRISL rule broken by the input
+	// that was fixed, in the order they appear. The ValidationError has the same
+	// offset and reason that Validate would return for the first one.
+	OnViolation func(v *ValidationError)
+}
+
+// Canonicalize decodes lenient CBOR and re-encodes it as valid DRISL.
+//
+// This is for CBOR from other tools that is not DRISL-canonical. These are fixed:
+// integers and lengths not in their shortest form, indefinite length items,
+// unsorted map keys, 16 and 32 bit floats, and undefined if AllowUndefined is set.
+//
+// Input that can't be represented in DRISL is an error: NaN, infinity,
+// non-string or duplicate map keys, tags other than 42, invalid CIDs,
+// other simple values, and invalid UTF-8. The error is a *ValidationError if the
+// input itself is the problem.
+//
+// If the input is already valid DRISL, the output is the same.
+func Canonicalize(in []byte, opts CanonOptions) ([]byte, error) {
+	if opts.MaxNestedLevels == 0 {
+		opts.MaxNestedLevels = defaultMaxNestedLevels
+	}
+	c := canonicalizer{data: in, opts: opts}
+	if len(in) == 0 {
+		return nil, &ValidationError{0, "no data"}
+	}
+	n, err := c.item(0)
+	if err != nil {
+		return nil, err
+	}
+	if c.off != len(in) {
+		return nil, &ValidationError{c.off, "extraneous data after item"}
+	}
+	return n.MarshalCBOR()
+}
+
+// canonicalizer decodes lenient CBOR into a Node.
+type canonicalizer struct {
+	data []byte
+	off  int
+	opts CanonOptions
+}
+
+func (c *canonicalizer) violation(off int, reason string) {
+	if c.opts.OnViolation != nil {
+		c.opts.OnViolation(&ValidationError{off, reason})
+	}
+}
+
+// head reads the head of a non-simple item, reporting non-shortest arguments.
+// For indefinite length items, indef is true.
+func (c *canonicalizer) head() (major byte, arg uint64, indef bool, err error) {
+	start := c.off
+	ib := c.data[c.off]
+	major = ib >> 5
+	ai := ib & 0x1f
+	c.off++
+
+	switch {
+	case ai < 24:
+		return major, uint64(ai), false, nil
+	case ai == 31:
+		if major < 2 || major == 6 {
+			return 0, 0, false, &ValidationError{start, "invalid indefinite length item"}
+		}
+		c.violation(start, "indefinite length items are not allowed")
+		return major, 0, true, nil
+	case ai > 27:
+		return 0, 0, false, &ValidationError{start, "reserved additional information value"}
+	}
+
+	n := 1 << (ai - 24)
+	if len(c.data)-c.off < n {
+		return 0, 0, false, &ValidationError{start, "unexpected end of data"}
+	}
+	b := c.data[c.off : c.off+n]
+	c.off += n
+	switch n {
+	case 1:
+		arg = uint64(b[0])
+	case 2:
+		arg = uint64(binary.BigEndian.Uint16(b))
+	case 4:
+		arg = uint64(binary.BigEndian.Uint32(b))
+	case 8:
+		arg = binary.BigEndian.Uint64(b)
+	}
+	if len(appendHead(nil, 0, arg)) < 1+n {
+		c.violation(start, "integer or length is not in its shortest form")
+	}
+	return major, arg, false, nil
+}
+
+// isBreak consumes the break byte ending an indefinite length item, if it is next.
+func (c *canonicalizer) isBreak() (bool, error) {
+	if c.off >= len(c.data) {
+		return false, &ValidationError{c.off, "unexpected end of data"}
+	}
+	if c.data[c.off] == 0xff {
+		c.off++
+		return true, nil
+	}
+	return false, nil
+}
+
+// item decodes a single item starting at the current offset.
+func (c *canonicalizer) item(depth int) (Node, error) {
+	start := c.off
+	if start >= len(c.data) {
+		return Node{}, &ValidationError{start, "unexpected end of data"}
+	}
+	if c.data[start]>>5 == 7 {
+		return c.simple()
+	}
+
+	major, arg, indef, err := c.head()
+	if err != nil {
+		return Node{}, err
+	}
+
+	switch major {
+	case 0:
+		return Node{kind: KindInt, num: arg}, nil
+	case 1:
+		return Node{kind: KindInt, neg: true, num: arg}, nil
+	case 2, 3:
+		s, err := c.str(start, major, arg, indef)
+		if err != nil {
+			return Node{}, err
+		}
+		if major == 2 {
+			return NewBytes(s), nil
+		}
+		if !utf8.Valid(s) {
+			return Node{}, &ValidationError{start, "invalid UTF-8 string"}
+		}
+		return NewString(string(s)), nil
+	case 4:
+		depth++
+		if depth > c.opts.MaxNestedLevels {
+			return Node{}, &ValidationError{start, "exceeded max nested levels"}
+		}
+		// Every item is at least one byte, don't trust the length beyond that
+		list := make([]Node, 0, min(arg, uint64(len(c.data)-c.off)))
+		for i := uint64(0); indef || i < arg; i++ {
+			if indef {
+				if done, err := c.isBreak(); done || err != nil {
+					if err != nil {
+						return Node{}, err
+					}
+					break
+				}
+			}
+			elem, err := c.item(depth)
+			if err != nil {
+				return Node{}, err
+			}
+			list = append(list, elem)
+		}
+		return Node{kind: KindList, list: list}, nil
+	case 5:
+		depth++
+		if depth > c.opts.MaxNestedLevels {
+			return Node{}, &ValidationError{start, "exceeded max nested levels"}
+		}
+		entries := make([]MapEntry, 0, min(arg, uint64(len(c.data)-c.off)/2))
+		unsorted := false
+		for i := uint64(0); indef || i < arg; i++ {
+			if indef {
+				if done, err := c.isBreak(); done || err != nil {
+					if err != nil {
+						return Node{}, err
+					}
+					break
+				}
+			}
+			keyStart := c.off
+			if keyStart >= len(c.data) {
+				return Node{}, &ValidationError{keyStart, "unexpected end of data"}
+			}
+			if c.data[keyStart]>>5 != 3 {
+				return Node{}, &ValidationError{keyStart, "map key is not a text string"}
+			}
+			key, err := c.item(depth)
+			if err != nil {
+				return Node{}, err
+			}
+			value, err := c.item(depth)
+			if err != nil {
+				return Node{}, err
+			}
+			e := MapEntry{Key: key.str, Value: value}
+			if len(entries) > 0 {
+				switch cmp := compareEntries(entries[len(entries)-1], e); {
+				case cmp == 0:
+					return Node{}, &ValidationError{keyStart, "duplicate map key"}
+				case cmp > 0 && !unsorted:
+					c.violation(keyStart, "map keys are not sorted")
+					unsorted = true
+				}
+			}
+			entries = append(entries, e)
+		}
+		if unsorted {
+			// Duplicates might not have been next to each other
+			slices.SortFunc(entries, compareEntries)
+			for i := 1; i < len(entries); i++ {
+				if entries[i-1].Key == entries[i].Key {
+					return Node{}, &ValidationError{start, "duplicate map key"}
+				}
+			}
+		}
+		return Node{kind: KindMap, entries: entries}, nil
+	default:
+		// Tag
+		depth++
+		if depth > c.opts.MaxNestedLevels {
+			return Node{}, &ValidationError{start, "exceeded max nested levels"}
+		}
+		if arg != CidTagNumber {
+			return Node{}, &ValidationError{start, "tag number is not 42"}
+		}
+		contentStart := c.off
+		content, err := c.item(depth)
+		if err != nil {
+			return Node{}, err
+		}
+		if content.kind != KindBytes {
+			return Node{}, &ValidationError{contentStart, "CID tag content is not a byte string"}
+		}
+		if len(content.str) == 0 {
+			return Node{}, &ValidationError{contentStart, "CID is empty"}
+		}
+		if content.str[0] != 0x00 {
+			return Node{}, &ValidationError{contentStart, "CID does not have 0x00 prefix"}
+		}
+		link, err := cid.NewCidFromBytes([]byte(content.str[1:]))
+		if err != nil {
+			return Node{}, &ValidationError{contentStart, "CID is not a valid DASL CID"}
+		}
+		return NewLink(link), nil
+	}
+}
+
+// str reads the content of a byte or text string, joining indefinite length chunks.
+func (c *canonicalizer) str(start int, major byte, arg uint64, indef bool) ([]byte, error) {
+	if !indef {
+		if uint64(len(c.data)-c.off) < arg {
+			return nil, &ValidationError{start, "unexpected end of data"}
+		}
+		c.off += int(arg)
+		return c.data[c.off-int(arg) : c.off], nil
+	}
+	var s []byte
+	for {
+		if done, err := c.isBreak(); done || err != nil {
+			return s, err
+		}
+		chunkStart := c.off
+		if c.data[chunkStart]>>5 != major || c.data[chunkStart]&0x1f == 31 {
+			return nil, &ValidationError{chunkStart, "invalid indefinite length string chunk"}
+		}
+		_, n, _, err := c.head()
+		if err != nil {
+			return nil, err
+		}
+		chunk, err := c.str(chunkStart, major, n, false)
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, chunk...)
+	}
+}
+
+// simple decodes major type 7: simple values and floats.
+func (c *canonicalizer) simple() (Node, error) {
+	start := c.off
+	ai := c.data[start] & 0x1f
+	c.off++
+
+	var f float64
+	switch ai {
+	case 20, 21:
+		return NewBool(ai == 21), nil
+	case 22:
+		return NewNull(), nil
+	case 23:
+		if !c.opts.AllowUndefined {
+			return Node{}, &ValidationError{start, "undefined is not allowed"}
+		}
+		c.violation(start, "undefined is not allowed")
+		return NewNull(), nil
+	case 25:
+		if len(c.data)-c.off < 2 {
+			return Node{}, &ValidationError{start, "unexpected end of data"}
+		}
+		f = float16to64(binary.BigEndian.Uint16(c.data[c.off:]))
+		c.off += 2
+		c.violation(start, "float is not 64 bits wide")
+	case 26:
+		if len(c.data)-c.off < 4 {
+			return Node{}, &ValidationError{start, "unexpected end of data"}
+		}
+		f = float64(math.Float32frombits(binary.BigEndian.Uint32(c.data[c.off:])))
+		c.off += 4
+		c.violation(start, "float is not 64 bits wide")
+	case 27:
+		if len(c.data)-c.off < 8 {
+			return Node{}, &ValidationError{start, "unexpected end of data"}
+		}
+		f = math.Float64frombits(binary.BigEndian.Uint64(c.data[c.off:]))
+		c.off += 8
+	case 31:
+		return Node{}, &ValidationError{start, "unexpected break"}
+	default:
+		return Node{}, &ValidationError{start, "simple value is not allowed"}
+	}
+	if math.IsNaN(f) {
+		return Node{}, &ValidationError{start, "NaN is not allowed"}
+	}
+	if math.IsInf(f, 0) {
+		return Node{}, &ValidationError{start, "infinity is not allowed"}
+	}
+	return NewFloat(f), nil
+}
+
+// float16to64 converts IEEE 754 half-precision float bits to a float64.
+func float16to64(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp := int(h>>10) & 0x1f
+	frac := float64(h & 0x3ff)
+	switch exp {
+	case 0:
+		// Subnormal
+		return sign * math.Ldexp(frac, -24)
+	case 0x1f:
+		if frac == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	default:
+		return sign * math.Ldexp(frac+1024, exp-25)
+	}
+}
@@ -0,0 +1,132 @@
+package drisl_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/hyphacoop/go-dasl/drisl"
+)
+
+func TestCanonicalizeValid(t *testing.T) {
+	for _, tt := range validTests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := hexDecode(tt.in)
+			got, err := drisl.Canonicalize(data, drisl.CanonOptions{
+				OnViolation: func(v *drisl.ValidationError) {
+					t.Errorf("unexpected violation: %v", v)
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("got %x, want %x", got, data)
+			}
+		})
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name       string
+		in         string
+		want       string
+		violations []string
+	}{
+		{"non-shortest int", "1801", "01", []string{"integer or length is not in its shortest form"}},
+		{"non-shortest negative", "3a00000000", "20", []string{"integer or length is not in its shortest form"}},
+		{"non-shortest length", "590001ff", "41ff", []string{"integer or length is not in its shortest form"}},
+		{"float16", "f93e00", "fb3ff8000000000000", []string{"float is not 64 bits wide"}},
+		{"float16 subnormal", "f90001", "fb3e70000000000000", []string{"float is not 64 bits wide"}},
+		{"float32", "fa3fc00000", "fb3ff8000000000000", []string{"float is not 64 bits wide"}},
+		{"unsorted map", "a2616202616101", "a2616101616202", []string{"map keys are not sorted"}},
+		{"length-first order", "a2626161016162f5", "a26162f562616101", []string{"map keys are not sorted"}},
+		{"indefinite array", "9f0102ff", "820102", []string{"indefinite length items are not allowed"}},
+		{"indefinite map", "bf6161f4ff", "a16161f4", []string{"indefinite length items are not allowed"}},
+		{"indefinite string", "7f616161626163ff", "63616263", []string{"indefinite length items are not allowed"}},
+		{
+			"nested",
+			"9f1801bf616202616101fffa3f800000ff",
+			"8301a2616101616202fb3ff0000000000000",
+			[]string{
+				"indefinite length items are not allowed",
+				"integer or length is not in its shortest form",
+				"indefinite length items are not allowed",
+				"map keys are not sorted",
+				"float is not 64 bits wide",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var violations []string
+			got, err := drisl.Canonicalize(hexDecode(tt.in), drisl.CanonOptions{
+				OnViolation: func(v *drisl.ValidationError) {
+					violations = append(violations, v.Reason)
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, hexDecode(tt.want)) {
+				t.Fatalf("got %x, want %s", got, tt.want)
+			}
+			if len(violations) != len(tt.violations) {
+				t.Fatalf("got violations %q, want %q", violations, tt.violations)
+			}
+			for i := range violations {
+				if violations[i] != tt.violations[i] {
+					t.Fatalf("got violations %q, want %q", violations, tt.violations)
+				}
+			}
+			if !drisl.Valid(got) {
+				t.Fatalf("output %x is not valid DRISL", got)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeUndefined(t *testing.T) {
+	if _, err := drisl.Canonicalize(hexDecode("f7"), drisl.CanonOptions{}); err == nil {
+		t.Fatal("Canonicalize succeeded for undefined")
+	}
+	got, err := drisl.Canonicalize(hexDecode("81f7"), drisl.CanonOptions{AllowUndefined: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, hexDecode("81f6")) {
+		t.Fatalf("got %x, want 81f6", got)
+	}
+}
+
+func TestCanonicalizeInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		in   string
+	}{
+		{"empty", ""},
+		{"truncated", "830102"},
+		{"extra data", "0101"},
+		{"NaN", "f97e00"},
+		{"infinity", "fa7f800000"},
+		{"int key", "a10102"},
+		{"duplicate key", "a2616101616102"},
+		{"unsorted duplicate key", "a3616101616202616103"},
+		{"other tag", "c11a514b67b0"},
+		{"non-DASL CID", nonDaslCid},
+		{"simple value", "f0"},
+		{"invalid UTF-8", "62c328"},
+		{"mixed chunks", "7f4161ff"},
+		{"unterminated", "9f01"},
+		{"lone break", "ff"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := drisl.Canonicalize(hexDecode(tt.in), drisl.CanonOptions{})
+			var ve *drisl.ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("got %v, want a ValidationError", err)
+			}
+		})
+	}
+}
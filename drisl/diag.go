@@ -0,0 +1,441 @@
+package drisl
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hyphacoop/go-dasl/cid"
+)
+
+// DiagOptions specifies options for printing CBOR diagnostic notation.
+type DiagOptions struct {
+	// CidStrings prints CIDs as 42("bafy...") instead of 42(h'00...').
+	// The former is not standard diagnostic notation, but it is much easier to read.
+	CidStrings bool
+
+	// Indent, if not empty, pretty-prints the output with each list element and
+	// map entry on its own line, indented by Indent for each level of nesting.
+	Indent string
+}
+
+// Diagnose returns the diagnostic notation for DRISL data, as described in
+// RFC 8949 Section 8, using the default options.
+//
+// An error is returned if data is not valid DRISL.
+func Diagnose(data []byte) (string, error) {
+	return DiagOptions{}.Diagnose(data)
+}
+
+// Diagnose returns the diagnostic notation for DRISL data, as described in
+// RFC 8949 Section 8.
+//
+// An error is returned if data is not valid DRISL.
+func (opts DiagOptions) Diagnose(data []byte) (string, error) {
+	var n Node
+	if err := Unmarshal(data, &n); err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	opts.writeNode(&sb, n, 0)
+	return sb.String(), nil
+}
+
+func (opts DiagOptions) writeNode(sb *strings.Builder, n Node, depth int) {
+	switch n.kind {
+	case KindNull:
+		sb.WriteString("null")
+	case KindBool:
+		sb.WriteString(strconv.FormatBool(n.num == 1))
+	case KindInt:
+		if n.neg {
+			i, _ := n.AsBigInt()
+			sb.WriteString(i.String())
+		} else {
+			sb.WriteString(strconv.FormatUint(n.num, 10))
+		}
+	case KindFloat:
+		s := strconv.FormatFloat(math.Float64frombits(n.num), 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			// Distinguish from integers
+			s += ".0"
+		}
+		sb.WriteString(s)
+	case KindString:
+		writeDiagString(sb, n.str)
+	case KindBytes:
+		sb.WriteString("h'")
+		sb.WriteString(hex.EncodeToString([]byte(n.str)))
+		sb.WriteByte('\'')
+	case KindLink:
+		if opts.CidStrings {
+			sb.WriteString(`42("`)
+			sb.WriteString(n.link.String())
+			sb.WriteString(`")`)
+		} else {
+			sb.WriteString("42(h'00")
+			sb.WriteString(hex.EncodeToString(n.link.Bytes()))
+			sb.WriteString("')")
+		}
+	case KindList:
+		sb.WriteByte('[')
+		for i, elem := range n.list {
+			opts.writeSeparator(sb, i, depth+1)
+			opts.writeNode(sb, elem, depth+1)
+		}
+		opts.writeClose(sb, len(n.list), depth, ']')
+	case KindMap:
+		sb.WriteByte('{')
+		for i, e := range n.entries {
+			opts.writeSeparator(sb, i, depth+1)
+			writeDiagString(sb, e.Key)
+			sb.WriteString(": ")
+			opts.writeNode(sb, e.Value, depth+1)
+		}
+		opts.writeClose(sb, len(n.entries), depth, '}')
+	}
+}
+
+// writeSeparator writes what comes before the i-th item of a list or map.
+func (opts DiagOptions) writeSeparator(sb *strings.Builder, i, depth int) {
+	if i > 0 {
+		sb.WriteByte(',')
+		if opts.Indent == "" {
+			sb.WriteByte(' ')
+		}
+	}
+	if opts.Indent != "" {
+		sb.WriteByte('\n')
+		sb.WriteString(strings.Repeat(opts.Indent, depth))
+	}
+}
+
+// writeClose ends a list or map with n items.
+func (opts DiagOptions) writeClose(sb *strings.Builder, n, depth int, c byte) {
+	if opts.Indent != "" && n > 0 {
+		sb.WriteByte('\n')
+		sb.WriteString(strings.Repeat(opts.Indent, depth))
+	}
+	sb.WriteByte(c)
+}
+
+// writeDiagString writes a string with JSON escaping, as required by diagnostic notation.
+func writeDiagString(sb *strings.Builder, s string) {
+	const hexDigits = "0123456789abcdef"
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c == '\n':
+			sb.WriteString(`\n`)
+		case c == '\r':
+			sb.WriteString(`\r`)
+		case c == '\t':
+			sb.WriteString(`\t`)
+		case c < 0x20 || c == 0x7f:
+			sb.WriteString(`\u00`)
+			sb.WriteByte(hexDigits[c>>4])
+			sb.WriteByte(hexDigits[c&0xf])
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte('"')
+}
+
+// ParseDiagnostic parses CBOR diagnostic notation and returns it encoded as DRISL.
+// This is useful for writing test fixtures in a readable way.
+//
+// Only the subset of diagnostic notation that can be represented in DRISL is supported:
+// integers, floats, strings, byte strings in hex (h'...'), true, false, null,
+// lists, maps with string keys, and CIDs written as 42(h'00...') or 42("bafy...").
+// Comments between slashes (/ like this /) are ignored.
+//
+// Map keys do not have to be in order, the output is always canonical DRISL.
+// Duplicate map keys, NaN, and infinity are errors.
+func ParseDiagnostic(s string) ([]byte, error) {
+	p := diagParser{s: s}
+	n, err := p.parseValue(0)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.skipSpace(); err != nil {
+		return nil, err
+	}
+	if p.off != len(p.s) {
+		return nil, p.errorf("unexpected data after value")
+	}
+	return n.MarshalCBOR()
+}
+
+type diagParser struct {
+	s   string
+	off int
+}
+
+// maxDiagDepth is the maximum nesting of lists and maps, matching the default
+// DecOptions.MaxNestedLevels.
+const maxDiagDepth = 32
+
+var (
+	minInt = new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 64))
+	maxInt = new(big.Int).SetUint64(math.MaxUint64)
+)
+
+func (p *diagParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("go-dasl/drisl: invalid diagnostic notation at offset %d: %s", p.off, fmt.Sprintf(format, args...))
+}
+
+// skipSpace skips whitespace and comments.
+func (p *diagParser) skipSpace() error {
+	for p.off < len(p.s) {
+		switch p.s[p.off] {
+		case ' ', '\t', '\n', '\r':
+			p.off++
+		case '/':
+			end := strings.IndexByte(p.s[p.off+1:], '/')
+			if end < 0 {
+				return p.errorf("unterminated comment")
+			}
+			p.off += end + 2
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// consume skips space and then c, returning whether c was found.
+func (p *diagParser) consume(c byte) (bool, error) {
+	if err := p.skipSpace(); err != nil {
+		return false, err
+	}
+	if p.off < len(p.s) && p.s[p.off] == c {
+		p.off++
+		return true, nil
+	}
+	return false, nil
+}
+
+func (p *diagParser) expect(c byte) error {
+	ok, err := p.consume(c)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return p.errorf("expected %q", c)
+	}
+	return nil
+}
+
+func (p *diagParser) parseValue(depth int) (Node, error) {
+	if err := p.skipSpace(); err != nil {
+		return Node{}, err
+	}
+	if p.off >= len(p.s) {
+		return Node{}, p.errorf("unexpected end of input")
+	}
+	switch c := p.s[p.off]; {
+	case c == '[':
+		if depth >= maxDiagDepth {
+			return Node{}, p.errorf("exceeded max nested level %d", maxDiagDepth)
+		}
+		p.off++
+		var list []Node
+		err := p.parseItems(']', func() error {
+			elem, err := p.parseValue(depth + 1)
+			list = append(list, elem)
+			return err
+		})
+		return NewList(list...), err
+	case c == '{':
+		if depth >= maxDiagDepth {
+			return Node{}, p.errorf("exceeded max nested level %d", maxDiagDepth)
+		}
+		p.off++
+		var entries []MapEntry
+		err := p.parseItems('}', func() error {
+			keyOff := p.off
+			key, err := p.parseValue(depth + 1)
+			if err != nil {
+				return err
+			}
+			if key.kind != KindString {
+				p.off = keyOff
+				return p.errorf("map keys must be strings")
+			}
+			if err := p.expect(':'); err != nil {
+				return err
+			}
+			value, err := p.parseValue(depth + 1)
+			entries = append(entries, MapEntry{Key: key.str, Value: value})
+			return err
+		})
+		return NewMap(entries...), err
+	case c == '"':
+		s, err := p.parseString()
+		return NewString(s), err
+	case c == 'h' && strings.HasPrefix(p.s[p.off:], "h'"):
+		b, err := p.parseHex()
+		return NewBytes(b), err
+	case c == '-' || c >= '0' && c <= '9':
+		return p.parseNumber()
+	default:
+		word := p.s[p.off:]
+		if i := strings.IndexFunc(word, func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+		}); i >= 0 {
+			word = word[:i]
+		}
+		switch word {
+		case "true", "false":
+			p.off += len(word)
+			return NewBool(word == "true"), nil
+		case "null":
+			p.off += len(word)
+			return NewNull(), nil
+		case "NaN", "Infinity":
+			return Node{}, p.errorf("NaN and infinity are not allowed")
+		case "undefined":
+			return Node{}, p.errorf("undefined is not allowed")
+		}
+		if word == "" {
+			_, size := utf8.DecodeRuneInString(p.s[p.off:])
+			word = p.s[p.off : p.off+size]
+		}
+		return Node{}, p.errorf("unexpected %q", word)
+	}
+}
+
+// parseItems parses comma-separated items until the closing byte,
+// after the opening byte has been consumed.
+func (p *diagParser) parseItems(closing byte, item func() error) error {
+	if ok, err := p.consume(closing); ok || err != nil {
+		return err
+	}
+	for {
+		if err := item(); err != nil {
+			return err
+		}
+		if ok, err := p.consume(closing); ok || err != nil {
+			return err
+		}
+		if err := p.expect(','); err != nil {
+			return err
+		}
+	}
+}
+
+func (p *diagParser) parseString() (string, error) {
+	start := p.off
+	for i := start + 1; i < len(p.s); i++ {
+		switch p.s[i] {
+		case '\\':
+			i++
+		case '"':
+			var s string
+			if err := json.Unmarshal([]byte(p.s[start:i+1]), &s); err != nil {
+				return "", p.errorf("invalid string: %v", err)
+			}
+			p.off = i + 1
+			return s, nil
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *diagParser) parseHex() ([]byte, error) {
+	end := strings.IndexByte(p.s[p.off+2:], '\'')
+	if end < 0 {
+		return nil, p.errorf("unterminated byte string")
+	}
+	// Whitespace is allowed inside byte strings
+	digits := strings.Join(strings.Fields(p.s[p.off+2:p.off+2+end]), "")
+	b, err := hex.DecodeString(digits)
+	if err != nil {
+		return nil, p.errorf("invalid hex: %v", err)
+	}
+	p.off += end + 3
+	return b, nil
+}
+
+func (p *diagParser) parseNumber() (Node, error) {
+	start := p.off
+	end := start + 1
+	for end < len(p.s) && strings.IndexByte("0123456789.eE+-", p.s[end]) >= 0 {
+		end++
+	}
+	num := p.s[start:end]
+	if num == "-" && strings.HasPrefix(p.s[end:], "Infinity") {
+		return Node{}, p.errorf("NaN and infinity are not allowed")
+	}
+
+	if strings.ContainsAny(num, ".eE") {
+		f, err := strconv.ParseFloat(num, 64)
+		if err != nil || math.IsInf(f, 0) {
+			return Node{}, p.errorf("invalid float %q", num)
+		}
+		p.off = end
+		return NewFloat(f), nil
+	}
+
+	i, ok := new(big.Int).SetString(num, 10)
+	if !ok {
+		return Node{}, p.errorf("invalid integer %q", num)
+	}
+	if i.Cmp(minInt) < 0 || i.Cmp(maxInt) > 0 {
+		return Node{}, p.errorf("integer %s out of range", num)
+	}
+	p.off = end
+	if end < len(p.s) && p.s[end] == '(' {
+		return p.parseTag(i)
+	}
+	n, _ := NewBigInt(i)
+	return n, nil
+}
+
+// parseTag parses the tagged value after a tag number, only allowing CIDs.
+func (p *diagParser) parseTag(tag *big.Int) (Node, error) {
+	if !tag.IsInt64() || tag.Int64() != 42 {
+		return Node{}, p.errorf("unsupported tag %s", tag)
+	}
+	p.off++ // (
+	if err := p.skipSpace(); err != nil {
+		return Node{}, err
+	}
+	valueOff := p.off
+	v, err := p.parseValue(maxDiagDepth)
+	if err != nil {
+		return Node{}, err
+	}
+	var c cid.Cid
+	switch v.kind {
+	case KindBytes:
+		if len(v.str) == 0 || v.str[0] != 0 {
+			p.off = valueOff
+			return Node{}, p.errorf("CID bytes must start with 00")
+		}
+		c, err = cid.NewCidFromBytes([]byte(v.str[1:]))
+	case KindString:
+		c, err = cid.NewCidFromString(v.str)
+	default:
+		p.off = valueOff
+		return Node{}, p.errorf("tag 42 must contain bytes or a string")
+	}
+	if err != nil {
+		p.off = valueOff
+		return Node{}, p.errorf("invalid CID: %v", err)
+	}
+	if err := p.expect(')'); err != nil {
+		return Node{}, err
+	}
+	return NewLink(c), nil
+}
@@ -0,0 +1,136 @@
+package drisl_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+)
+
+func TestDiagnoseRoundtrip(t *testing.T) {
+	for _, tt := range validTests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := hexDecode(tt.in)
+			for _, opts := range []drisl.DiagOptions{{}, {CidStrings: true, Indent: "  "}} {
+				diag, err := opts.Diagnose(data)
+				if err != nil {
+					t.Fatalf("Diagnose failed: %v", err)
+				}
+				got, err := drisl.ParseDiagnostic(diag)
+				if err != nil {
+					t.Fatalf("ParseDiagnostic(%q) failed: %v", diag, err)
+				}
+				if !bytes.Equal(got, data) {
+					t.Fatalf("%q: got %x, want %x", diag, got, data)
+				}
+			}
+		})
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	link := cid.HashBytes([]byte("a"))
+	data, err := drisl.Marshal(map[string]any{
+		"b":    []any{1, -2, 1.5, 2.0, "x\n\"y\"", []byte{0xca, 0xfe}, nil},
+		"a":    true,
+		"link": link,
+		"e":    []any{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := drisl.Diagnose(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"a": true, "b": [1, -2, 1.5, 2.0, "x\n\"y\"", h'cafe', null], "e": [], "link": 42(h'00` +
+		hex.EncodeToString(link.Bytes()) + `')}`
+	if got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	got, err = drisl.DiagOptions{CidStrings: true, Indent: "  "}.Diagnose(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `{
+  "a": true,
+  "b": [
+    1,
+    -2,
+    1.5,
+    2.0,
+    "x\n\"y\"",
+    h'cafe',
+    null
+  ],
+  "e": [],
+  "link": 42("` + link.String() + `")
+}`
+	if got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	if _, err := drisl.Diagnose(hexDecode("a2616201616101")); err == nil {
+		t.Fatal("Diagnose succeeded for invalid DRISL")
+	}
+}
+
+func TestParseDiagnostic(t *testing.T) {
+	link := cid.HashBytes([]byte("a"))
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`0`, "00"},
+		{`-18446744073709551616`, "3bffffffffffffffff"},
+		{`1.0e0 / a comment /`, "fb3ff0000000000000"},
+		{`h'01 02 03'`, "43010203"},
+		{`"é"`, "62c3a9"},
+		{`{ "b": 2, "a": 1 }`, "a2616101616202"},
+		{`[null, false, [], {}]`, "84f6f480a0"},
+		{`42("` + link.String() + `")`, "d82a582500" + hex.EncodeToString(link.Bytes())},
+	}
+	for _, tt := range tests {
+		got, err := drisl.ParseDiagnostic(tt.in)
+		if err != nil {
+			t.Errorf("ParseDiagnostic(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if !bytes.Equal(got, hexDecode(tt.want)) {
+			t.Errorf("ParseDiagnostic(%q) = %x, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseDiagnosticInvalid(t *testing.T) {
+	for _, in := range []string{
+		``,
+		`[1, 2`,
+		`[1 2]`,
+		`{1: 2}`,
+		`{"a": 1, "a": 2}`,
+		`"abc`,
+		`h'0'`,
+		`NaN`,
+		`-Infinity`,
+		`1e999`,
+		`undefined`,
+		`18446744073709551616`,
+		`-18446744073709551617`,
+		`1(2)`,
+		`42(h'01')`,
+		`42("nope")`,
+		`1 2`,
+		`/ comment`,
+		strings.Repeat("[", 33) + strings.Repeat("]", 33),
+	} {
+		if _, err := drisl.ParseDiagnostic(in); err == nil {
+			t.Errorf("ParseDiagnostic(%q) succeeded, want error", in)
+		}
+	}
+}
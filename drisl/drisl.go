@@ -6,7 +6,6 @@ https://dasl.ing/drisl.html
 package drisl
 
 import (
-	"crypto/sha256"
 	"io"
 	"reflect"
 
@@ -220,12 +219,6 @@ func NewDecoder(r io.Reader) *cbor.Decoder {
 	return drislDecMode.NewDecoder(r)
 }
 
-// func Valid(data []byte) bool {
-// 	// XXX: this is correct but inefficient
-// 	var v any
-// 	return drislDecMode.Unmarshal(data, &v) == nil
-// }
-
 // DecOptions specifies decoding options.
 type DecOptions struct {
 	// MaxNestedLevels specifies the max nested levels allowed for any combination of CBOR array, maps, and tags.
@@ -279,6 +272,35 @@ type DecMode interface {
 	NewDecoder(r io.Reader) *cbor.Decoder
 }
 
+type decMode struct {
+	cbor.DecMode
+	opts DecOptions
+}
+
+// modeOf returns dm as a decMode. If dm was not created by DecOptions.DecMode,
+// the default decoding mode is returned instead.
+func modeOf(dm DecMode) *decMode {
+	if m, ok := dm.(*decMode); ok {
+		return m
+	}
+	return drislDecMode.(*decMode)
+}
+
+// DecOptionsOf returns the options dm was created with. If dm was not created by
+// DecOptions.DecMode, the zero DecOptions are returned.
+func DecOptionsOf(dm DecMode) DecOptions {
+	return modeOf(dm).opts
+}
+
+func (dm *decMode) Unmarshal(data []byte, v any) error {
+	if mu, ok := v.(ModeUnmarshaler); ok {
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && !rv.IsNil() {
+			return mu.UnmarshalDRISL(dm, data)
+		}
+	}
+	return dm.DecMode.Unmarshal(data, v)
+}
+
 // DecMode returns a DecMode to decode with the given options.
 func (opts DecOptions) DecMode() (DecMode, error) {
 	thisSvr := svr
@@ -313,10 +335,15 @@ func (opts DecOptions) DecMode() (DecMode, error) {
 		NoFloats:           opts.NoFloats,
 		ExtraReturnErrors:  extra,
 	}
+	tags := cidTag
 	if opts.UseRawCid {
-		return do.DecModeWithSharedTags(rawCidTag)
+		tags = rawCidTag
 	}
-	return do.DecModeWithSharedTags(cidTag)
+	dm, err := do.DecModeWithSharedTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	return &decMode{dm, opts}, nil
 }
 
 // TimeMode specifies how to encode time.Time values
@@ -454,16 +481,33 @@ type Unmarshaler interface {
 	UnmarshalCBOR([]byte) error
 }
 
+// ModeUnmarshaler is the interface implemented by types that can unmarshal DRISL
+// themselves while honouring the options of a DecMode, like the types generated by
+// drislgen. Unlike UnmarshalCBOR, UnmarshalDRISL is given data that has not been
+// validated yet, and must validate it with ValidateWith.
+//
+// DecMode.Unmarshal and Unmarshal call UnmarshalDRISL instead of UnmarshalCBOR when
+// decoding directly into a ModeUnmarshaler. When it is nested in a type decoded
+// with reflection, UnmarshalCBOR is called instead, without the options.
+type ModeUnmarshaler interface {
+	UnmarshalDRISL(dm DecMode, data []byte) error
+}
+
 // CidForValue calculates the DRISL SHA-256 CID for the given Go value.
 // This is achieved by marshalling it into DRISL and then hashing those bytes.
 // An error is returned if the value could not be marshalled.
 func CidForValue(v any) (cid.Cid, error) {
+	return CidForValueWith(v, cid.HashTypeSha256)
+}
+
+// CidForValueWith is like CidForValue, but hashes with the given hash type,
+// like cid.HashTypeBlake3. An error is returned if the hash type is not supported.
+func CidForValueWith(v any, hashType cid.HashType) (cid.Cid, error) {
 	b, err := Marshal(v)
 	if err != nil {
 		return cid.Cid{}, err
 	}
-	digest := sha256.Sum256(b)
-	return cid.NewCidFromInfo(cid.CodecDrisl, cid.HashTypeSha256, digest)
+	return cid.HashDrisl(b, hashType)
 }
 
 // RawMessage is a raw encoded DRISL value.
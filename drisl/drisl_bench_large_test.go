@@ -111,6 +111,33 @@ func BenchmarkUnmarshalTwitterCid(b *testing.B) {
 	}
 }
 
+func BenchmarkValidateTwitterCid(b *testing.B) {
+	// Load
+	data, err := os.ReadFile("testdata/twitter.json")
+	if err != nil {
+		panic(err)
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		panic(err)
+	}
+	// Add in CIDs
+	cid := cid.MustNewCidFromString("bafkreifn5yxi7nkftsn46b6x26grda57ict7md2xuvfbsgkiahe2e7vnq4")
+	for _, status := range v.(map[string]any)["statuses"].([]any) {
+		status.(map[string]any)["cid"] = cid
+	}
+	// Marshal and measure
+	marshaled, err := drisl.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	b.SetBytes(int64(len(marshaled)))
+	// Benchmark
+	for b.Loop() {
+		drisl.Validate(marshaled)
+	}
+}
+
 func BenchmarkUnmarshalBluesky(b *testing.B) {
 	f, err := os.Open("/tmp/firehose.bin")
 	if err != nil {
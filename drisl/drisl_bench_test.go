@@ -256,6 +256,12 @@ func BenchmarkUnmarshal(b *testing.B) {
 			data:         hexDecode("A76141F56142581A0102030405060708090A0B0C0D0E0F101112131415161718191A6143FBC0106666666666666144782B54686520717569636B2062726F776E20666F78206A756D7073206F76657220746865206C617A7920646F676255491BFFFFFFFFFFFFFFFF634D7373AD6163614361656145616661466167614761686148616D614E616E614D616F61416170614261716144617261496173614A6174614C64456C6369981A0102030405060708090A0B0C0D0E0F101112131415161718181819181A"),
 			decodeToType: reflect.TypeOf(T1{}),
 		},
+		// Unmarshal CBOR map with string key to struct with generated methods.
+		{
+			name:         "CBOR map to Go struct generated",
+			data:         hexDecode("A76141F56142581A0102030405060708090A0B0C0D0E0F101112131415161718191A6143FBC0106666666666666144782B54686520717569636B2062726F776E20666F78206A756D7073206F76657220746865206C617A7920646F676255491BFFFFFFFFFFFFFFFF634D7373AD6163614361656145616661466167614761686148616D614E616E614D616F61416170614261716144617261496173614A6174614C64456C6369981A0102030405060708090A0B0C0D0E0F101112131415161718181819181A"),
+			decodeToType: reflect.TypeOf(GenT1{}),
+		},
 		// Unmarshal CBOR array of known sequence of data types, such as signed/maced/encrypted CWT, to []interface{}.
 		{
 			name:         "CBOR array to Go []interface{}",
@@ -268,6 +274,12 @@ func BenchmarkUnmarshal(b *testing.B) {
 			data:         hexDecode("88F51BFFFFFFFFFFFFFFFF3903E7FBC010666666666666581A0102030405060708090A0B0C0D0E0F101112131415161718191A782B54686520717569636B2062726F776E20666F78206A756D7073206F76657220746865206C617A7920646F67981A0102030405060708090A0B0C0D0E0F101112131415161718181819181AAD616261426163614361646144616561456166614661696149616D614E616E6141616F6147617061486171614A6172614C6173614D"),
 			decodeToType: reflect.TypeOf(T3{}),
 		},
+		// Unmarshal CBOR array to struct toarray with generated methods.
+		{
+			name:         "CBOR array to Go struct toarray generated",
+			data:         hexDecode("88F51BFFFFFFFFFFFFFFFF3903E7FBC010666666666666581A0102030405060708090A0B0C0D0E0F101112131415161718191A782B54686520717569636B2062726F776E20666F78206A756D7073206F76657220746865206C617A7920646F67981A0102030405060708090A0B0C0D0E0F101112131415161718181819181AAD616261426163614361646144616561456166614661696149616D614E616E6141616F6147617061486171614A6172614C6173614D"),
+			decodeToType: reflect.TypeOf(GenT3{}),
+		},
 	}
 	for _, bm := range moreBenchmarks {
 		b.Run(bm.name, func(b *testing.B) {
@@ -355,6 +367,10 @@ func BenchmarkMarshal(b *testing.B) {
 			name:  "Go struct to CBOR map",
 			value: v1,
 		},
+		{
+			name:  "Go struct generated to CBOR map",
+			value: GenT1(v1),
+		},
 		{
 			name:  "Go struct many fields all omitempty all empty to CBOR map",
 			value: ManyFieldsAllOmitEmpty{},
@@ -392,6 +408,10 @@ func BenchmarkMarshal(b *testing.B) {
 			name:  "Go struct toarray to CBOR array",
 			value: v3,
 		},
+		{
+			name:  "Go struct toarray generated to CBOR array",
+			value: genT3,
+		},
 	}
 	for _, bm := range moreBenchmarks {
 		b.Run(bm.name, func(b *testing.B) {
@@ -506,3 +526,58 @@ func BenchmarkUnmarshalMapToStruct(b *testing.B) {
 		}
 	}
 }
+
+// BenchmarkGenerated compares types with methods generated by drislgen to the same
+// types decoded with reflection.
+func BenchmarkGenerated(b *testing.B) {
+	link := cid.MustNewCidFromString("bafkreifn5yxi7nkftsn46b6x26grda57ict7md2xuvfbsgkiahe2e7vnq4")
+	opt := int64(-5)
+	record := GenRecord{
+		Type:   "app.bsky.feed.post",
+		Text:   "The quick brown fox jumps over the lazy dog",
+		Count:  42,
+		Big:    18446744073709551615,
+		Link:   link,
+		Langs:  []string{"en", "fr"},
+		Labels: map[string]int64{"a": 1, "b": 2},
+		Reply:  &GenRef{Root: link, Parent: link},
+		Facets: []GenFacet{{Start: 0, End: 3, Tags: []string{"fox"}}, {Start: 4, End: 9}},
+		Links:  map[string]cid.Cid{"self": link},
+		Opt:    &opt,
+	}
+	for _, tc := range []struct {
+		name     string
+		gen, std any
+	}{
+		{"struct", genT1, plainT1(genT1)},
+		{"struct toarray", genT3, plainT3(genT3)},
+		{"record", record, plainRecord(record)},
+	} {
+		data, err := drisl.Marshal(tc.std)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, v := range []struct {
+			name  string
+			value any
+		}{{"reflection", tc.std}, {"generated", tc.gen}} {
+			b.Run("Marshal "+tc.name+"/"+v.name, func(b *testing.B) {
+				b.SetBytes(int64(len(data)))
+				for b.Loop() {
+					if _, err := drisl.Marshal(v.value); err != nil {
+						b.Fatal("Marshal:", err)
+					}
+				}
+			})
+			b.Run("Unmarshal "+tc.name+"/"+v.name, func(b *testing.B) {
+				t := reflect.TypeOf(v.value)
+				b.SetBytes(int64(len(data)))
+				for b.Loop() {
+					if err := drisl.Unmarshal(data, reflect.New(t).Interface()); err != nil {
+						b.Fatal("Unmarshal:", err)
+					}
+				}
+			})
+		}
+	}
+}
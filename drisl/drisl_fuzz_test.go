@@ -46,6 +46,21 @@ func FuzzUnmarshal(f *testing.F) {
 	})
 }
 
+// Make sure Validate agrees with Unmarshal
+func FuzzValidate(f *testing.F) {
+	for _, seed := range seeds() {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, val []byte) {
+		var v any
+		err := drisl.Unmarshal(val, &v)
+		err2 := drisl.Validate(val)
+		if (err == nil) != (err2 == nil) {
+			t.Errorf("Unmarshal: %v | Validate: %v", err, err2)
+		}
+	})
+}
+
 // Make sure Decoder performs the same as Unmarshal
 func FuzzDecoder(f *testing.F) {
 	for _, seed := range seeds() {
@@ -74,6 +89,28 @@ func FuzzDecoder(f *testing.F) {
 	})
 }
 
+// Canonicalize output must be valid, and valid input must be unchanged
+func FuzzCanonicalize(f *testing.F) {
+	for _, seed := range seeds() {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, val []byte) {
+		result, err := drisl.Canonicalize(val, drisl.CanonOptions{})
+		if err != nil {
+			if drisl.Valid(val) {
+				t.Errorf("Canonicalize failed for valid data %x: %v", val, err)
+			}
+			return
+		}
+		if err := drisl.Validate(result); err != nil {
+			t.Errorf("Canonicalize(%x) = %x, which is invalid: %v", val, result, err)
+		}
+		if drisl.Valid(val) && !bytes.Equal(result, val) {
+			t.Errorf("Canonicalize(%x) = %x, want unchanged", val, result)
+		}
+	})
+}
+
 type marshaler struct{ val []byte }
 
 func (m marshaler) MarshalCBOR() ([]byte, error) {
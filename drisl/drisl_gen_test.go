@@ -0,0 +1,396 @@
+package drisl_test
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+	"pgregory.net/rapid"
+)
+
+//go:generate go run ../cmd/drislgen -type GenT1,GenT3,GenRecord,GenRef,GenFacet -output drisl_gen_types_test.go
+
+// GenT1 and GenT3 are T1 and T3 with generated methods.
+type GenT1 struct {
+	T    bool
+	UI   uint
+	I    int
+	F    float64
+	B    []byte
+	S    string
+	Slci []int
+	Mss  map[string]string
+}
+
+type GenT3 struct {
+	_    struct{} `cbor:",toarray"`
+	T    bool
+	UI   uint
+	I    int
+	F    float64
+	B    []byte
+	S    string
+	Slci []int
+	Mss  map[string]string
+}
+
+// GenRecord covers the field types and options supported by drislgen.
+type GenRecord struct {
+	Type      string             `cbor:"$type"`
+	Text      string             `json:"text"`
+	Count     int32              `cbor:"count,omitzero"`
+	Score     float32            `cbor:"score,omitempty"`
+	Ratio     float64            `cbor:"ratio,omitzero"`
+	Small     int8               `cbor:"small,omitempty"`
+	Big       uint64             `cbor:"big"`
+	Flag      bool               `cbor:"flag,omitempty"`
+	Link      cid.Cid            `cbor:"link,omitzero"`
+	Blob      []byte             `cbor:"blob,omitzero"`
+	Embed     drisl.RawMessage   `cbor:"embed,omitempty"`
+	Langs     []string           `cbor:"langs,omitempty"`
+	Labels    map[string]int64   `cbor:"labels,omitempty"`
+	Reply     *GenRef            `cbor:"reply,omitempty"`
+	Facets    []GenFacet         `cbor:"facets"`
+	Main      GenFacet           `cbor:"main,omitzero"`
+	Links     map[string]cid.Cid `cbor:"links,omitempty"`
+	Opt       *int64             `cbor:"opt"`
+	Extra     any                `cbor:"extra,omitempty"`
+	Pair      [2]int             `cbor:"pair,omitzero"`
+	Ignored   string             `cbor:"-"`
+	unexposed int
+}
+
+type GenRef struct {
+	Root   cid.Cid `cbor:"root"`
+	Parent cid.Cid `cbor:"parent"`
+}
+
+type GenFacet struct {
+	Start uint16
+	End   uint16
+	Tags  []string `cbor:"tags,omitempty"`
+}
+
+// The plain types have the same fields, without the generated methods.
+type (
+	plainT1     GenT1
+	plainT3     GenT3
+	plainRecord GenRecord
+)
+
+func genCid() *rapid.Generator[cid.Cid] {
+	return rapid.Custom(func(t *rapid.T) cid.Cid {
+		return cid.HashBytes(rapid.SliceOf(rapid.Byte()).Draw(t, "data"))
+	})
+}
+
+func genFacet() *rapid.Generator[GenFacet] {
+	return rapid.Custom(func(t *rapid.T) GenFacet {
+		return GenFacet{
+			Start: rapid.Uint16().Draw(t, "start"),
+			End:   rapid.Uint16().Draw(t, "end"),
+			Tags:  rapid.SliceOf(rapid.String()).Draw(t, "tags"),
+		}
+	})
+}
+
+func genRecord() *rapid.Generator[GenRecord] {
+	return rapid.Custom(func(t *rapid.T) GenRecord {
+		r := GenRecord{
+			Type:   rapid.SampledFrom([]string{"", "app.bsky.feed.post"}).Draw(t, "type"),
+			Text:   rapid.String().Draw(t, "text"),
+			Count:  rapid.Int32().Draw(t, "count"),
+			Score:  rapid.Float32().Draw(t, "score"),
+			Ratio:  rapid.SampledFrom([]float64{0, math.Copysign(0, -1), -4.1, 1e300}).Draw(t, "ratio"),
+			Small:  rapid.Int8().Draw(t, "small"),
+			Big:    rapid.Uint64().Draw(t, "big"),
+			Flag:   rapid.Bool().Draw(t, "flag"),
+			Blob:   rapid.SliceOf(rapid.Byte()).Draw(t, "blob"),
+			Langs:  rapid.SliceOf(rapid.String()).Draw(t, "langs"),
+			Labels: rapid.MapOf(rapid.String(), rapid.Int64()).Draw(t, "labels"),
+			Facets: rapid.SliceOf(genFacet()).Draw(t, "facets"),
+			Main:   genFacet().Draw(t, "main"),
+			Links:  rapid.MapOf(rapid.String(), genCid()).Draw(t, "links"),
+			Pair:   [2]int{rapid.Int().Draw(t, "pair0"), rapid.Int().Draw(t, "pair1")},
+		}
+		if rapid.Bool().Draw(t, "hasLink") {
+			r.Link = genCid().Draw(t, "link")
+		}
+		if rapid.Bool().Draw(t, "hasEmbed") {
+			r.Embed = hexDecode("a1646b696e64646c696e6b")
+		}
+		if rapid.Bool().Draw(t, "hasReply") {
+			r.Reply = &GenRef{Root: genCid().Draw(t, "root"), Parent: genCid().Draw(t, "parent")}
+		}
+		if rapid.Bool().Draw(t, "hasOpt") {
+			opt := rapid.Int64().Draw(t, "opt")
+			r.Opt = &opt
+		}
+		if rapid.Bool().Draw(t, "hasExtra") {
+			r.Extra = rapid.SampledFrom([]any{"extra", uint64(1), []any{true, nil}}).Draw(t, "extra")
+		}
+		return r
+	})
+}
+
+var genT1 = GenT1{
+	T:    true,
+	UI:   18446744073709551615,
+	I:    -1000,
+	F:    -4.1,
+	B:    []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26},
+	S:    "The quick brown fox jumps over the lazy dog",
+	Slci: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26},
+	Mss:  map[string]string{"a": "A", "b": "B", "c": "C", "d": "D", "e": "E", "f": "F", "g": "G", "h": "H", "i": "I", "j": "J", "l": "L", "m": "M", "n": "N"},
+}
+
+var genT3 = GenT3{
+	T:    genT1.T,
+	UI:   genT1.UI,
+	I:    genT1.I,
+	F:    genT1.F,
+	B:    genT1.B,
+	S:    genT1.S,
+	Slci: genT1.Slci,
+	Mss:  genT1.Mss,
+}
+
+func TestGeneratedMarshal(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		gen, std any
+	}{
+		{"T1", genT1, plainT1(genT1)},
+		{"T1 empty", GenT1{}, plainT1{}},
+		{"T3", genT3, plainT3(genT3)},
+		{"T3 empty", GenT3{}, plainT3{}},
+		{"record empty", GenRecord{}, plainRecord{}},
+	} {
+		got, err := drisl.Marshal(tc.gen)
+		if err != nil {
+			t.Fatalf("%s: Marshal: %v", tc.name, err)
+		}
+		want, err := drisl.Marshal(tc.std)
+		if err != nil {
+			t.Fatalf("%s: Marshal plain: %v", tc.name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("%s: got %x, want %x", tc.name, got, want)
+		}
+	}
+}
+
+func TestGeneratedRoundtrip(t *testing.T) {
+	rapid.Check(t, func(t *rapid.T) {
+		r := genRecord().Draw(t, "record")
+		got, err := r.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("MarshalCBOR: %v", err)
+		}
+		want, err := drisl.Marshal(plainRecord(r))
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("got %x, want %x", got, want)
+		}
+
+		var gotR GenRecord
+		if err := gotR.UnmarshalCBOR(got); err != nil {
+			t.Fatalf("UnmarshalCBOR: %v", err)
+		}
+		var wantR plainRecord
+		if err := drisl.Unmarshal(want, &wantR); err != nil {
+			t.Fatalf("Unmarshal: %v", err)
+		}
+		if !reflect.DeepEqual(plainRecord(gotR), wantR) {
+			t.Fatalf("got %+v, want %+v", gotR, wantR)
+		}
+	})
+}
+
+func TestGeneratedUnmarshal(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		// Keys in a different case, unknown keys, and nulls
+		{"case insensitive", hexDecode("a3617402617501617901")},
+		{"nulls", hexDecode("a4617401614ef66142f66153f6")},
+		{"int to float", hexDecode("a1614618ff")},
+		// T is still decoded after the type error in S
+		{"continue after error", hexDecode("a26153016154f5")},
+		{"duplicate field", hexDecode("a36149016154f5616902")},
+	} {
+		var got GenT1
+		gotErr := drisl.Unmarshal(tc.data, &got)
+		var want plainT1
+		wantErr := drisl.Unmarshal(tc.data, &want)
+		if (gotErr == nil) != (wantErr == nil) {
+			t.Fatalf("%s: got error %v, want %v", tc.name, gotErr, wantErr)
+		}
+		if !reflect.DeepEqual(plainT1(got), want) {
+			t.Fatalf("%s: got %+v, want %+v", tc.name, got, want)
+		}
+	}
+}
+
+func TestGeneratedUnmarshalErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data []byte
+		v    any
+	}{
+		{"wrong type", hexDecode("a1615461"), new(GenT1)},
+		{"overflow", hexDecode("a16149" + "1bffffffffffffffff"), new(GenT1)},
+		{"float32 precision", hexDecode("a16573636f7265fbc010666666666666"), new(GenRecord)},
+		{"toarray length", hexDecode("820102"), new(GenT3)},
+		{"duplicate field", hexDecode("a2614901616902"), new(GenT1)},
+		{"invalid", hexDecode("a2614201614101"), new(GenT1)},
+		{"undefined cid", hexDecode("a1647265706c79a0"), new(GenRecord)},
+	} {
+		if err := drisl.Unmarshal(tc.data, tc.v); err == nil {
+			t.Fatalf("%s: got no error", tc.name)
+		}
+	}
+}
+
+func TestGeneratedMarshalErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		v    any
+	}{
+		{"NaN", GenT1{F: math.NaN()}},
+		{"undefined cid", GenRecord{Reply: &GenRef{}}},
+		{"invalid raw", GenRecord{Embed: hexDecode("a10101")}},
+	} {
+		if _, err := drisl.Marshal(tc.v); err == nil {
+			t.Fatalf("%s: got no error", tc.name)
+		}
+	}
+}
+
+func TestGeneratedDecMode(t *testing.T) {
+	// 40 nested arrays in an unknown field, past the default limit of 32 levels
+	deep := append(hexDecode("a261490165446565707c"), bytes.Repeat([]byte{0x81}, 40)...)
+	deep = append(deep, 0xf6)
+	for _, tc := range []struct {
+		name string
+		opts drisl.DecOptions
+		data []byte
+		ok   bool
+	}{
+		{"default", drisl.DecOptions{}, deep, false},
+		{"max nested levels", drisl.DecOptions{MaxNestedLevels: 64}, deep, true},
+		{"disallow unknown fields", drisl.DecOptions{DisallowUnknownFields: true}, hexDecode("a2614901614e01"), false},
+		{"known fields", drisl.DecOptions{DisallowUnknownFields: true}, hexDecode("a1614901"), true},
+		{"undefined", drisl.DecOptions{}, hexDecode("a26149016153f7"), false},
+		{"allow undefined", drisl.DecOptions{AllowUndefined: true}, hexDecode("a26149016153f7"), true},
+	} {
+		dm, err := tc.opts.DecMode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got GenT1
+		gotErr := dm.Unmarshal(tc.data, &got)
+		var want plainT1
+		wantErr := dm.Unmarshal(tc.data, &want)
+		if (gotErr == nil) != tc.ok || (wantErr == nil) != tc.ok {
+			t.Fatalf("%s: got error %v, reflection got %v, want success %v", tc.name, gotErr, wantErr, tc.ok)
+		}
+		if !reflect.DeepEqual(plainT1(got), want) {
+			t.Fatalf("%s: got %+v, want %+v", tc.name, got, want)
+		}
+	}
+}
+
+// genDrislValue generates arbitrary DRISL values, which are often the wrong type
+// for the fields they end up in.
+func genDrislValue(depth int) *rapid.Generator[any] {
+	return rapid.Custom(func(t *rapid.T) any {
+		n := 9
+		if depth > 0 {
+			n = 12
+		}
+		switch rapid.IntRange(0, n-1).Draw(t, "kind") {
+		case 0:
+			return nil
+		case 1:
+			return rapid.Bool().Draw(t, "bool")
+		case 2:
+			return rapid.SampledFrom([]uint64{0, 1, 255, 65536, math.MaxInt64, math.MaxUint64}).Draw(t, "uint")
+		case 3:
+			return rapid.SampledFrom([]int64{-1, -129, -65537, math.MinInt64}).Draw(t, "int")
+		case 4:
+			return rapid.SampledFrom([]float64{0, -4.1, 0.5, 1e300}).Draw(t, "float")
+		case 5:
+			return rapid.StringN(0, 4, -1).Draw(t, "string")
+		case 6:
+			return rapid.SliceOfN(rapid.Byte(), 0, 4).Draw(t, "bytes")
+		case 7:
+			return genCid().Draw(t, "cid")
+		case 8:
+			return map[string]any{}
+		case 9:
+			return rapid.SliceOfN(genDrislValue(depth-1), 0, 3).Draw(t, "array")
+		default:
+			return rapid.MapOfN(rapid.StringN(0, 2, -1), genDrislValue(depth-1), 0, 3).Draw(t, "map")
+		}
+	})
+}
+
+// genDrislStruct generates maps with keys that match, differ only in case from,
+// or don't match the field names.
+func genDrislStruct(names []string) *rapid.Generator[map[string]any] {
+	var keys []string
+	for _, name := range names {
+		keys = append(keys, name, strings.ToUpper(name), strings.ToLower(name))
+	}
+	keys = append(keys, "x", "Other")
+	return rapid.MapOfN(rapid.SampledFrom(keys), genDrislValue(2), 0, 6)
+}
+
+func TestGeneratedUnmarshalDifferential(t *testing.T) {
+	check := func(t *rapid.T, opts drisl.DecOptions, data []byte, gen, std any) {
+		dm, err := opts.DecMode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotErr := gen.(drisl.ModeUnmarshaler).UnmarshalDRISL(dm, data)
+		wantErr := dm.Unmarshal(data, std)
+		if (gotErr == nil) != (wantErr == nil) {
+			t.Fatalf("data %x: got error %v, reflection got %v", data, gotErr, wantErr)
+		}
+		if !reflect.DeepEqual(reflect.ValueOf(gen).Elem().Interface(), reflect.ValueOf(std).Elem().Convert(reflect.TypeOf(gen).Elem()).Interface()) {
+			t.Fatalf("data %x: got %+v, reflection got %+v", data, gen, std)
+		}
+	}
+	t.Run("T1", rapid.MakeCheck(func(t *rapid.T) {
+		data, err := drisl.Marshal(genDrislStruct(drislFieldsGenT1).Draw(t, "map"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts := drisl.DecOptions{DisallowUnknownFields: rapid.Bool().Draw(t, "disallowUnknown")}
+		check(t, opts, data, new(GenT1), new(plainT1))
+	}))
+	t.Run("T3", rapid.MakeCheck(func(t *rapid.T) {
+		data, err := drisl.Marshal(rapid.SliceOfN(genDrislValue(2), 7, 9).Draw(t, "array"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, drisl.DecOptions{}, data, new(GenT3), new(plainT3))
+	}))
+	t.Run("record", rapid.MakeCheck(func(t *rapid.T) {
+		data, err := drisl.Marshal(genDrislStruct(drislFieldsGenRecord).Draw(t, "map"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Reflection decodes the generated types nested in plainRecord without
+		// the options, so only the defaults give the same results
+		check(t, drisl.DecOptions{}, data, new(GenRecord), new(plainRecord))
+	}))
+}
@@ -0,0 +1,806 @@
+// Code generated by "drislgen -type GenT1,GenT3,GenRecord,GenRef,GenFacet -output drisl_gen_types_test.go"; DO NOT EDIT.
+
+package drisl_test
+
+import (
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+	"github.com/hyphacoop/go-dasl/drisl/gensupport"
+)
+
+// MarshalCBOR fulfills the drisl.Marshaler interface.
+func (v GenT1) MarshalCBOR() ([]byte, error) {
+	return v.appendDRISL(nil)
+}
+
+func (v GenT1) appendDRISL(b []byte) ([]byte, error) {
+	var err error
+	b = gensupport.AppendMapHead(b, 8)
+	b = gensupport.AppendString(b, "B")
+	b = gensupport.AppendBytes(b, v.B)
+	b = gensupport.AppendString(b, "F")
+	if b, err = gensupport.AppendFloat(b, float64(v.F)); err != nil {
+		return nil, err
+	}
+	b = gensupport.AppendString(b, "I")
+	b = gensupport.AppendInt(b, int64(v.I))
+	b = gensupport.AppendString(b, "S")
+	b = gensupport.AppendString(b, v.S)
+	b = gensupport.AppendString(b, "T")
+	b = gensupport.AppendBool(b, v.T)
+	b = gensupport.AppendString(b, "UI")
+	b = gensupport.AppendUint(b, uint64(v.UI))
+	b = gensupport.AppendString(b, "Mss")
+	if v.Mss == nil {
+		b = gensupport.AppendNull(b)
+	} else {
+		b = gensupport.AppendMapHead(b, len(v.Mss))
+		for _, k1 := range gensupport.SortedKeys(v.Mss) {
+			b = gensupport.AppendString(b, k1)
+			b = gensupport.AppendString(b, v.Mss[k1])
+		}
+	}
+	b = gensupport.AppendString(b, "Slci")
+	if v.Slci == nil {
+		b = gensupport.AppendNull(b)
+	} else {
+		b = gensupport.AppendArrayHead(b, len(v.Slci))
+		for _, e2 := range v.Slci {
+			b = gensupport.AppendInt(b, int64(e2))
+		}
+	}
+	return b, nil
+}
+
+// UnmarshalCBOR fulfills the drisl.Unmarshaler interface.
+func (v *GenT1) UnmarshalCBOR(data []byte) error {
+	d, err := gensupport.NewDecoder(data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+// UnmarshalDRISL fulfills the drisl.ModeUnmarshaler interface.
+func (v *GenT1) UnmarshalDRISL(dm drisl.DecMode, data []byte) error {
+	d, err := gensupport.NewDecoderMode(dm, data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+var drislFieldsGenT1 = []string{"T", "UI", "I", "F", "B", "S", "Slci", "Mss"}
+
+func (v *GenT1) decodeDRISL(d *gensupport.Decoder) {
+	if d.SkipNull() {
+		return
+	}
+	n, err := d.MapHead("drisl_test.GenT1")
+	if err != nil {
+		return
+	}
+	var seen [8]bool
+	for i := range n {
+		f, err := d.Field("drisl_test.GenT1", drislFieldsGenT1, seen[:])
+		if err != nil {
+			d.SkipPairs(n - i - 1)
+			return
+		}
+		switch f {
+		case 0:
+			if !d.SkipNull() {
+				d.Bool(&v.T)
+			}
+		case 1:
+			if !d.SkipNull() {
+				gensupport.Int(d, &v.UI)
+			}
+		case 2:
+			if !d.SkipNull() {
+				gensupport.Int(d, &v.I)
+			}
+		case 3:
+			if !d.SkipNull() {
+				gensupport.Float(d, &v.F)
+			}
+		case 4:
+			if d.SkipNull() {
+				v.B = nil
+			} else {
+				d.Bytes(&v.B)
+			}
+		case 5:
+			if !d.SkipNull() {
+				d.String(&v.S)
+			}
+		case 6:
+			if d.SkipNull() {
+				v.Slci = nil
+			} else if n3, err := d.ArrayHead("[]int"); err == nil {
+				if n3 == 0 || cap(v.Slci) < n3 {
+					v.Slci = make([]int, n3)
+				} else {
+					v.Slci = v.Slci[:n3]
+				}
+				for i4 := range v.Slci {
+					if !d.SkipNull() {
+						gensupport.Int(d, &v.Slci[i4])
+					}
+				}
+			}
+		case 7:
+			if d.SkipNull() {
+				v.Mss = nil
+			} else if n5, err := d.MapHead("map[string]string"); err == nil {
+				if v.Mss == nil {
+					v.Mss = make(map[string]string, n5)
+				}
+				var e7 string
+				for range n5 {
+					var k6 string
+					d.String(&k6)
+					errs8 := d.Errors()
+					if !d.SkipNull() {
+						d.String(&e7)
+					}
+					if d.Errors() == errs8 {
+						v.Mss[k6] = e7
+					}
+				}
+			}
+		}
+	}
+}
+
+// MarshalCBOR fulfills the drisl.Marshaler interface.
+func (v GenT3) MarshalCBOR() ([]byte, error) {
+	return v.appendDRISL(nil)
+}
+
+func (v GenT3) appendDRISL(b []byte) ([]byte, error) {
+	var err error
+	b = gensupport.AppendArrayHead(b, 8)
+	b = gensupport.AppendBool(b, v.T)
+	b = gensupport.AppendUint(b, uint64(v.UI))
+	b = gensupport.AppendInt(b, int64(v.I))
+	if b, err = gensupport.AppendFloat(b, float64(v.F)); err != nil {
+		return nil, err
+	}
+	b = gensupport.AppendBytes(b, v.B)
+	b = gensupport.AppendString(b, v.S)
+	if v.Slci == nil {
+		b = gensupport.AppendNull(b)
+	} else {
+		b = gensupport.AppendArrayHead(b, len(v.Slci))
+		for _, e1 := range v.Slci {
+			b = gensupport.AppendInt(b, int64(e1))
+		}
+	}
+	if v.Mss == nil {
+		b = gensupport.AppendNull(b)
+	} else {
+		b = gensupport.AppendMapHead(b, len(v.Mss))
+		for _, k2 := range gensupport.SortedKeys(v.Mss) {
+			b = gensupport.AppendString(b, k2)
+			b = gensupport.AppendString(b, v.Mss[k2])
+		}
+	}
+	return b, nil
+}
+
+// UnmarshalCBOR fulfills the drisl.Unmarshaler interface.
+func (v *GenT3) UnmarshalCBOR(data []byte) error {
+	d, err := gensupport.NewDecoder(data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+// UnmarshalDRISL fulfills the drisl.ModeUnmarshaler interface.
+func (v *GenT3) UnmarshalDRISL(dm drisl.DecMode, data []byte) error {
+	d, err := gensupport.NewDecoderMode(dm, data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+func (v *GenT3) decodeDRISL(d *gensupport.Decoder) {
+	if d.SkipNull() {
+		return
+	}
+	if d.ArrayHeadN("drisl_test.GenT3", 8) != nil {
+		return
+	}
+	if !d.SkipNull() {
+		d.Bool(&v.T)
+	}
+	if !d.SkipNull() {
+		gensupport.Int(d, &v.UI)
+	}
+	if !d.SkipNull() {
+		gensupport.Int(d, &v.I)
+	}
+	if !d.SkipNull() {
+		gensupport.Float(d, &v.F)
+	}
+	if d.SkipNull() {
+		v.B = nil
+	} else {
+		d.Bytes(&v.B)
+	}
+	if !d.SkipNull() {
+		d.String(&v.S)
+	}
+	if d.SkipNull() {
+		v.Slci = nil
+	} else if n3, err := d.ArrayHead("[]int"); err == nil {
+		if n3 == 0 || cap(v.Slci) < n3 {
+			v.Slci = make([]int, n3)
+		} else {
+			v.Slci = v.Slci[:n3]
+		}
+		for i4 := range v.Slci {
+			if !d.SkipNull() {
+				gensupport.Int(d, &v.Slci[i4])
+			}
+		}
+	}
+	if d.SkipNull() {
+		v.Mss = nil
+	} else if n5, err := d.MapHead("map[string]string"); err == nil {
+		if v.Mss == nil {
+			v.Mss = make(map[string]string, n5)
+		}
+		var e7 string
+		for range n5 {
+			var k6 string
+			d.String(&k6)
+			errs8 := d.Errors()
+			if !d.SkipNull() {
+				d.String(&e7)
+			}
+			if d.Errors() == errs8 {
+				v.Mss[k6] = e7
+			}
+		}
+	}
+}
+
+// MarshalCBOR fulfills the drisl.Marshaler interface.
+func (v GenRecord) MarshalCBOR() ([]byte, error) {
+	return v.appendDRISL(nil)
+}
+
+func (v GenRecord) appendDRISL(b []byte) ([]byte, error) {
+	var err error
+	f15, ok15, err15 := gensupport.MarshalField(struct {
+		V GenFacet `cbor:"V,omitzero"`
+	}{v.Main})
+	if err15 != nil {
+		return nil, err15
+	}
+	f18, ok18, err18 := gensupport.MarshalField(struct {
+		V any `cbor:"V,omitempty"`
+	}{v.Extra})
+	if err18 != nil {
+		return nil, err18
+	}
+	f19, ok19, err19 := gensupport.MarshalField(struct {
+		V [2]int `cbor:"V,omitzero"`
+	}{v.Pair})
+	if err19 != nil {
+		return nil, err19
+	}
+	n := 20
+	if !(v.Count != 0) {
+		n--
+	}
+	if !(v.Score != 0) {
+		n--
+	}
+	if !(v.Ratio != 0) {
+		n--
+	}
+	if !(v.Small != 0) {
+		n--
+	}
+	if !(v.Flag) {
+		n--
+	}
+	if !(v.Link.Defined()) {
+		n--
+	}
+	if !(v.Blob != nil) {
+		n--
+	}
+	if !(len(v.Embed) != 0) {
+		n--
+	}
+	if !(len(v.Langs) != 0) {
+		n--
+	}
+	if !(len(v.Labels) != 0) {
+		n--
+	}
+	if !(v.Reply != nil) {
+		n--
+	}
+	if !(ok15) {
+		n--
+	}
+	if !(len(v.Links) != 0) {
+		n--
+	}
+	if !(ok18) {
+		n--
+	}
+	if !(ok19) {
+		n--
+	}
+	b = gensupport.AppendMapHead(b, n)
+	b = gensupport.AppendString(b, "big")
+	b = gensupport.AppendUint(b, uint64(v.Big))
+	b = gensupport.AppendString(b, "opt")
+	if v.Opt == nil {
+		b = gensupport.AppendNull(b)
+	} else {
+		b = gensupport.AppendInt(b, int64((*v.Opt)))
+	}
+	if v.Blob != nil {
+		b = gensupport.AppendString(b, "blob")
+		b = gensupport.AppendBytes(b, v.Blob)
+	}
+	if v.Flag {
+		b = gensupport.AppendString(b, "flag")
+		b = gensupport.AppendBool(b, v.Flag)
+	}
+	if v.Link.Defined() {
+		b = gensupport.AppendString(b, "link")
+		if b, err = gensupport.AppendCid(b, v.Link); err != nil {
+			return nil, err
+		}
+	}
+	if ok15 {
+		b = gensupport.AppendString(b, "main")
+		b = append(b, f15...)
+	}
+	if ok19 {
+		b = gensupport.AppendString(b, "pair")
+		b = append(b, f19...)
+	}
+	b = gensupport.AppendString(b, "text")
+	b = gensupport.AppendString(b, v.Text)
+	b = gensupport.AppendString(b, "$type")
+	b = gensupport.AppendString(b, v.Type)
+	if v.Count != 0 {
+		b = gensupport.AppendString(b, "count")
+		b = gensupport.AppendInt(b, int64(v.Count))
+	}
+	if len(v.Embed) != 0 {
+		b = gensupport.AppendString(b, "embed")
+		if b, err = gensupport.AppendRaw(b, v.Embed); err != nil {
+			return nil, err
+		}
+	}
+	if ok18 {
+		b = gensupport.AppendString(b, "extra")
+		b = append(b, f18...)
+	}
+	if len(v.Langs) != 0 {
+		b = gensupport.AppendString(b, "langs")
+		if v.Langs == nil {
+			b = gensupport.AppendNull(b)
+		} else {
+			b = gensupport.AppendArrayHead(b, len(v.Langs))
+			for _, e1 := range v.Langs {
+				b = gensupport.AppendString(b, e1)
+			}
+		}
+	}
+	if len(v.Links) != 0 {
+		b = gensupport.AppendString(b, "links")
+		if v.Links == nil {
+			b = gensupport.AppendNull(b)
+		} else {
+			b = gensupport.AppendMapHead(b, len(v.Links))
+			for _, k2 := range gensupport.SortedKeys(v.Links) {
+				b = gensupport.AppendString(b, k2)
+				if b, err = gensupport.AppendCid(b, v.Links[k2]); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if v.Ratio != 0 {
+		b = gensupport.AppendString(b, "ratio")
+		if b, err = gensupport.AppendFloat(b, float64(v.Ratio)); err != nil {
+			return nil, err
+		}
+	}
+	if v.Reply != nil {
+		b = gensupport.AppendString(b, "reply")
+		if v.Reply == nil {
+			b = gensupport.AppendNull(b)
+		} else {
+			if b, err = (*v.Reply).appendDRISL(b); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if v.Score != 0 {
+		b = gensupport.AppendString(b, "score")
+		if b, err = gensupport.AppendFloat(b, float64(v.Score)); err != nil {
+			return nil, err
+		}
+	}
+	if v.Small != 0 {
+		b = gensupport.AppendString(b, "small")
+		b = gensupport.AppendInt(b, int64(v.Small))
+	}
+	b = gensupport.AppendString(b, "facets")
+	if v.Facets == nil {
+		b = gensupport.AppendNull(b)
+	} else {
+		b = gensupport.AppendArrayHead(b, len(v.Facets))
+		for _, e3 := range v.Facets {
+			if b, err = e3.appendDRISL(b); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(v.Labels) != 0 {
+		b = gensupport.AppendString(b, "labels")
+		if v.Labels == nil {
+			b = gensupport.AppendNull(b)
+		} else {
+			b = gensupport.AppendMapHead(b, len(v.Labels))
+			for _, k4 := range gensupport.SortedKeys(v.Labels) {
+				b = gensupport.AppendString(b, k4)
+				b = gensupport.AppendInt(b, int64(v.Labels[k4]))
+			}
+		}
+	}
+	return b, nil
+}
+
+// UnmarshalCBOR fulfills the drisl.Unmarshaler interface.
+func (v *GenRecord) UnmarshalCBOR(data []byte) error {
+	d, err := gensupport.NewDecoder(data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+// UnmarshalDRISL fulfills the drisl.ModeUnmarshaler interface.
+func (v *GenRecord) UnmarshalDRISL(dm drisl.DecMode, data []byte) error {
+	d, err := gensupport.NewDecoderMode(dm, data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+var drislFieldsGenRecord = []string{"$type", "text", "count", "score", "ratio", "small", "big", "flag", "link", "blob", "embed", "langs", "labels", "reply", "facets", "main", "links", "opt", "extra", "pair"}
+
+func (v *GenRecord) decodeDRISL(d *gensupport.Decoder) {
+	if d.SkipNull() {
+		return
+	}
+	n, err := d.MapHead("drisl_test.GenRecord")
+	if err != nil {
+		return
+	}
+	var seen [20]bool
+	for i := range n {
+		f, err := d.Field("drisl_test.GenRecord", drislFieldsGenRecord, seen[:])
+		if err != nil {
+			d.SkipPairs(n - i - 1)
+			return
+		}
+		switch f {
+		case 0:
+			if !d.SkipNull() {
+				d.String(&v.Type)
+			}
+		case 1:
+			if !d.SkipNull() {
+				d.String(&v.Text)
+			}
+		case 2:
+			if !d.SkipNull() {
+				gensupport.Int(d, &v.Count)
+			}
+		case 3:
+			if !d.SkipNull() {
+				gensupport.Float(d, &v.Score)
+			}
+		case 4:
+			if !d.SkipNull() {
+				gensupport.Float(d, &v.Ratio)
+			}
+		case 5:
+			if !d.SkipNull() {
+				gensupport.Int(d, &v.Small)
+			}
+		case 6:
+			if !d.SkipNull() {
+				gensupport.Int(d, &v.Big)
+			}
+		case 7:
+			if !d.SkipNull() {
+				d.Bool(&v.Flag)
+			}
+		case 8:
+			d.Cid(&v.Link)
+		case 9:
+			if d.SkipNull() {
+				v.Blob = nil
+			} else {
+				d.Bytes(&v.Blob)
+			}
+		case 10:
+			v.Embed = d.RawCopy()
+		case 11:
+			if d.SkipNull() {
+				v.Langs = nil
+			} else if n5, err := d.ArrayHead("[]string"); err == nil {
+				if n5 == 0 || cap(v.Langs) < n5 {
+					v.Langs = make([]string, n5)
+				} else {
+					v.Langs = v.Langs[:n5]
+				}
+				for i6 := range v.Langs {
+					if !d.SkipNull() {
+						d.String(&v.Langs[i6])
+					}
+				}
+			}
+		case 12:
+			if d.SkipNull() {
+				v.Labels = nil
+			} else if n7, err := d.MapHead("map[string]int64"); err == nil {
+				if v.Labels == nil {
+					v.Labels = make(map[string]int64, n7)
+				}
+				var e9 int64
+				for range n7 {
+					var k8 string
+					d.String(&k8)
+					errs10 := d.Errors()
+					if !d.SkipNull() {
+						gensupport.Int(d, &e9)
+					}
+					if d.Errors() == errs10 {
+						v.Labels[k8] = e9
+					}
+				}
+			}
+		case 13:
+			if d.SkipNull() {
+				v.Reply = nil
+			} else {
+				if v.Reply == nil {
+					v.Reply = new(GenRef)
+				}
+				(*v.Reply).decodeDRISL(d)
+			}
+		case 14:
+			if d.SkipNull() {
+				v.Facets = nil
+			} else if n11, err := d.ArrayHead("[]GenFacet"); err == nil {
+				if n11 == 0 || cap(v.Facets) < n11 {
+					v.Facets = make([]GenFacet, n11)
+				} else {
+					v.Facets = v.Facets[:n11]
+				}
+				for i12 := range v.Facets {
+					v.Facets[i12].decodeDRISL(d)
+				}
+			}
+		case 15:
+			v.Main.decodeDRISL(d)
+		case 16:
+			if d.SkipNull() {
+				v.Links = nil
+			} else if n13, err := d.MapHead("map[string]cid.Cid"); err == nil {
+				if v.Links == nil {
+					v.Links = make(map[string]cid.Cid, n13)
+				}
+				for range n13 {
+					var k14 string
+					d.String(&k14)
+					var e15 cid.Cid
+					errs16 := d.Errors()
+					d.Cid(&e15)
+					if d.Errors() == errs16 {
+						v.Links[k14] = e15
+					}
+				}
+			}
+		case 17:
+			if d.SkipNull() {
+				v.Opt = nil
+			} else {
+				if v.Opt == nil {
+					v.Opt = new(int64)
+				}
+				if !d.SkipNull() {
+					gensupport.Int(d, &(*v.Opt))
+				}
+			}
+		case 18:
+			d.Value(&v.Extra)
+		case 19:
+			d.Value(&v.Pair)
+		}
+	}
+}
+
+// MarshalCBOR fulfills the drisl.Marshaler interface.
+func (v GenRef) MarshalCBOR() ([]byte, error) {
+	return v.appendDRISL(nil)
+}
+
+func (v GenRef) appendDRISL(b []byte) ([]byte, error) {
+	var err error
+	b = gensupport.AppendMapHead(b, 2)
+	b = gensupport.AppendString(b, "root")
+	if b, err = gensupport.AppendCid(b, v.Root); err != nil {
+		return nil, err
+	}
+	b = gensupport.AppendString(b, "parent")
+	if b, err = gensupport.AppendCid(b, v.Parent); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// UnmarshalCBOR fulfills the drisl.Unmarshaler interface.
+func (v *GenRef) UnmarshalCBOR(data []byte) error {
+	d, err := gensupport.NewDecoder(data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+// UnmarshalDRISL fulfills the drisl.ModeUnmarshaler interface.
+func (v *GenRef) UnmarshalDRISL(dm drisl.DecMode, data []byte) error {
+	d, err := gensupport.NewDecoderMode(dm, data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+var drislFieldsGenRef = []string{"root", "parent"}
+
+func (v *GenRef) decodeDRISL(d *gensupport.Decoder) {
+	if d.SkipNull() {
+		return
+	}
+	n, err := d.MapHead("drisl_test.GenRef")
+	if err != nil {
+		return
+	}
+	var seen [2]bool
+	for i := range n {
+		f, err := d.Field("drisl_test.GenRef", drislFieldsGenRef, seen[:])
+		if err != nil {
+			d.SkipPairs(n - i - 1)
+			return
+		}
+		switch f {
+		case 0:
+			d.Cid(&v.Root)
+		case 1:
+			d.Cid(&v.Parent)
+		}
+	}
+}
+
+// MarshalCBOR fulfills the drisl.Marshaler interface.
+func (v GenFacet) MarshalCBOR() ([]byte, error) {
+	return v.appendDRISL(nil)
+}
+
+func (v GenFacet) appendDRISL(b []byte) ([]byte, error) {
+	n := 3
+	if !(len(v.Tags) != 0) {
+		n--
+	}
+	b = gensupport.AppendMapHead(b, n)
+	b = gensupport.AppendString(b, "End")
+	b = gensupport.AppendUint(b, uint64(v.End))
+	if len(v.Tags) != 0 {
+		b = gensupport.AppendString(b, "tags")
+		if v.Tags == nil {
+			b = gensupport.AppendNull(b)
+		} else {
+			b = gensupport.AppendArrayHead(b, len(v.Tags))
+			for _, e1 := range v.Tags {
+				b = gensupport.AppendString(b, e1)
+			}
+		}
+	}
+	b = gensupport.AppendString(b, "Start")
+	b = gensupport.AppendUint(b, uint64(v.Start))
+	return b, nil
+}
+
+// UnmarshalCBOR fulfills the drisl.Unmarshaler interface.
+func (v *GenFacet) UnmarshalCBOR(data []byte) error {
+	d, err := gensupport.NewDecoder(data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+// UnmarshalDRISL fulfills the drisl.ModeUnmarshaler interface.
+func (v *GenFacet) UnmarshalDRISL(dm drisl.DecMode, data []byte) error {
+	d, err := gensupport.NewDecoderMode(dm, data)
+	if err != nil {
+		return err
+	}
+	v.decodeDRISL(d)
+	return d.Err()
+}
+
+var drislFieldsGenFacet = []string{"Start", "End", "tags"}
+
+func (v *GenFacet) decodeDRISL(d *gensupport.Decoder) {
+	if d.SkipNull() {
+		return
+	}
+	n, err := d.MapHead("drisl_test.GenFacet")
+	if err != nil {
+		return
+	}
+	var seen [3]bool
+	for i := range n {
+		f, err := d.Field("drisl_test.GenFacet", drislFieldsGenFacet, seen[:])
+		if err != nil {
+			d.SkipPairs(n - i - 1)
+			return
+		}
+		switch f {
+		case 0:
+			if !d.SkipNull() {
+				gensupport.Int(d, &v.Start)
+			}
+		case 1:
+			if !d.SkipNull() {
+				gensupport.Int(d, &v.End)
+			}
+		case 2:
+			if d.SkipNull() {
+				v.Tags = nil
+			} else if n2, err := d.ArrayHead("[]string"); err == nil {
+				if n2 == 0 || cap(v.Tags) < n2 {
+					v.Tags = make([]string, n2)
+				} else {
+					v.Tags = v.Tags[:n2]
+				}
+				for i3 := range v.Tags {
+					if !d.SkipNull() {
+						d.String(&v.Tags[i3])
+					}
+				}
+			}
+		}
+	}
+}
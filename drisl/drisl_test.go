@@ -3,6 +3,7 @@ package drisl_test
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -101,11 +102,11 @@ func runTests(t *testing.T, tests []*daslTestCase) {
 					t.Errorf("got %x, want %x", b, testData)
 				}
 			})
-			// t.Run(test.Name+"-Valid", func(t *testing.T) {
-			// 	if !drisl.Valid(testData) {
-			// 		t.Errorf("got false, want true")
-			// 	}
-			// })
+			t.Run(test.Name+"-Valid", func(t *testing.T) {
+				if !drisl.Valid(testData) {
+					t.Errorf("got false, want true")
+				}
+			})
 		case "invalid_in":
 			t.Run(test.Name, func(t *testing.T) {
 				var v any
@@ -113,11 +114,11 @@ func runTests(t *testing.T, tests []*daslTestCase) {
 					t.Error("Unmarshal didn't raise an error")
 				}
 			})
-			// t.Run(test.Name+"-Valid", func(t *testing.T) {
-			// 	if drisl.Valid(testData) {
-			// 		t.Errorf("got true, want false")
-			// 	}
-			// })
+			t.Run(test.Name+"-Valid", func(t *testing.T) {
+				if drisl.Valid(testData) {
+					t.Errorf("got true, want false")
+				}
+			})
 		case "invalid_out":
 			t.Run(test.Name, func(t *testing.T) {
 				// Decode data with neutral CBOR decoder, then confirm it cannot be
@@ -131,11 +132,11 @@ func runTests(t *testing.T, tests []*daslTestCase) {
 					t.Error("Marshal didn't raise an error")
 				}
 			})
-			// t.Run(test.Name+"-Valid", func(t *testing.T) {
-			// 	if drisl.Valid(testData) {
-			// 		t.Errorf("got true, want false")
-			// 	}
-			// })
+			t.Run(test.Name+"-Valid", func(t *testing.T) {
+				if drisl.Valid(testData) {
+					t.Errorf("got true, want false")
+				}
+			})
 		default:
 			panic(fmt.Errorf("unknown test type '%s'", test.Type))
 		}
@@ -590,3 +591,28 @@ func TestDecoder(t *testing.T) {
 		t.Errorf("Decode(0x01) = %v, %v", v, err)
 	}
 }
+
+func TestCidForValueWith(t *testing.T) {
+	v := map[string]any{"hello": "world"}
+	sha, err := drisl.CidForValueWith(v, cid.HashTypeSha256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := drisl.CidForValue(v); sha != want {
+		t.Errorf("got %s, want %s", sha, want)
+	}
+
+	b3, err := drisl.CidForValueWith(v, cid.HashTypeBlake3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := drisl.Marshal(v)
+	if b3.Codec() != cid.CodecDrisl || b3.HashType() != cid.HashTypeBlake3 || !b3.VerifyBytes(data) {
+		t.Errorf("got %s", b3)
+	}
+
+	var forbidden *cid.ForbiddenCidError
+	if _, err := drisl.CidForValueWith(v, 0x13); !errors.As(err, &forbidden) {
+		t.Errorf("got %v, want ForbiddenCidError for unsupported hash type", err)
+	}
+}
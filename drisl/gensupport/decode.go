@@ -0,0 +1,497 @@
+package gensupport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+)
+
+// TypeError is returned when a DRISL item can't be decoded into a Go type.
+type TypeError struct {
+	// CBORType is the type of the item, like "map" or "positive integer".
+	CBORType string
+	// GoType is the Go type being decoded into.
+	GoType string
+	// Reason is an optional explanation.
+	Reason string
+}
+
+func (e *TypeError) Error() string {
+	s := "go-dasl/drisl/gensupport: cannot decode CBOR " + e.CBORType + " into Go " + e.GoType
+	if e.Reason != "" {
+		s += ": " + e.Reason
+	}
+	return s
+}
+
+var cborTypeNames = [...]string{
+	"positive integer",
+	"negative integer",
+	"byte string",
+	"text string",
+	"array",
+	"map",
+	"tag",
+	"primitives",
+}
+
+// UnknownFieldError is returned when a map key doesn't match any field of a struct,
+// and the decoding options disallow unknown fields.
+type UnknownFieldError struct {
+	// GoType is the struct type being decoded into.
+	GoType string
+	// Key is the map key.
+	Key string
+}
+
+func (e *UnknownFieldError) Error() string {
+	return "go-dasl/drisl/gensupport: unknown field " + strconv.Quote(e.Key) + " for Go " + e.GoType
+}
+
+// DuplicateFieldError is returned when two map keys match the same field of a struct,
+// like "a" and "A".
+type DuplicateFieldError struct {
+	// GoType is the struct type being decoded into.
+	GoType string
+	// Key is the second map key that matched the field.
+	Key string
+}
+
+func (e *DuplicateFieldError) Error() string {
+	return "go-dasl/drisl/gensupport: duplicate field " + strconv.Quote(e.Key) + " for Go " + e.GoType
+}
+
+var defaultDecMode drisl.DecMode
+
+func init() {
+	var err error
+	defaultDecMode, err = drisl.DecOptions{}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Decoder reads items from valid DRISL data.
+//
+// Like drisl.Unmarshal, decoding continues after an item can't be decoded into
+// its Go type: the item is skipped, and the value is left unchanged. Methods return
+// the error, and the first one is also kept and returned by Err.
+type Decoder struct {
+	data []byte
+	off  int
+	dm   drisl.DecMode
+	// disallowUnknown is set from DecOptions.DisallowUnknownFields
+	disallowUnknown bool
+	// err is the first error, and errs the number of errors
+	err  error
+	errs int
+}
+
+// NewDecoder validates data with the default decoding options,
+// and returns a Decoder for it.
+func NewDecoder(data []byte) (*Decoder, error) {
+	return NewDecoderMode(defaultDecMode, data)
+}
+
+// NewDecoderMode is like NewDecoder, but validates data with the options of dm.
+// They also apply to fields decoded with Value, and to unknown fields.
+func NewDecoderMode(dm drisl.DecMode, data []byte) (*Decoder, error) {
+	if err := drisl.ValidateWith(dm, data); err != nil {
+		return nil, err
+	}
+	return &Decoder{data: data, dm: dm, disallowUnknown: drisl.DecOptionsOf(dm).DisallowUnknownFields}, nil
+}
+
+// readHead reads the head of an item in valid DRISL, returning the argument
+// and the offset after the head.
+func readHead(data []byte, off int) (uint64, int) {
+	ai := data[off] & 0x1f
+	switch ai {
+	case 24:
+		return uint64(data[off+1]), off + 2
+	case 25:
+		return uint64(binary.BigEndian.Uint16(data[off+1:])), off + 3
+	case 26:
+		return uint64(binary.BigEndian.Uint32(data[off+1:])), off + 5
+	case 27:
+		return binary.BigEndian.Uint64(data[off+1:]), off + 9
+	default:
+		return uint64(ai), off + 1
+	}
+}
+
+func (d *Decoder) major() byte {
+	return d.data[d.off] >> 5
+}
+
+func (d *Decoder) typeError(goType string) error {
+	return d.fail(&TypeError{CBORType: cborTypeNames[d.major()], GoType: goType})
+}
+
+// fail records err and returns it.
+func (d *Decoder) fail(err error) error {
+	if d.err == nil {
+		d.err = err
+	}
+	d.errs++
+	return err
+}
+
+// Err returns the first error returned by a method of the Decoder, or nil.
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+// Errors returns the number of errors returned by methods of the Decoder so far.
+// It is used to find out whether decoding a map value failed, in which case the
+// value is not added to the map, like drisl.Unmarshal.
+func (d *Decoder) Errors() int {
+	return d.errs
+}
+
+// SkipNull skips the next item if it is null, and reports whether it did.
+// Undefined is treated as null, as it is only valid when allowed by the options.
+func (d *Decoder) SkipNull() bool {
+	if d.data[d.off] == 0xf6 || d.data[d.off] == 0xf7 {
+		d.off++
+		return true
+	}
+	return false
+}
+
+// Skip skips the next item.
+func (d *Decoder) Skip() {
+	major := d.major()
+	if major == 7 {
+		if d.data[d.off] == 0xfb {
+			d.off += 9
+		} else {
+			d.off++
+		}
+		return
+	}
+	arg, off := readHead(d.data, d.off)
+	d.off = off
+	switch major {
+	case 2, 3:
+		d.off += int(arg)
+	case 4:
+		for range arg {
+			d.Skip()
+		}
+	case 5:
+		for range arg * 2 {
+			d.Skip()
+		}
+	case 6:
+		d.Skip()
+	}
+}
+
+// Raw returns the next item without decoding it. The returned slice is not a copy.
+func (d *Decoder) Raw() []byte {
+	start := d.off
+	d.Skip()
+	return d.data[start:d.off:d.off]
+}
+
+// RawCopy is like Raw but returns a copy.
+func (d *Decoder) RawCopy() []byte {
+	return bytes.Clone(d.Raw())
+}
+
+// Bool decodes a bool into *p.
+func (d *Decoder) Bool(p *bool) error {
+	switch d.data[d.off] {
+	case 0xf4:
+		d.off++
+		*p = false
+		return nil
+	case 0xf5:
+		d.off++
+		*p = true
+		return nil
+	}
+	err := d.typeError("bool")
+	d.Skip()
+	return err
+}
+
+// Integer is the constraint for Int.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// Int decodes an integer into *p, which can be any integer type.
+// An error is returned if it overflows, and *p is left unchanged.
+func Int[T Integer](d *Decoder, p *T) error {
+	major := d.major()
+	if major > 1 {
+		err := d.typeError(fmt.Sprintf("%T", T(0)))
+		d.Skip()
+		return err
+	}
+	arg, off := readHead(d.data, d.off)
+	d.off = off
+
+	var v T
+	if major == 0 {
+		v = T(arg)
+		if v < 0 || uint64(v) != arg {
+			return d.fail(overflow(v, "positive integer", strconv.FormatUint(arg, 10)))
+		}
+		*p = v
+		return nil
+	}
+	if arg > math.MaxInt64 {
+		return d.fail(overflow(v, "negative integer", negString(arg)))
+	}
+	i := -1 - int64(arg)
+	v = T(i)
+	if v >= 0 || int64(v) != i {
+		return d.fail(overflow(v, "negative integer", negString(arg)))
+	}
+	*p = v
+	return nil
+}
+
+func overflow(v any, cborType, n string) error {
+	return &TypeError{
+		CBORType: cborType,
+		GoType:   fmt.Sprintf("%T", v),
+		Reason:   fmt.Sprintf("%s overflows %T", n, v),
+	}
+}
+
+// negString formats the CBOR negative integer -1 - arg.
+func negString(arg uint64) string {
+	return new(big.Int).Sub(big.NewInt(-1), new(big.Int).SetUint64(arg)).String()
+}
+
+// Float decodes a float, or an integer, into *p, which can be any float type.
+// Like drisl.Unmarshal, floats that would lose precision in a float32 are an error,
+// though *p is still set to the rounded value. Floats that overflow it are not.
+func Float[T ~float32 | ~float64](d *Decoder, p *T) error {
+	var f float64
+	switch ib := d.data[d.off]; {
+	case ib == 0xfb:
+		f = math.Float64frombits(binary.BigEndian.Uint64(d.data[d.off+1:]))
+		d.off += 9
+		if float64(T(f)) == f {
+			*p = T(f)
+			return nil
+		}
+		goType := fmt.Sprintf("%T", T(0))
+		if math.Abs(f) > math.MaxFloat32 {
+			return d.fail(&TypeError{CBORType: "primitives", GoType: goType, Reason: "overflows " + goType})
+		}
+		*p = T(f)
+		return d.fail(&TypeError{
+			CBORType: "primitives",
+			GoType:   goType,
+			Reason:   "float64 value would lose precision in float32 type",
+		})
+	case ib>>5 == 0:
+		arg, off := readHead(d.data, d.off)
+		d.off = off
+		f = float64(arg)
+	case ib>>5 == 1:
+		arg, off := readHead(d.data, d.off)
+		d.off = off
+		if arg > math.MaxInt64 {
+			return d.fail(&TypeError{
+				CBORType: "negative integer",
+				GoType:   fmt.Sprintf("%T", T(0)),
+				Reason:   negString(arg) + " overflows Go's int64",
+			})
+		}
+		f = float64(-1 - int64(arg))
+	default:
+		err := d.typeError(fmt.Sprintf("%T", T(0)))
+		d.Skip()
+		return err
+	}
+	*p = T(f)
+	return nil
+}
+
+// str returns the content of the next byte or text string.
+func (d *Decoder) str(major byte, goType string) ([]byte, error) {
+	if d.major() != major {
+		err := d.typeError(goType)
+		d.Skip()
+		return nil, err
+	}
+	n, off := readHead(d.data, d.off)
+	d.off = off + int(n)
+	return d.data[off:d.off], nil
+}
+
+// String decodes a text string into *p.
+func (d *Decoder) String(p *string) error {
+	b, err := d.str(3, "string")
+	if err != nil {
+		return err
+	}
+	*p = string(b)
+	return nil
+}
+
+// Bytes decodes a byte string into a new slice, and sets *p to it.
+//
+// Like drisl.Unmarshal, the content of a CID is decoded as a byte string,
+// and an array is decoded element by element, reusing *p if it is big enough.
+func (d *Decoder) Bytes(p *[]byte) error {
+	switch d.major() {
+	case 6:
+		// Tag 42, whose content is a byte string
+		_, d.off = readHead(d.data, d.off)
+	case 4:
+		n, off := readHead(d.data, d.off)
+		d.off = off
+		if n == 0 || *p == nil || uint64(cap(*p)) < n {
+			*p = make([]byte, n)
+		} else {
+			*p = (*p)[:n]
+		}
+		for i := range *p {
+			if !d.SkipNull() {
+				Int(d, &(*p)[i])
+			}
+		}
+		return nil
+	}
+	b, err := d.str(2, "[]uint8")
+	if err != nil {
+		return err
+	}
+	*p = bytes.Clone(b)
+	return nil
+}
+
+// Cid decodes a CID into *p.
+func (d *Decoder) Cid(p *cid.Cid) error {
+	if d.major() != 6 {
+		err := d.typeError("cid.Cid")
+		d.Skip()
+		return err
+	}
+	// Validated data means this is tag 42 with a byte string with the 0x00 prefix
+	_, off := readHead(d.data, d.off)
+	n, off := readHead(d.data, off)
+	d.off = off + int(n)
+	c, err := cid.NewCidFromBytes(d.data[off+1 : d.off])
+	if err != nil {
+		return d.fail(err)
+	}
+	*p = c
+	return nil
+}
+
+// ArrayHead decodes the head of an array and returns the number of elements.
+func (d *Decoder) ArrayHead(goType string) (int, error) {
+	if d.major() != 4 {
+		err := d.typeError(goType)
+		d.Skip()
+		return 0, err
+	}
+	n, off := readHead(d.data, d.off)
+	d.off = off
+	return int(n), nil
+}
+
+// ArrayHeadN decodes the head of an array, returning an error if it doesn't have
+// exactly n elements. It is used for structs with the toarray option.
+func (d *Decoder) ArrayHeadN(goType string, n int) error {
+	start := d.off
+	got, err := d.ArrayHead(goType)
+	if err != nil {
+		return err
+	}
+	if got != n {
+		d.off = start
+		d.Skip()
+		return d.fail(&TypeError{
+			CBORType: "array",
+			GoType:   goType,
+			Reason:   "cannot decode CBOR array to struct with different number of elements",
+		})
+	}
+	return nil
+}
+
+// MapHead decodes the head of a map and returns the number of pairs.
+func (d *Decoder) MapHead(goType string) (int, error) {
+	if d.major() != 5 {
+		err := d.typeError(goType)
+		d.Skip()
+		return 0, err
+	}
+	n, off := readHead(d.data, d.off)
+	d.off = off
+	return int(n), nil
+}
+
+// Field decodes a map key of the struct goType, and returns the index of the
+// matching name in names. Like drisl.Unmarshal, an exact match is preferred, and
+// otherwise the first case-insensitive match is used.
+//
+// The value of a key that doesn't match any name is skipped, and -1 is returned.
+// An UnknownFieldError is returned instead if the options disallow unknown fields.
+//
+// Matched fields are marked in seen, which has the same length as names. If a field
+// was already seen, the value is skipped and a DuplicateFieldError is returned.
+// After an error, the rest of the map should be skipped with SkipPairs.
+func (d *Decoder) Field(goType string, names []string, seen []bool) (int, error) {
+	// Keys are always text strings in valid DRISL
+	n, off := readHead(d.data, d.off)
+	d.off = off + int(n)
+	key := d.data[off:d.off]
+	i := slices.Index(names, string(key))
+	if i < 0 {
+		for j, name := range names {
+			if len(name) == len(key) && strings.EqualFold(string(key), name) {
+				i = j
+				break
+			}
+		}
+	}
+	switch {
+	case i < 0 && d.disallowUnknown:
+		d.Skip()
+		return -1, d.fail(&UnknownFieldError{GoType: goType, Key: string(key)})
+	case i < 0:
+		d.Skip()
+		return -1, nil
+	case seen[i]:
+		d.Skip()
+		return -1, d.fail(&DuplicateFieldError{GoType: goType, Key: string(key)})
+	}
+	seen[i] = true
+	return i, nil
+}
+
+// SkipPairs skips n map keys and their values.
+func (d *Decoder) SkipPairs(n int) {
+	for range n * 2 {
+		d.Skip()
+	}
+}
+
+// Value decodes the next item into v using the Unmarshal method of the DecMode
+// the Decoder was created with. It is used for types the generator does not know
+// how to decode.
+func (d *Decoder) Value(v any) error {
+	if err := d.dm.Unmarshal(d.Raw(), v); err != nil {
+		return d.fail(err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+// Package gensupport contains the helpers used by code generated with cmd/drislgen.
+//
+// It is not meant to be used directly, and its API may change along with the generator.
+package gensupport
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+)
+
+var (
+	// ErrNaN is returned when encoding a NaN float.
+	ErrNaN = errors.New("go-dasl/drisl/gensupport: NaN is not allowed")
+	// ErrInf is returned when encoding an infinite float.
+	ErrInf = errors.New("go-dasl/drisl/gensupport: infinity is not allowed")
+)
+
+// marshalerDecMode validates the output of MarshalCBOR methods, with the same
+// limits the CBOR library uses.
+var marshalerDecMode drisl.DecMode
+
+func init() {
+	var err error
+	marshalerDecMode, err = drisl.DecOptions{
+		MaxNestedLevels:  math.MaxUint16,
+		MaxArrayElements: math.MaxInt32,
+		MaxMapPairs:      math.MaxInt32,
+	}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+}
+
+// appendHead appends the head of an item in its shortest form.
+func appendHead(b []byte, major byte, arg uint64) []byte {
+	mt := major << 5
+	switch {
+	case arg < 24:
+		return append(b, mt|byte(arg))
+	case arg <= math.MaxUint8:
+		return append(b, mt|24, byte(arg))
+	case arg <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, mt|25), uint16(arg))
+	case arg <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, mt|26), uint32(arg))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, mt|27), arg)
+	}
+}
+
+// AppendNull appends null.
+func AppendNull(b []byte) []byte {
+	return append(b, 0xf6)
+}
+
+// AppendBool appends a bool.
+func AppendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xf5)
+	}
+	return append(b, 0xf4)
+}
+
+// AppendInt appends a signed integer.
+func AppendInt(b []byte, i int64) []byte {
+	if i < 0 {
+		return appendHead(b, 1, uint64(-1-i))
+	}
+	return appendHead(b, 0, uint64(i))
+}
+
+// AppendUint appends an unsigned integer.
+func AppendUint(b []byte, u uint64) []byte {
+	return appendHead(b, 0, u)
+}
+
+// AppendFloat appends a float as 64 bits, which is the only width allowed in DRISL.
+func AppendFloat(b []byte, f float64) ([]byte, error) {
+	if math.IsNaN(f) {
+		return nil, ErrNaN
+	}
+	if math.IsInf(f, 0) {
+		return nil, ErrInf
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xfb), math.Float64bits(f)), nil
+}
+
+// AppendString appends a text string.
+func AppendString(b []byte, s string) []byte {
+	b = appendHead(b, 3, uint64(len(s)))
+	return append(b, s...)
+}
+
+// AppendBytes appends a byte string, or null if v is nil.
+func AppendBytes(b []byte, v []byte) []byte {
+	if v == nil {
+		return AppendNull(b)
+	}
+	b = appendHead(b, 2, uint64(len(v)))
+	return append(b, v...)
+}
+
+// AppendArrayHead appends the head of an array with n elements.
+func AppendArrayHead(b []byte, n int) []byte {
+	return appendHead(b, 4, uint64(n))
+}
+
+// AppendMapHead appends the head of a map with n pairs.
+func AppendMapHead(b []byte, n int) []byte {
+	return appendHead(b, 5, uint64(n))
+}
+
+// AppendCid appends a CID. Like cid.Cid.MarshalCBOR, undefined CIDs are an error.
+func AppendCid(b []byte, c cid.Cid) ([]byte, error) {
+	if !c.Defined() {
+		return nil, cid.ErrUndefinedCid
+	}
+	b = append(b, 0xd8, drisl.CidTagNumber, 0x58, 0x25, 0x00)
+	return append(b, c.Bytes()...), nil
+}
+
+// AppendRaw appends an already encoded item after validating it, or null if it is empty.
+func AppendRaw(b []byte, raw []byte) ([]byte, error) {
+	if len(raw) == 0 {
+		return AppendNull(b), nil
+	}
+	if err := drisl.ValidateWith(marshalerDecMode, raw); err != nil {
+		return nil, err
+	}
+	return append(b, raw...), nil
+}
+
+// AppendValue appends any value using drisl.Marshal.
+// It is used for types the generator does not know how to encode.
+func AppendValue(b []byte, v any) ([]byte, error) {
+	data, err := drisl.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, data...), nil
+}
+
+// MarshalField encodes a struct with a single field using drisl.Marshal, returning the
+// encoded field value, or false if the field was omitted. It is used for fields with
+// omitempty or omitzero whose types the generator does not know, so that the rules for
+// omitting them are exactly the same as drisl.Marshal.
+func MarshalField(v any) ([]byte, bool, error) {
+	data, err := drisl.Marshal(v)
+	if err != nil {
+		return nil, false, err
+	}
+	if data[0] == 0xa0 {
+		return nil, false, nil
+	}
+	// Skip the map head and the key
+	keyLen, off := readHead(data, 1)
+	return data[off+int(keyLen):], true, nil
+}
+
+// SortedKeys returns the keys of a map in DRISL order: shorter keys first, then bytewise.
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		if len(a) != len(b) {
+			return len(a) - len(b)
+		}
+		return strings.Compare(a, b)
+	})
+	return keys
+}
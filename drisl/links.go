@@ -0,0 +1,102 @@
+package drisl
+
+import (
+	"errors"
+	"iter"
+	"slices"
+
+	"github.com/hyphacoop/go-dasl/cid"
+)
+
+// errStopLinks is returned by the validator when its onLink callback stops it early.
+var errStopLinks = errors.New("go-dasl/drisl: stopped scanning for links")
+
+// Links returns the CIDs linked to by data, in the order they appear,
+// using the default decoding options.
+//
+// The data is scanned without decoding it into Go values, and it is validated at the
+// same time: an error is returned if data is not valid DRISL, like with Validate.
+// If no CIDs are found, the slice is nil.
+func Links(data []byte) ([]cid.Cid, error) {
+	return LinksWith(drislDecMode, data)
+}
+
+// LinksSeq is the iterator form of Links. Iteration stops after the first error.
+//
+// CIDs are yielded as they are found, so CIDs may be yielded before an error
+// is found later in the data.
+func LinksSeq(data []byte) iter.Seq2[cid.Cid, error] {
+	return LinksSeqWith(drislDecMode, data)
+}
+
+// scanLinks validates data, calling fn with the bytes of each CID found.
+// It returns early without an error if fn returns false.
+func (dm *decMode) scanLinks(data []byte, fn func(b []byte) bool) error {
+	v := newValidator(dm.opts)
+	v.onLink = fn
+	if err := v.validate(data); err != nil && err != errStopLinks {
+		return err
+	}
+	return nil
+}
+
+// LinksWith is like Links, but honours the limits and other options of dm.
+// If dm was not created by DecOptions.DecMode, the default decoding options are used.
+// If UseRawCid is enabled, an error is returned for non-DASL CIDs; use RawLinksWith instead.
+func LinksWith(dm DecMode, data []byte) ([]cid.Cid, error) {
+	var links []cid.Cid
+	for c, err := range LinksSeqWith(dm, data) {
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, c)
+	}
+	return links, nil
+}
+
+// LinksSeqWith is the iterator form of LinksWith.
+func LinksSeqWith(dm DecMode, data []byte) iter.Seq2[cid.Cid, error] {
+	m := modeOf(dm)
+	return func(yield func(cid.Cid, error) bool) {
+		var cidErr error
+		err := m.scanLinks(data, func(b []byte) bool {
+			c, err := cid.NewCidFromBytes(b)
+			if err != nil {
+				cidErr = err
+				return false
+			}
+			return yield(c, nil)
+		})
+		if cidErr != nil {
+			yield(cid.Cid{}, cidErr)
+		} else if err != nil {
+			yield(cid.Cid{}, err)
+		}
+	}
+}
+
+// RawLinksWith is like LinksWith, but returns the CIDs as RawCids.
+// If UseRawCid is enabled, this includes non-DASL CIDs.
+func RawLinksWith(dm DecMode, data []byte) ([]cid.RawCid, error) {
+	var links []cid.RawCid
+	for c, err := range RawLinksSeqWith(dm, data) {
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, c)
+	}
+	return links, nil
+}
+
+// RawLinksSeqWith is the iterator form of RawLinksWith.
+func RawLinksSeqWith(dm DecMode, data []byte) iter.Seq2[cid.RawCid, error] {
+	m := modeOf(dm)
+	return func(yield func(cid.RawCid, error) bool) {
+		err := m.scanLinks(data, func(b []byte) bool {
+			return yield(slices.Clone(b), nil)
+		})
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
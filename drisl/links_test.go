@@ -0,0 +1,107 @@
+package drisl_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+)
+
+// nonDaslCid is a CID-in-CBOR for a dag-pb CIDv0, which is not a DASL CID.
+const nonDaslCid = "d82a582300122022ad631c69ee983095b5b8acd029ff94aff1dc6c48837878589a92b90dfea317"
+
+func TestLinks(t *testing.T) {
+	a := cid.HashBytes([]byte("a"))
+	b := cid.HashBytes([]byte("b"))
+	c := cid.HashBytes([]byte("c"))
+	data, err := drisl.Marshal(map[string]any{
+		"z":    a,
+		"list": []any{b, map[string]any{"x": c}, 1},
+		"yy":   a,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	links, err := drisl.Links(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Document order, with keys sorted length-first
+	want := []cid.Cid{a, a, b, c}
+	if len(links) != len(want) {
+		t.Fatalf("got %d links, want %d", len(links), len(want))
+	}
+	for i := range want {
+		if !links[i].Equal(want[i]) {
+			t.Errorf("link %d: got %v, want %v", i, links[i], want[i])
+		}
+	}
+
+	// Stopping early
+	n := 0
+	for _, err := range drisl.LinksSeq(data) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+		if n == 2 {
+			break
+		}
+	}
+}
+
+func TestLinksNone(t *testing.T) {
+	links, err := drisl.Links(hexDecode("a3616101616202626161f5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if links != nil {
+		t.Fatalf("got %v, want nil", links)
+	}
+}
+
+func TestLinksInvalid(t *testing.T) {
+	for _, tt := range invalidTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := drisl.Links(hexDecode(tt.in)); err == nil {
+				t.Fatalf("Links(%s) succeeded, want error", tt.in)
+			}
+		})
+	}
+
+	// CIDs before the error are still yielded
+	link := "d82a582500015512205891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	var n int
+	var gotErr error
+	for _, err := range drisl.LinksSeq(hexDecode("83" + link + "1801")) {
+		if err != nil {
+			gotErr = err
+			break
+		}
+		n++
+	}
+	if n != 1 || gotErr == nil {
+		t.Fatalf("got %d links and error %v, want 1 link and an error", n, gotErr)
+	}
+}
+
+func TestRawLinks(t *testing.T) {
+	data := hexDecode("82" + nonDaslCid + nonDaslCid)
+	if _, err := drisl.Links(data); err == nil {
+		t.Fatal("Links succeeded for non-DASL CID")
+	}
+
+	dm, _ := drisl.DecOptions{UseRawCid: true}.DecMode()
+	links, err := drisl.RawLinksWith(dm, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := hexDecode(nonDaslCid)[5:]
+	if len(links) != 2 || !bytes.Equal(links[0], want) || !bytes.Equal(links[1], want) {
+		t.Fatalf("got %x, want 2 links of %x", links, want)
+	}
+	if _, err := drisl.LinksWith(dm, data); err == nil {
+		t.Fatal("Links succeeded for non-DASL CID")
+	}
+}
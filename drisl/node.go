@@ -0,0 +1,586 @@
+package drisl
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hyphacoop/go-dasl/cid"
+)
+
+// Kind is the kind of value stored in a Node, following the DRISL data model.
+type Kind uint8
+
+const (
+	KindNull Kind = iota
+	KindBool
+	KindInt
+	KindFloat
+	KindString
+	KindBytes
+	KindList
+	KindMap
+	KindLink
+)
+
+var kindNames = [...]string{
+	KindNull:   "null",
+	KindBool:   "bool",
+	KindInt:    "int",
+	KindFloat:  "float",
+	KindString: "string",
+	KindBytes:  "bytes",
+	KindList:   "list",
+	KindMap:    "map",
+	KindLink:   "link",
+}
+
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Node is a generic DRISL value, for working with data without knowing its schema.
+//
+// It can be decoded from and encoded to DRISL like any other Go type, and it does so
+// without losing information: integers keep their exact value and sign across the whole
+// CBOR range, and map entries keep their order. Decoded maps are always in DRISL order,
+// and maps created with NewMap are sorted when they are encoded.
+//
+// The zero value is a null Node. Nodes are created with the New* functions, and their
+// values are retrieved with the As* methods, which return false if the Node is not
+// of that kind.
+type Node struct {
+	kind Kind
+	// neg is true for negative integers, which have the value -1 - num,
+	// like in CBOR.
+	neg bool
+	// num stores bools, integer magnitudes, and float bits.
+	num uint64
+	// str stores strings and bytes.
+	str     string
+	list    []Node
+	entries []MapEntry
+	link    cid.Cid
+}
+
+// MapEntry is a single key-value pair in a map Node.
+type MapEntry struct {
+	Key   string
+	Value Node
+}
+
+// NewNull returns a null Node. It is the same as the zero value.
+func NewNull() Node {
+	return Node{}
+}
+
+// NewBool returns a bool Node.
+func NewBool(b bool) Node {
+	n := Node{kind: KindBool}
+	if b {
+		n.num = 1
+	}
+	return n
+}
+
+// NewInt returns an int Node.
+func NewInt(i int64) Node {
+	if i < 0 {
+		return Node{kind: KindInt, neg: true, num: uint64(-1 - i)}
+	}
+	return Node{kind: KindInt, num: uint64(i)}
+}
+
+// NewUint returns an int Node for an unsigned integer.
+func NewUint(u uint64) Node {
+	return Node{kind: KindInt, num: u}
+}
+
+// NewBigInt returns an int Node for a big.Int.
+// An error is returned if the integer is outside of the CBOR range: [-(2^64), 2^64-1].
+func NewBigInt(i *big.Int) (Node, error) {
+	if i.Sign() >= 0 {
+		if !i.IsUint64() {
+			return Node{}, errors.New("go-dasl/drisl: integer out of range")
+		}
+		return NewUint(i.Uint64()), nil
+	}
+	// -1 - i
+	mag := new(big.Int).Neg(i)
+	mag.Sub(mag, big.NewInt(1))
+	if !mag.IsUint64() {
+		return Node{}, errors.New("go-dasl/drisl: integer out of range")
+	}
+	return Node{kind: KindInt, neg: true, num: mag.Uint64()}, nil
+}
+
+// NewFloat returns a float Node.
+func NewFloat(f float64) Node {
+	return Node{kind: KindFloat, num: math.Float64bits(f)}
+}
+
+// NewString returns a string Node.
+func NewString(s string) Node {
+	return Node{kind: KindString, str: s}
+}
+
+// NewBytes returns a bytes Node. The bytes are copied.
+func NewBytes(b []byte) Node {
+	return Node{kind: KindBytes, str: string(b)}
+}
+
+// NewList returns a list Node containing the provided Nodes.
+func NewList(elems ...Node) Node {
+	if elems == nil {
+		elems = []Node{}
+	}
+	return Node{kind: KindList, list: elems}
+}
+
+// NewMap returns a map Node containing the provided entries.
+// Entries don't need to be sorted, but keys must be unique or encoding will fail.
+func NewMap(entries ...MapEntry) Node {
+	if entries == nil {
+		entries = []MapEntry{}
+	}
+	return Node{kind: KindMap, entries: entries}
+}
+
+// NewLink returns a link Node for the CID.
+func NewLink(c cid.Cid) Node {
+	return Node{kind: KindLink, link: c}
+}
+
+// Kind returns the kind of the Node.
+func (n Node) Kind() Kind {
+	return n.kind
+}
+
+// IsNull returns true if the Node is null.
+func (n Node) IsNull() bool {
+	return n.kind == KindNull
+}
+
+// AsBool returns the bool value of the Node.
+func (n Node) AsBool() (bool, bool) {
+	if n.kind != KindBool {
+		return false, false
+	}
+	return n.num == 1, true
+}
+
+// AsInt returns the value of an int Node, if it fits in an int64.
+func (n Node) AsInt() (int64, bool) {
+	if n.kind != KindInt || n.num > math.MaxInt64 {
+		return 0, false
+	}
+	if n.neg {
+		return -1 - int64(n.num), true
+	}
+	return int64(n.num), true
+}
+
+// AsUint returns the value of an int Node, if it is not negative.
+func (n Node) AsUint() (uint64, bool) {
+	if n.kind != KindInt || n.neg {
+		return 0, false
+	}
+	return n.num, true
+}
+
+// AsBigInt returns the value of an int Node as a big.Int.
+// Unlike AsInt and AsUint, this works for every int Node.
+func (n Node) AsBigInt() (*big.Int, bool) {
+	if n.kind != KindInt {
+		return nil, false
+	}
+	i := new(big.Int).SetUint64(n.num)
+	if n.neg {
+		// -1 - num
+		i.Neg(i).Sub(i, big.NewInt(1))
+	}
+	return i, true
+}
+
+// AsFloat returns the value of a float Node.
+func (n Node) AsFloat() (float64, bool) {
+	if n.kind != KindFloat {
+		return 0, false
+	}
+	return math.Float64frombits(n.num), true
+}
+
+// AsString returns the value of a string Node.
+func (n Node) AsString() (string, bool) {
+	if n.kind != KindString {
+		return "", false
+	}
+	return n.str, true
+}
+
+// AsBytes returns the value of a bytes Node. It is safe to modify.
+func (n Node) AsBytes() ([]byte, bool) {
+	if n.kind != KindBytes {
+		return nil, false
+	}
+	return []byte(n.str), true
+}
+
+// AsLink returns the CID of a link Node.
+func (n Node) AsLink() (cid.Cid, bool) {
+	if n.kind != KindLink {
+		return cid.Cid{}, false
+	}
+	return n.link, true
+}
+
+// AsList returns the elements of a list Node.
+// The slice is not copied, so modifying it modifies the Node.
+func (n Node) AsList() ([]Node, bool) {
+	if n.kind != KindList {
+		return nil, false
+	}
+	return n.list, true
+}
+
+// AsMap returns the entries of a map Node.
+// The slice is not copied, so modifying it modifies the Node.
+func (n Node) AsMap() ([]MapEntry, bool) {
+	if n.kind != KindMap {
+		return nil, false
+	}
+	return n.entries, true
+}
+
+// Len returns the number of elements in a list Node, or entries in a map Node.
+// It returns 0 for all other kinds.
+func (n Node) Len() int {
+	switch n.kind {
+	case KindList:
+		return len(n.list)
+	case KindMap:
+		return len(n.entries)
+	default:
+		return 0
+	}
+}
+
+// Get returns the value for the key in a map Node.
+func (n Node) Get(key string) (Node, bool) {
+	if n.kind != KindMap {
+		return Node{}, false
+	}
+	for _, e := range n.entries {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return Node{}, false
+}
+
+// Index returns the element at index i in a list Node.
+func (n Node) Index(i int) (Node, bool) {
+	if n.kind != KindList || i < 0 || i >= len(n.list) {
+		return Node{}, false
+	}
+	return n.list[i], true
+}
+
+// Lookup finds a Node by following a slash-separated path of map keys and list indexes.
+// For example: "embed/images/0/image". Leading and trailing slashes are ignored, and
+// an empty path returns the Node itself.
+//
+// Map keys that contain a slash can't be looked up with this method, use LookupPath.
+func (n Node) Lookup(path string) (Node, bool) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return n, true
+	}
+	return n.LookupPath(strings.Split(path, "/")...)
+}
+
+// LookupPath finds a Node by following the provided map keys and list indexes.
+// List indexes are decimal strings.
+func (n Node) LookupPath(segments ...string) (Node, bool) {
+	cur := n
+	for _, seg := range segments {
+		switch cur.kind {
+		case KindMap:
+			next, ok := cur.Get(seg)
+			if !ok {
+				return Node{}, false
+			}
+			cur = next
+		case KindList:
+			i, err := strconv.Atoi(seg)
+			if err != nil {
+				return Node{}, false
+			}
+			next, ok := cur.Index(i)
+			if !ok {
+				return Node{}, false
+			}
+			cur = next
+		default:
+			return Node{}, false
+		}
+	}
+	return cur, true
+}
+
+// Set sets the value for the key in a map Node, replacing any existing value.
+// New keys are added to the end of the map.
+// It panics if the Node is not a map.
+func (n *Node) Set(key string, v Node) {
+	if n.kind != KindMap {
+		panic("go-dasl/drisl: Set called on " + n.kind.String() + " Node")
+	}
+	for i := range n.entries {
+		if n.entries[i].Key == key {
+			n.entries[i].Value = v
+			return
+		}
+	}
+	n.entries = append(n.entries, MapEntry{key, v})
+}
+
+// Delete removes the key from a map Node, if it exists.
+// It panics if the Node is not a map.
+func (n *Node) Delete(key string) {
+	if n.kind != KindMap {
+		panic("go-dasl/drisl: Delete called on " + n.kind.String() + " Node")
+	}
+	n.entries = slices.DeleteFunc(n.entries, func(e MapEntry) bool {
+		return e.Key == key
+	})
+}
+
+// Equal returns true if the two Nodes have the same DRISL encoding.
+func (n Node) Equal(o Node) bool {
+	if n.kind != o.kind {
+		return false
+	}
+	switch n.kind {
+	case KindNull:
+		return true
+	case KindBool, KindFloat:
+		return n.num == o.num
+	case KindInt:
+		return n.neg == o.neg && n.num == o.num
+	case KindString, KindBytes:
+		return n.str == o.str
+	case KindLink:
+		return n.link.Equal(o.link)
+	case KindList:
+		return slices.EqualFunc(n.list, o.list, Node.Equal)
+	case KindMap:
+		if len(n.entries) != len(o.entries) {
+			return false
+		}
+		// Order doesn't matter, because it is always sorted when encoding
+		for _, e := range n.entries {
+			v, ok := o.Get(e.Key)
+			if !ok || !e.Value.Equal(v) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// MarshalCBOR fulfills the drisl.Marshaler interface.
+func (n Node) MarshalCBOR() ([]byte, error) {
+	return n.appendCBOR(nil)
+}
+
+func (n Node) appendCBOR(b []byte) ([]byte, error) {
+	switch n.kind {
+	case KindNull:
+		return append(b, 0xf6), nil
+	case KindBool:
+		if n.num == 1 {
+			return append(b, 0xf5), nil
+		}
+		return append(b, 0xf4), nil
+	case KindInt:
+		if n.neg {
+			return appendHead(b, 1, n.num), nil
+		}
+		return appendHead(b, 0, n.num), nil
+	case KindFloat:
+		f := math.Float64frombits(n.num)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, errors.New("go-dasl/drisl: NaN and infinity are not allowed")
+		}
+		b = append(b, 0xfb)
+		return binary.BigEndian.AppendUint64(b, n.num), nil
+	case KindString:
+		b = appendHead(b, 3, uint64(len(n.str)))
+		return append(b, n.str...), nil
+	case KindBytes:
+		b = appendHead(b, 2, uint64(len(n.str)))
+		return append(b, n.str...), nil
+	case KindLink:
+		link, err := n.link.MarshalCBOR()
+		if err != nil {
+			return nil, err
+		}
+		return append(b, link...), nil
+	case KindList:
+		b = appendHead(b, 4, uint64(len(n.list)))
+		for _, elem := range n.list {
+			var err error
+			b, err = elem.appendCBOR(b)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case KindMap:
+		entries := n.entries
+		if !slices.IsSortedFunc(entries, compareEntries) {
+			entries = slices.Clone(entries)
+			slices.SortFunc(entries, compareEntries)
+		}
+		b = appendHead(b, 5, uint64(len(entries)))
+		for i, e := range entries {
+			if i > 0 && entries[i-1].Key == e.Key {
+				return nil, fmt.Errorf("go-dasl/drisl: duplicate map key %q", e.Key)
+			}
+			b = appendHead(b, 3, uint64(len(e.Key)))
+			b = append(b, e.Key...)
+			var err error
+			b, err = e.Value.appendCBOR(b)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("go-dasl/drisl: invalid Node kind %s", n.kind)
+	}
+}
+
+// compareEntries sorts map entries in DRISL order: shorter keys first, then bytewise.
+func compareEntries(a, b MapEntry) int {
+	if len(a.Key) != len(b.Key) {
+		return len(a.Key) - len(b.Key)
+	}
+	return strings.Compare(a.Key, b.Key)
+}
+
+// UnmarshalCBOR fulfills the drisl.Unmarshaler interface.
+//
+// If it is called directly, the data is validated first.
+// The undefined simple value is decoded as null, like in Unmarshal.
+func (n *Node) UnmarshalCBOR(b []byte) error {
+	v := newValidator(DecOptions{
+		// Leave limits up to the caller's DecMode
+		MaxNestedLevels:  math.MaxUint16,
+		MaxArrayElements: math.MaxInt32,
+		MaxMapPairs:      math.MaxInt32,
+		AllowUndefined:   true,
+	})
+	if err := v.validate(b); err != nil {
+		return err
+	}
+	*n, _ = decodeNode(b, 0)
+	return nil
+}
+
+// decodeNode decodes a Node starting at the offset in valid DRISL data.
+// It returns the Node and the offset after it.
+func decodeNode(data []byte, off int) (Node, int) {
+	ib := data[off]
+	major, arg, off := readHead(data, off)
+	switch major {
+	case 0:
+		return Node{kind: KindInt, num: arg}, off
+	case 1:
+		return Node{kind: KindInt, neg: true, num: arg}, off
+	case 2:
+		return Node{kind: KindBytes, str: string(data[off : off+int(arg)])}, off + int(arg)
+	case 3:
+		return Node{kind: KindString, str: string(data[off : off+int(arg)])}, off + int(arg)
+	case 4:
+		list := make([]Node, arg)
+		for i := range list {
+			list[i], off = decodeNode(data, off)
+		}
+		return Node{kind: KindList, list: list}, off
+	case 5:
+		entries := make([]MapEntry, arg)
+		for i := range entries {
+			var key Node
+			key, off = decodeNode(data, off)
+			entries[i].Key = key.str
+			entries[i].Value, off = decodeNode(data, off)
+		}
+		return Node{kind: KindMap, entries: entries}, off
+	case 6:
+		// Tag 42, skip byte string head and 0x00 prefix
+		_, arg, off = readHead(data, off)
+		c, _ := cid.NewCidFromBytes(data[off+1 : off+int(arg)])
+		return Node{kind: KindLink, link: c}, off + int(arg)
+	default:
+		switch ib {
+		case 0xf4:
+			return NewBool(false), off
+		case 0xf5:
+			return NewBool(true), off
+		case 0xfb:
+			return Node{kind: KindFloat, num: arg}, off
+		default:
+			// null and undefined
+			return Node{}, off
+		}
+	}
+}
+
+// readHead reads the head of an item in well-formed CBOR, returning the major type,
+// the argument, and the offset after the head.
+// For major type 7 the argument is the raw value following the initial byte, if any.
+func readHead(data []byte, off int) (byte, uint64, int) {
+	ib := data[off]
+	major, n := cborHeadLen(ib)
+	var arg uint64
+	switch n {
+	case 1:
+		arg = uint64(ib & 0x1f)
+	case 2:
+		arg = uint64(data[off+1])
+	case 3:
+		arg = uint64(binary.BigEndian.Uint16(data[off+1:]))
+	case 5:
+		arg = uint64(binary.BigEndian.Uint32(data[off+1:]))
+	case 9:
+		arg = binary.BigEndian.Uint64(data[off+1:])
+	}
+	return major, arg, off + n
+}
+
+// appendHead appends the head of an item in its shortest form.
+func appendHead(b []byte, major byte, arg uint64) []byte {
+	mt := major << 5
+	switch {
+	case arg < 24:
+		return append(b, mt|byte(arg))
+	case arg <= math.MaxUint8:
+		return append(b, mt|24, byte(arg))
+	case arg <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, mt|25), uint16(arg))
+	case arg <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, mt|26), uint32(arg))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, mt|27), arg)
+	}
+}
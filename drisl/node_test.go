@@ -0,0 +1,188 @@
+package drisl_test
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+)
+
+func TestNodeRoundtrip(t *testing.T) {
+	for _, tt := range validTests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := hexDecode(tt.in)
+			var n drisl.Node
+			if err := drisl.Unmarshal(data, &n); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			got, err := drisl.Marshal(n)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("got %x, want %x", got, data)
+			}
+		})
+	}
+}
+
+func TestNodeInvalid(t *testing.T) {
+	for _, tt := range invalidTests {
+		if tt.name == "undefined" {
+			// Decoded as null
+			continue
+		}
+		t.Run(tt.name, func(t *testing.T) {
+			var n drisl.Node
+			if err := n.UnmarshalCBOR(hexDecode(tt.in)); err == nil {
+				t.Fatalf("UnmarshalCBOR(%s) succeeded, want error", tt.in)
+			}
+		})
+	}
+}
+
+func TestNodeInts(t *testing.T) {
+	// min negative int, -(2^64)
+	var n drisl.Node
+	if err := drisl.Unmarshal(hexDecode("3bffffffffffffffff"), &n); err != nil {
+		t.Fatal(err)
+	}
+	if n.Kind() != drisl.KindInt {
+		t.Fatalf("got kind %v, want int", n.Kind())
+	}
+	if _, ok := n.AsInt(); ok {
+		t.Fatal("AsInt succeeded for out of range int")
+	}
+	if _, ok := n.AsUint(); ok {
+		t.Fatal("AsUint succeeded for negative int")
+	}
+	bi, _ := n.AsBigInt()
+	want := new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), 64))
+	if bi.Cmp(want) != 0 {
+		t.Fatalf("got %v, want %v", bi, want)
+	}
+	n2, err := drisl.NewBigInt(bi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !n2.Equal(n) {
+		t.Fatal("NewBigInt result not equal to decoded Node")
+	}
+	if _, err := drisl.NewBigInt(want.Sub(want, big.NewInt(1))); err == nil {
+		t.Fatal("NewBigInt succeeded for out of range int")
+	}
+
+	for _, i := range []int64{0, 1, -1, -9223372036854775808, 9223372036854775807} {
+		got, ok := drisl.NewInt(i).AsInt()
+		if !ok || got != i {
+			t.Fatalf("got %d, want %d", got, i)
+		}
+	}
+
+	// Other accessors don't interpret ints
+	if b, ok := drisl.NewInt(1).AsBool(); b || ok {
+		t.Fatalf("AsBool on int 1 = %v, %v, want false, false", b, ok)
+	}
+}
+
+func TestNodeLookup(t *testing.T) {
+	link := cid.HashBytes([]byte("image"))
+	data, err := drisl.Marshal(map[string]any{
+		"text": "hello",
+		"embed": map[string]any{
+			"images": []any{
+				map[string]any{"alt": "", "image": link},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n drisl.Node
+	if err := drisl.Unmarshal(data, &n); err != nil {
+		t.Fatal(err)
+	}
+
+	img, ok := n.Lookup("embed/images/0/image")
+	if !ok {
+		t.Fatal("Lookup failed")
+	}
+	if got, _ := img.AsLink(); !got.Equal(link) {
+		t.Fatalf("got %v, want %v", got, link)
+	}
+	if got, _ := n.Lookup("/text"); got.Kind() != drisl.KindString {
+		t.Fatalf("got kind %v, want string", got.Kind())
+	}
+	if got, _ := n.Lookup(""); !got.Equal(n) {
+		t.Fatal("empty path did not return the Node itself")
+	}
+	for _, path := range []string{"missing", "embed/images/1", "embed/images/x", "text/a"} {
+		if _, ok := n.Lookup(path); ok {
+			t.Errorf("Lookup(%q) succeeded, want failure", path)
+		}
+	}
+}
+
+func TestNodeMap(t *testing.T) {
+	// Keys are sorted when encoding
+	n := drisl.NewMap(
+		drisl.MapEntry{Key: "bb", Value: drisl.NewInt(-1)},
+		drisl.MapEntry{Key: "a", Value: drisl.NewList(drisl.NewNull(), drisl.NewBool(true))},
+	)
+	n.Set("c", drisl.NewBytes([]byte{1}))
+	n.Set("bb", drisl.NewString("x"))
+	n.Delete("c")
+	got, err := drisl.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := hexDecode("a2616182f6f56262626178")
+	if !bytes.Equal(got, want) {
+		t.Fatalf("got %x, want %x", got, want)
+	}
+
+	// Decoded order is preserved
+	var n2 drisl.Node
+	if err := drisl.Unmarshal(got, &n2); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := n2.AsMap()
+	if entries[0].Key != "a" || entries[1].Key != "bb" {
+		t.Fatalf("got keys %q, %q", entries[0].Key, entries[1].Key)
+	}
+	if !n2.Equal(n) {
+		t.Fatal("decoded Node not equal to original")
+	}
+
+	dup := drisl.NewMap(
+		drisl.MapEntry{Key: "a", Value: drisl.NewInt(1)},
+		drisl.MapEntry{Key: "a", Value: drisl.NewInt(2)},
+	)
+	if _, err := drisl.Marshal(dup); err == nil {
+		t.Fatal("Marshal succeeded with duplicate keys")
+	}
+}
+
+func TestNodeInStruct(t *testing.T) {
+	type record struct {
+		Type  string     `cbor:"$type"`
+		Value drisl.Node `cbor:"value"`
+	}
+	data := hexDecode("a265247479706561786576616c7565a1616101")
+	var r record
+	if err := drisl.Unmarshal(data, &r); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := r.Value.Lookup("a"); !v.Equal(drisl.NewUint(1)) {
+		t.Fatalf("got %v, want 1", v)
+	}
+	got, err := drisl.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("got %x, want %x", got, data)
+	}
+}
@@ -0,0 +1,334 @@
+package drisl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"unicode/utf8"
+
+	"github.com/hyphacoop/go-dasl/cid"
+)
+
+// These defaults match the underlying CBOR library.
+const (
+	defaultMaxNestedLevels  = 32
+	defaultMaxArrayElements = 128 * 1024
+	defaultMaxMapPairs      = 128 * 1024
+)
+
+// ValidationError is returned when data is not valid DRISL.
+type ValidationError struct {
+	// Offset is the byte offset of the first violation, from the start of the data.
+	Offset int
+
+	// Reason describes the violation.
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("go-dasl/drisl: invalid data at offset %d: %s", e.Offset, e.Reason)
+}
+
+// Valid reports whether data is a single valid DRISL item, using the default decoding options.
+// See Validate for details.
+func Valid(data []byte) bool {
+	return Validate(data) == nil
+}
+
+// Validate checks that data is a single valid DRISL item, using the default decoding options.
+// Data that passes validation will be accepted by Unmarshal when decoding into an empty interface.
+//
+// The data is walked once and no memory is allocated, unless a ValidationError is returned.
+// That error contains the byte offset of the first violation.
+func Validate(data []byte) error {
+	return ValidateWith(drislDecMode, data)
+}
+
+// ValidateWith is like Validate, but honours the limits and other options of dm.
+// If dm was not created by DecOptions.DecMode, the default decoding options are used.
+func ValidateWith(dm DecMode, data []byte) error {
+	v := newValidator(modeOf(dm).opts)
+	return v.validate(data)
+}
+
+// validator walks encoded CBOR and checks that it follows every DRISL rule.
+type validator struct {
+	data []byte
+	off  int
+
+	maxNestedLevels  int
+	maxArrayElements int
+	maxMapPairs      int
+	int64RangeOnly   bool
+	allowUndefined   bool
+	useRawCid        bool
+	noFloats         bool
+
+	// onLink is called with the bytes of each CID found, without the 0x00 prefix.
+	// Returning false stops validation with errStopLinks.
+	onLink func(b []byte) bool
+}
+
+func newValidator(opts DecOptions) validator {
+	v := validator{
+		maxNestedLevels:  opts.MaxNestedLevels,
+		maxArrayElements: opts.MaxArrayElements,
+		maxMapPairs:      opts.MaxMapPairs,
+		int64RangeOnly:   opts.Int64RangeOnly,
+		allowUndefined:   opts.AllowUndefined,
+		useRawCid:        opts.UseRawCid,
+		noFloats:         opts.NoFloats,
+	}
+	if v.maxNestedLevels == 0 {
+		v.maxNestedLevels = defaultMaxNestedLevels
+	}
+	if v.maxArrayElements == 0 {
+		v.maxArrayElements = defaultMaxArrayElements
+	}
+	if v.maxMapPairs == 0 {
+		v.maxMapPairs = defaultMaxMapPairs
+	}
+	return v
+}
+
+// validate checks the whole of data, which must be exactly one item.
+func (v *validator) validate(data []byte) error {
+	v.data = data
+	v.off = 0
+	if len(data) == 0 {
+		return &ValidationError{0, "no data"}
+	}
+	if err := v.item(0); err != nil {
+		return err
+	}
+	if v.off != len(v.data) {
+		return &ValidationError{v.off, "extraneous data after item"}
+	}
+	return nil
+}
+
+// head reads the initial byte and argument of an item, enforcing the shortest
+// possible encoding of the argument. It is not used for major type 7.
+func (v *validator) head() (major byte, arg uint64, err error) {
+	start := v.off
+	ib := v.data[v.off]
+	major = ib >> 5
+	ai := ib & 0x1f
+	v.off++
+
+	switch {
+	case ai < 24:
+		return major, uint64(ai), nil
+	case ai == 31:
+		return 0, 0, &ValidationError{start, "indefinite length items are not allowed"}
+	case ai > 27:
+		return 0, 0, &ValidationError{start, "reserved additional information value"}
+	}
+
+	n := 1 << (ai - 24)
+	if len(v.data)-v.off < n {
+		return 0, 0, &ValidationError{start, "unexpected end of data"}
+	}
+	b := v.data[v.off : v.off+n]
+	v.off += n
+	var min uint64
+	switch n {
+	case 1:
+		arg, min = uint64(b[0]), 24
+	case 2:
+		arg, min = uint64(binary.BigEndian.Uint16(b)), math.MaxUint8+1
+	case 4:
+		arg, min = uint64(binary.BigEndian.Uint32(b)), math.MaxUint16+1
+	case 8:
+		arg, min = binary.BigEndian.Uint64(b), math.MaxUint32+1
+	}
+	if arg < min {
+		return 0, 0, &ValidationError{start, "integer or length is not in its shortest form"}
+	}
+	return major, arg, nil
+}
+
+// item validates a single item starting at the current offset.
+func (v *validator) item(depth int) error {
+	start := v.off
+	if start >= len(v.data) {
+		return &ValidationError{start, "unexpected end of data"}
+	}
+	if v.data[start]>>5 == 7 {
+		return v.simple()
+	}
+
+	major, arg, err := v.head()
+	if err != nil {
+		return err
+	}
+
+	switch major {
+	case 0, 1:
+		// Integers
+		if v.int64RangeOnly && arg > math.MaxInt64 {
+			return &ValidationError{start, "integer is out of the int64 range"}
+		}
+	case 2:
+		// Byte string
+		if uint64(len(v.data)-v.off) < arg {
+			return &ValidationError{start, "unexpected end of data"}
+		}
+		v.off += int(arg)
+	case 3:
+		// Text string
+		if uint64(len(v.data)-v.off) < arg {
+			return &ValidationError{start, "unexpected end of data"}
+		}
+		if !utf8.Valid(v.data[v.off : v.off+int(arg)]) {
+			return &ValidationError{start, "invalid UTF-8 string"}
+		}
+		v.off += int(arg)
+	case 4:
+		// Array
+		depth++
+		if depth > v.maxNestedLevels {
+			return &ValidationError{start, "exceeded max nested levels"}
+		}
+		if arg > uint64(v.maxArrayElements) {
+			return &ValidationError{start, "exceeded max array elements"}
+		}
+		for range arg {
+			if err := v.item(depth); err != nil {
+				return err
+			}
+		}
+	case 5:
+		// Map
+		depth++
+		if depth > v.maxNestedLevels {
+			return &ValidationError{start, "exceeded max nested levels"}
+		}
+		if arg > uint64(v.maxMapPairs) {
+			return &ValidationError{start, "exceeded max map pairs"}
+		}
+		var prevKey []byte
+		for range arg {
+			keyStart := v.off
+			if keyStart >= len(v.data) {
+				return &ValidationError{keyStart, "unexpected end of data"}
+			}
+			if v.data[keyStart]>>5 != 3 {
+				return &ValidationError{keyStart, "map key is not a text string"}
+			}
+			if err := v.item(depth); err != nil {
+				return err
+			}
+			// Keys must be sorted by their encoded bytes, which means length-first
+			key := v.data[keyStart:v.off]
+			if prevKey != nil {
+				switch bytes.Compare(prevKey, key) {
+				case 0:
+					return &ValidationError{keyStart, "duplicate map key"}
+				case 1:
+					return &ValidationError{keyStart, "map keys are not sorted"}
+				}
+			}
+			prevKey = key
+			if err := v.item(depth); err != nil {
+				return err
+			}
+		}
+	case 6:
+		// Tag
+		depth++
+		if depth > v.maxNestedLevels {
+			return &ValidationError{start, "exceeded max nested levels"}
+		}
+		if arg != CidTagNumber {
+			return &ValidationError{start, "tag number is not 42"}
+		}
+		return v.cid()
+	}
+	return nil
+}
+
+// cid validates the content of a CID tag.
+func (v *validator) cid() error {
+	start := v.off
+	if start >= len(v.data) {
+		return &ValidationError{start, "unexpected end of data"}
+	}
+	if v.data[start]>>5 != 2 {
+		return &ValidationError{start, "CID tag content is not a byte string"}
+	}
+	if err := v.item(0); err != nil {
+		return err
+	}
+	// Skip the byte string head to get to the content
+	content := v.data[start:v.off]
+	_, n := cborHeadLen(content[0])
+	content = content[n:]
+
+	if len(content) == 0 {
+		return &ValidationError{start, "CID is empty"}
+	}
+	if content[0] != 0x00 {
+		return &ValidationError{start, "CID does not have 0x00 prefix"}
+	}
+	if !v.useRawCid {
+		if _, err := cid.NewCidFromBytes(content[1:]); err != nil {
+			return &ValidationError{start, "CID is not a valid DASL CID"}
+		}
+	}
+	if v.onLink != nil && !v.onLink(content[1:]) {
+		return errStopLinks
+	}
+	return nil
+}
+
+// simple validates major type 7: simple values and floats.
+func (v *validator) simple() error {
+	start := v.off
+	ai := v.data[start] & 0x1f
+	v.off++
+
+	switch ai {
+	case 20, 21, 22:
+		// false, true, null
+		return nil
+	case 23:
+		if v.allowUndefined {
+			return nil
+		}
+		return &ValidationError{start, "undefined is not allowed"}
+	case 25, 26:
+		return &ValidationError{start, "float is not 64 bits wide"}
+	case 27:
+		if v.noFloats {
+			return &ValidationError{start, "floats are not allowed"}
+		}
+		if len(v.data)-v.off < 8 {
+			return &ValidationError{start, "unexpected end of data"}
+		}
+		f := math.Float64frombits(binary.BigEndian.Uint64(v.data[v.off:]))
+		v.off += 8
+		if math.IsNaN(f) {
+			return &ValidationError{start, "NaN is not allowed"}
+		}
+		if math.IsInf(f, 0) {
+			return &ValidationError{start, "infinity is not allowed"}
+		}
+		return nil
+	case 31:
+		return &ValidationError{start, "indefinite length items are not allowed"}
+	default:
+		return &ValidationError{start, "simple value is not allowed"}
+	}
+}
+
+// cborHeadLen returns the major type and the length in bytes of an item head,
+// given its initial byte. The initial byte must not be indefinite or reserved.
+func cborHeadLen(ib byte) (major byte, n int) {
+	ai := ib & 0x1f
+	if ai < 24 {
+		return ib >> 5, 1
+	}
+	return ib >> 5, 1 + 1<<(ai-24)
+}
@@ -0,0 +1,164 @@
+package drisl_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hyphacoop/go-dasl/drisl"
+)
+
+var validTests = []struct {
+	name string
+	in   string
+}{
+	{"int", "1903e8"},
+	{"negative int", "3903e7"},
+	{"max uint64", "1bffffffffffffffff"},
+	{"min negative int", "3bffffffffffffffff"},
+	{"float64", "fb3ff8000000000000"},
+	{"bytes", "43010203"},
+	{"string", "6568656c6c6f"},
+	{"array", "8301820203820405"},
+	{"sorted map", "a3616101616202626161f5"},
+	{"nested map", "a16161a1616280"},
+	{"simple values", "83f4f5f6"},
+	{"cid", "d82a582500015512205891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"},
+}
+
+var invalidTests = []struct {
+	name   string
+	in     string
+	offset int
+}{
+	{"empty", "", 0},
+	{"truncated", "19", 0},
+	{"truncated string", "6568656c6c", 0},
+	{"truncated array", "830102", 3},
+	{"extra data", "0101", 1},
+	{"non-shortest int", "1801", 0},
+	{"non-shortest length", "590001ff", 0},
+	{"non-shortest nested", "82011801", 2},
+	{"indefinite array", "9f01ff", 0},
+	{"indefinite string", "7f6161ff", 0},
+	{"reserved info", "1c", 0},
+	{"float16", "f93e00", 0},
+	{"float32", "fa3fc00000", 0},
+	{"NaN", "fb7ff8000000000000", 0},
+	{"infinity", "fb7ff0000000000000", 0},
+	{"undefined", "f7", 0},
+	{"simple value", "e0", 0},
+	{"one byte simple value", "f820", 0},
+	{"invalid UTF-8", "62c328", 0},
+	{"int map key", "a10101", 1},
+	{"bytes map key", "a1410101", 1},
+	{"unsorted map", "a2616201616102", 4},
+	{"length-first sorting", "a2626161016162", 5},
+	{"duplicate map key", "a2616101616102", 4},
+	{"tag not 42", "c11a514b67b0", 0},
+	{"cid not bytes", "d82a6161", 2},
+	{"cid no prefix", "d82a582501015512205891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03", 2},
+	{"cid empty", "d82a40", 2},
+	{"cid not DASL", "d82a582300122022ad631c69ee983095b5b8acd029ff94aff1dc6c48837878589a92b90dfea317", 2},
+}
+
+func TestValidate(t *testing.T) {
+	for _, tt := range validTests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := hexDecode(tt.in)
+			if err := drisl.Validate(data); err != nil {
+				t.Fatalf("Validate(%s) = %v, want nil", tt.in, err)
+			}
+			// Make sure the validator agrees with the decoder
+			var v any
+			if err := drisl.Unmarshal(data, &v); err != nil {
+				t.Fatalf("Unmarshal(%s) = %v, want nil", tt.in, err)
+			}
+		})
+	}
+}
+
+func TestValidateInvalid(t *testing.T) {
+	for _, tt := range invalidTests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := hexDecode(tt.in)
+			err := drisl.Validate(data)
+			var ve *drisl.ValidationError
+			if !errors.As(err, &ve) {
+				t.Fatalf("Validate(%s) = %v, want ValidationError", tt.in, err)
+			}
+			if ve.Offset != tt.offset {
+				t.Errorf("Validate(%s) offset = %d, want %d (%v)", tt.in, ve.Offset, tt.offset, err)
+			}
+			if drisl.Valid(data) {
+				t.Errorf("Valid(%s) = true, want false", tt.in)
+			}
+			// Make sure the validator agrees with the decoder
+			var v any
+			if err := drisl.Unmarshal(data, &v); err == nil {
+				t.Errorf("Unmarshal(%s) succeeded, want error", tt.in)
+			}
+		})
+	}
+}
+
+func TestValidateOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts drisl.DecOptions
+		in   string
+	}{
+		{"Int64RangeOnly", drisl.DecOptions{Int64RangeOnly: true}, "1b8000000000000000"},
+		{"Int64RangeOnly negative", drisl.DecOptions{Int64RangeOnly: true}, "3b8000000000000000"},
+		{"NoFloats", drisl.DecOptions{NoFloats: true}, "fb3ff8000000000000"},
+		{"MaxNestedLevels", drisl.DecOptions{MaxNestedLevels: 4}, "818181818100"},
+		{"MaxArrayElements", drisl.DecOptions{MaxArrayElements: 16}, "91" + strings.Repeat("00", 17)},
+		{"MaxMapPairs", drisl.DecOptions{MaxMapPairs: 16}, "b1" + "616100616200616300616400616500616600616700616800616900616a00616b00616c00616d00616e00616f00617000617100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := hexDecode(tt.in)
+			if err := drisl.Validate(data); err != nil {
+				t.Fatalf("Validate with default options = %v, want nil", err)
+			}
+			dm, err := tt.opts.DecMode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := drisl.ValidateWith(dm, data); err == nil {
+				t.Fatal("Validate with options succeeded, want error")
+			}
+			var v any
+			if err := dm.Unmarshal(data, &v); err == nil {
+				t.Fatal("Unmarshal with options succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestValidateAllowUndefined(t *testing.T) {
+	dm, _ := drisl.DecOptions{AllowUndefined: true}.DecMode()
+	if err := drisl.ValidateWith(dm, []byte{0xf7}); err != nil {
+		t.Fatalf("Validate(undefined) when allowed = %v", err)
+	}
+}
+
+func TestValidateRawCid(t *testing.T) {
+	data := hexDecode("d82a582300122022ad631c69ee983095b5b8acd029ff94aff1dc6c48837878589a92b90dfea317")
+	dm, _ := drisl.DecOptions{UseRawCid: true}.DecMode()
+	if err := drisl.ValidateWith(dm, data); err != nil {
+		t.Fatalf("Validate(RawCid) when allowed = %v", err)
+	}
+}
+
+func TestValidateAllocs(t *testing.T) {
+	data := hexDecode("a3616183010203616282f5f6626161d82a582500015512205891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03")
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := drisl.Validate(data); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Validate allocated %v times, want 0", allocs)
+	}
+}
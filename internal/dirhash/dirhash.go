@@ -0,0 +1,194 @@
+// Package dirhash hashes all the files in a directory in parallel.
+// It is shared by the rasl and masl packages.
+package dirhash
+
+import (
+	"context"
+	"crypto/sha256"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"lukechampine.com/blake3"
+)
+
+// Options configures Hash.
+type Options struct {
+	// Sha256 hashes every file with SHA-256.
+	Sha256 bool
+
+	// Blake3 hashes every file with BLAKE3.
+	Blake3 bool
+
+	// Read is called with the whole contents of each file, if set.
+	// This requires reading each file into memory. The return value is stored
+	// in File.Info. It is called from multiple goroutines.
+	Read func(path string, data []byte) any
+}
+
+// File is a hashed file.
+type File struct {
+	// Path is the slash-separated path of the file, relative to the directory.
+	Path string
+
+	// Sha256 and Blake3 are the digests of the file, if enabled in the options.
+	Sha256 [cid.HashSize]byte
+	Blake3 [cid.HashSize]byte
+
+	// Info is the value returned by Options.Read.
+	Info any
+}
+
+// Hash recursively hashes all the regular files in a directory. Files are read in parallel.
+// The files are returned sorted by path.
+//
+// An error is returned if there is any issue reading files.
+func Hash(dir string, opts Options) ([]File, error) {
+	// Have worker pool iterate over path channel
+	// Inspired by: https://github.com/makew0rld/merkdir/blob/f69ec2d2218689a423d548f56aabd8514ec49591/commands.go#L34
+	// Which I give myself permission to reuse under the license in this repo
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, 16)
+	pathCh := make(chan string)
+	retCh := make(chan File, 16)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Launch workers, waiting for paths
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					// Cancelled
+					return
+				case path, ok := <-pathCh:
+					if !ok {
+						// No more paths
+						return
+					}
+					f, err := hashFile(dir, path, opts)
+					if err != nil {
+						errCh <- err
+						return
+					}
+					select {
+					case retCh <- f:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	// Distribute file paths to workers
+	go func() {
+		fs.WalkDir(os.DirFS(dir), ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				errCh <- err
+				return err // Stop
+			}
+			if d.IsDir() {
+				return nil
+			}
+			if d.Type() != 0 {
+				// Some sort of special file
+				return nil
+			}
+			select {
+			case pathCh <- path:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		close(pathCh)
+	}()
+
+	// Signal when all workers are done with no errors
+	go func() {
+		wg.Wait()
+		close(retCh)
+	}()
+
+	// Process worker results
+	var files []File
+	for {
+		select {
+		case err := <-errCh:
+			// One worker had an error, stop all of them
+			return nil, err
+		case f, ok := <-retCh:
+			if !ok {
+				// All workers done
+				// Check for a walk error that came in after the last result
+				select {
+				case err := <-errCh:
+					return nil, err
+				default:
+				}
+				slices.SortFunc(files, func(a, b File) int {
+					return strings.Compare(a.Path, b.Path)
+				})
+				return files, nil
+			}
+			files = append(files, f)
+		}
+	}
+}
+
+// hashFile hashes a single file.
+func hashFile(dir, path string, opts Options) (File, error) {
+	f, err := os.Open(filepath.Join(dir, path))
+	if err != nil {
+		return File{}, err
+	}
+	// Closed explicitly below to catch errors, this is just for early returns
+	defer f.Close()
+
+	var writers []io.Writer
+	var hasherSha256, hasherBlake3 hash.Hash
+	if opts.Sha256 {
+		hasherSha256 = sha256.New()
+		writers = append(writers, hasherSha256)
+	}
+	if opts.Blake3 {
+		hasherBlake3 = blake3.New(cid.HashSize, nil)
+		writers = append(writers, hasherBlake3)
+	}
+	w := io.MultiWriter(writers...)
+
+	ret := File{Path: path}
+	if opts.Read != nil {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return File{}, err
+		}
+		w.Write(data)
+		ret.Info = opts.Read(path, data)
+	} else if _, err := io.Copy(w, f); err != nil {
+		return File{}, err
+	}
+	if err := f.Close(); err != nil {
+		return File{}, err
+	}
+
+	if hasherSha256 != nil {
+		hasherSha256.Sum(ret.Sha256[:0])
+	}
+	if hasherBlake3 != nil {
+		hasherBlake3.Sum(ret.Blake3[:0])
+	}
+	return ret, nil
+}
@@ -0,0 +1,65 @@
+package masl
+
+import (
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/internal/dirhash"
+)
+
+// BundleOptions configures BundleFromDir.
+type BundleOptions struct {
+	// HashBlake3 makes CIDs with BLAKE3 instead of SHA-256.
+	HashBlake3 bool
+}
+
+// BundleFromDir creates a bundle mode document from all the files in a directory.
+// Files are read in parallel, and an error is returned if there is any issue reading them.
+//
+// Each file becomes a resource at its path relative to dir, with a raw CID (bafkr) for its
+// content. The index.html file at the root of the directory is stored at "/". Other index.html
+// files keep their path, and Document.Lookup finds them for their directory path.
+//
+// ContentType is set based on the file extension, or by sniffing the content
+// if the extension is unknown.
+//
+// The content of every file is returned in the blocks map, keyed by CID.
+// This means the whole directory is read into memory.
+func BundleFromDir(dir string, opts BundleOptions) (*Document, map[cid.Cid][]byte, error) {
+	files, err := dirhash.Hash(dir, dirhash.Options{
+		Sha256: !opts.HashBlake3,
+		Blake3: opts.HashBlake3,
+		Read: func(_ string, data []byte) any {
+			return data
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	doc := &Document{Resources: make(map[string]*Resource, len(files))}
+	blocks := make(map[cid.Cid][]byte, len(files))
+	for _, f := range files {
+		data := f.Info.([]byte)
+		var c cid.Cid
+		if opts.HashBlake3 {
+			c, _ = cid.NewCidFromInfo(cid.CodecRaw, cid.HashTypeBlake3, f.Blake3)
+		} else {
+			c, _ = cid.NewCidFromInfo(cid.CodecRaw, cid.HashTypeSha256, f.Sha256)
+		}
+		blocks[c] = data
+
+		contentType := mime.TypeByExtension(path.Ext(f.Path))
+		if contentType == "" {
+			contentType = http.DetectContentType(data)
+		}
+		p := "/" + f.Path
+		if p == "/index.html" {
+			p = "/"
+		}
+		doc.Resources[p] = &Resource{Src: c, ContentType: contentType}
+	}
+	return doc, blocks, nil
+}
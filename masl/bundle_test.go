@@ -0,0 +1,76 @@
+package masl_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/masl"
+)
+
+func TestBundleFromDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"index.html":      []byte("<!DOCTYPE html><h1>Hello</h1>"),
+		"app.js":          []byte("console.log('hi')"),
+		"docs/index.html": []byte("<!DOCTYPE html><h1>Docs</h1>"),
+		"data":            []byte("\x89PNG\r\n\x1a\n"),
+	}
+	for name, data := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, blake3 := range []bool{false, true} {
+		doc, blocks, err := masl.BundleFromDir(dir, masl.BundleOptions{HashBlake3: blake3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := doc.Validate(); err != nil {
+			t.Fatalf("invalid document: %v", err)
+		}
+		if len(doc.Resources) != 4 || len(blocks) != 4 {
+			t.Fatalf("expected 4 resources and blocks, got %d and %d", len(doc.Resources), len(blocks))
+		}
+
+		tests := []struct {
+			path        string
+			file        string
+			contentType string
+		}{
+			{"/", "index.html", "text/html"},
+			{"/docs/", "docs/index.html", "text/html"},
+			{"/app.js", "app.js", "text/javascript"},
+			{"/data", "data", "image/png"},
+		}
+		for _, tt := range tests {
+			r, ok := doc.Lookup(tt.path)
+			if !ok {
+				t.Fatalf("%s not found", tt.path)
+			}
+			if !strings.HasPrefix(r.ContentType, tt.contentType) {
+				t.Errorf("%s: expected content type %s, got %s", tt.path, tt.contentType, r.ContentType)
+			}
+			if (r.Src.HashType() == cid.HashTypeBlake3) != blake3 {
+				t.Errorf("%s: unexpected hash type %v", tt.path, r.Src.HashType())
+			}
+			if r.Src.Codec() != cid.CodecRaw {
+				t.Errorf("%s: unexpected codec %v", tt.path, r.Src.Codec())
+			}
+			if !bytes.Equal(blocks[r.Src], files[tt.file]) {
+				t.Errorf("%s: block does not match file", tt.path)
+			}
+			if !r.Src.VerifyBytes(blocks[r.Src]) {
+				t.Errorf("%s: block does not match CID", tt.path)
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package masl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/hyphacoop/go-dasl/car"
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+)
+
+var ErrMissingBlock = errors.New("go-dasl/masl: resource content is missing")
+
+// srcs returns the CIDs of all the resources in the document, without duplicates.
+// For bundles they are in the order of the resource paths.
+func (d *Document) srcs() []cid.Cid {
+	if !d.IsBundle() {
+		if d.Src.Defined() {
+			return []cid.Cid{d.Src}
+		}
+		return nil
+	}
+	paths := make([]string, 0, len(d.Resources))
+	for path := range d.Resources {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	var srcs []cid.Cid
+	seen := make(map[cid.Cid]bool)
+	for _, path := range paths {
+		c := d.Resources[path].Src
+		if !seen[c] {
+			seen[c] = true
+			srcs = append(srcs, c)
+		}
+	}
+	return srcs
+}
+
+// carHeader is a document written as a CAR header. Unlike in Document, the roots
+// are always encoded, even when empty.
+type carHeader struct {
+	Document
+	Roots []cid.Cid `cbor:"roots"`
+}
+
+// WriteCar writes a MASL CAR: a CAR v1 file where the header is the MASL document,
+// and the blocks are the content of every resource.
+//
+// The document is written with Version set to 1. If it has no Roots, they are set
+// to the Src CIDs of the resources, or left as an empty array if there are none.
+// The document passed in is not modified.
+//
+// The content of each resource is taken from blocks. ErrMissingBlock is returned
+// if any is missing, and ErrCidMismatch if any doesn't match its CID.
+func WriteCar(w io.Writer, doc *Document, blocks map[cid.Cid][]byte) error {
+	srcs := doc.srcs()
+	for _, c := range srcs {
+		data, ok := blocks[c]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrMissingBlock, c)
+		}
+		if !c.VerifyBytes(data) {
+			return fmt.Errorf("%w: %s", ErrCidMismatch, c)
+		}
+	}
+
+	header := carHeader{Document: *doc}
+	header.Version = 1
+	header.Roots = doc.Roots
+	if len(header.Roots) == 0 {
+		header.Roots = srcs
+	}
+	if header.Roots == nil {
+		// Encode as an empty array, which CAR v1 requires
+		header.Roots = []cid.Cid{}
+	}
+	cw, err := car.NewWriterWithHeader(w, &header)
+	if err != nil {
+		return err
+	}
+	for _, c := range srcs {
+		if err := cw.WriteBlock(c, blocks[c]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadCar reads a MASL CAR written by WriteCar, returning the document from the header and
+// the blocks keyed by CID.
+//
+// Every block is verified against its CID, and ErrMissingBlock is returned if the
+// content of any resource is not in the file. Blocks not used by the document are
+// returned as well.
+func ReadCar(r io.Reader) (*Document, map[cid.Cid][]byte, error) {
+	cr, err := car.NewReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	var doc Document
+	if err := drisl.Unmarshal(cr.RawHeader(), &doc); err != nil {
+		return nil, nil, fmt.Errorf("go-dasl/masl: decoding document: %w", err)
+	}
+
+	blocks := make(map[cid.Cid][]byte)
+	for c, data := range cr.Blocks() {
+		blocks[c] = data
+	}
+	if err := cr.Err(); err != nil {
+		return nil, nil, err
+	}
+	for _, c := range doc.srcs() {
+		if _, ok := blocks[c]; !ok {
+			return nil, nil, fmt.Errorf("%w: %s", ErrMissingBlock, c)
+		}
+	}
+	return &doc, blocks, nil
+}
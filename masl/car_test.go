@@ -0,0 +1,123 @@
+package masl_test
+
+import (
+	"bytes"
+	"errors"
+	"maps"
+	"reflect"
+	"testing"
+
+	"github.com/hyphacoop/go-dasl/car"
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+	"github.com/hyphacoop/go-dasl/masl"
+)
+
+func TestCarRoundtrip(t *testing.T) {
+	index := []byte("<h1>Hello</h1>")
+	script := []byte("console.log('hi')")
+	blocks := map[cid.Cid][]byte{
+		cid.HashBytes(index):  index,
+		cid.HashBytes(script): script,
+	}
+	doc := &masl.Document{
+		Resource: masl.Resource{Name: "App"},
+		Resources: map[string]*masl.Resource{
+			"/":           {Src: cid.HashBytes(index), ContentType: "text/html"},
+			"/index.html": {Src: cid.HashBytes(index), ContentType: "text/html"},
+			"/app.js":     {Src: cid.HashBytes(script), ContentType: "text/javascript"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := masl.WriteCar(&buf, doc, blocks); err != nil {
+		t.Fatalf("WriteCar failed: %v", err)
+	}
+	if doc.Version != 0 || doc.Roots != nil {
+		t.Fatal("WriteCar modified the document")
+	}
+
+	// It's a normal CAR file too
+	cr, err := car.NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	wantRoots := []cid.Cid{cid.HashBytes(index), cid.HashBytes(script)}
+	if h := cr.Header(); h.Version != 1 || !reflect.DeepEqual(h.Roots, wantRoots) {
+		t.Fatalf("unexpected header: %+v", h)
+	}
+
+	got, gotBlocks, err := masl.ReadCar(&buf)
+	if err != nil {
+		t.Fatalf("ReadCar failed: %v", err)
+	}
+	if !maps.EqualFunc(gotBlocks, blocks, bytes.Equal) {
+		t.Fatalf("blocks not equal after roundtrip")
+	}
+	doc.Version = 1
+	doc.Roots = wantRoots
+	// Compare encodings, since empty and nil Attributes maps are equivalent
+	gotData, _ := drisl.Marshal(got)
+	wantData, _ := drisl.Marshal(doc)
+	if !bytes.Equal(gotData, wantData) {
+		t.Fatalf("documents not equal after roundtrip:\noriginal: %+v\nread: %+v", doc, got)
+	}
+}
+
+func TestWriteCarInvalid(t *testing.T) {
+	data := []byte("data")
+	doc := &masl.Document{Resource: masl.Resource{Src: cid.HashBytes(data)}}
+
+	var buf bytes.Buffer
+	if err := masl.WriteCar(&buf, doc, nil); !errors.Is(err, masl.ErrMissingBlock) {
+		t.Fatalf("got %v, want ErrMissingBlock", err)
+	}
+	blocks := map[cid.Cid][]byte{cid.HashBytes(data): []byte("other")}
+	if err := masl.WriteCar(&buf, doc, blocks); !errors.Is(err, masl.ErrCidMismatch) {
+		t.Fatalf("got %v, want ErrCidMismatch", err)
+	}
+}
+
+func TestReadCarMissingBlock(t *testing.T) {
+	doc := &masl.Document{
+		Resource: masl.Resource{Src: cid.HashBytes([]byte("data"))},
+		Version:  1,
+	}
+	// A plain CAR writer doesn't check for the blocks
+	var buf bytes.Buffer
+	if _, err := car.NewWriterWithHeader(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := masl.ReadCar(&buf); !errors.Is(err, masl.ErrMissingBlock) {
+		t.Fatalf("got %v, want ErrMissingBlock", err)
+	}
+}
+
+func TestWriteCarNoRoots(t *testing.T) {
+	doc := &masl.Document{Resource: masl.Resource{Name: "x"}}
+
+	var buf bytes.Buffer
+	if err := masl.WriteCar(&buf, doc, nil); err != nil {
+		t.Fatal(err)
+	}
+	// Any CAR reader must accept the header
+	cr, err := car.NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var header map[string]any
+	if err := drisl.Unmarshal(cr.RawHeader(), &header); err != nil {
+		t.Fatal(err)
+	}
+	if roots, ok := header["roots"].([]any); !ok || len(roots) != 0 {
+		t.Fatalf("got roots %#v, want an empty array", header["roots"])
+	}
+
+	got, _, err := masl.ReadCar(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "x" || len(got.Roots) != 0 {
+		t.Fatalf("got document %+v", got)
+	}
+}
@@ -0,0 +1,102 @@
+package masl
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/hyphacoop/go-dasl/cid"
+)
+
+// manifestPath is where the generated Web App Manifest is served by BundleHandler.
+const manifestPath = "/manifest.webmanifest"
+
+// BundleHandler serves the resources of a bundle mode MASL document over HTTP.
+//
+// Request paths are resolved using Document.Lookup, and the content for the Src CID
+// of the resource is retrieved with the store function. HTTP headers such as
+// Content-Type are set from the fields of the resource.
+//
+// The store function can return (nil, nil) to indicate the CID is not available.
+// This will result in status code 404. Otherwise, errors are returned over HTTP with
+// status code 500. The returned io.ReadCloser is closed after all data is sent.
+//
+// It is up to the caller to validate that the data returned actually matches the hash digest
+// of the CID it's in response to.
+//
+// If the document has Web App Manifest fields, a manifest generated from them is served
+// at /manifest.webmanifest, unless the bundle already has a resource at that path.
+//
+// The document can't be safely modified after being passed to this function.
+func BundleHandler(doc *Document, store func(cid.Cid) (io.ReadCloser, error)) http.Handler {
+	// Don't override a manifest that's already in the bundle
+	var manifest []byte
+	if _, ok := doc.Resources[manifestPath]; !ok && doc.hasManifestFields() {
+		var err error
+		manifest, err = doc.WebManifest()
+		if err != nil {
+			// Only strings and slices of structs of strings
+			panic(err)
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "HEAD" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		resource, ok := doc.Lookup(r.URL.Path)
+		if !ok {
+			if manifest != nil && r.URL.Path == manifestPath {
+				w.Header().Set("Content-Type", "application/manifest+json")
+				w.Write(manifest)
+				return
+			}
+			http.NotFound(w, r)
+			return
+		}
+
+		rc, err := store(resource.Src)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if rc == nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer rc.Close()
+
+		setHeaders(w.Header(), resource)
+		if _, err := io.Copy(w, rc); err != nil {
+			// Unfortunately some data might already be written at this point
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	})
+}
+
+// setHeaders sets the HTTP response headers described by the resource.
+func setHeaders(h http.Header, r *Resource) {
+	for _, f := range []struct {
+		name  string
+		value string
+	}{
+		{"Content-Type", r.ContentType},
+		{"Content-Disposition", r.ContentDisposition},
+		{"Content-Encoding", r.ContentEncoding},
+		{"Content-Language", r.ContentLanguage},
+		{"Content-Security-Policy", r.ContentSecurityPolicy},
+		{"Link", r.Link},
+		{"Permissions-Policy", r.PermissionsPolicy},
+		{"Referrer-Policy", r.ReferrerPolicy},
+		{"Service-Worker-Allowed", r.ServiceWorkerAllowed},
+		{"SourceMap", r.Sourcemap},
+		{"Speculation-Rules", r.SpeculationRules},
+		{"Supports-Loading-Mode", r.SupportsLoadingMode},
+		{"X-Content-Type-Options", r.XContentTypeOptions},
+	} {
+		if f.value != "" {
+			h.Set(f.name, f.value)
+		}
+	}
+}
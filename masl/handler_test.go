@@ -0,0 +1,148 @@
+package masl_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/masl"
+)
+
+func TestBundleHandler(t *testing.T) {
+	page := []byte("<h1>Hello</h1>")
+	script := []byte("console.log('hi')")
+	icon := []byte("png")
+	blocks := map[cid.Cid][]byte{
+		cid.HashBytes(page):   page,
+		cid.HashBytes(script): script,
+		cid.HashBytes(icon):   icon,
+	}
+	doc := &masl.Document{
+		Resource: masl.Resource{
+			Name:  "Test App",
+			Icons: []masl.Icon{{Src: "/icon.png", Sizes: "512x512"}},
+		},
+		Resources: map[string]*masl.Resource{
+			"/index.html": {
+				Src:                   cid.HashBytes(page),
+				ContentType:           "text/html",
+				ContentLanguage:       "en",
+				ContentSecurityPolicy: "default-src 'self'",
+			},
+			"/app.js": {
+				Src:              cid.HashBytes(script),
+				ContentType:      "application/javascript",
+				ContentEncoding:  "identity",
+				Sourcemap:        "/app.js.map",
+				SpeculationRules: "/rules.json",
+			},
+			"/icon.png":    {Src: cid.HashBytes(icon), ContentType: "image/png"},
+			"/missing.txt": {Src: cid.HashBytes([]byte("missing"))},
+		},
+	}
+	handler := masl.BundleHandler(doc, func(c cid.Cid) (io.ReadCloser, error) {
+		data, ok := blocks[c]
+		if !ok {
+			return nil, nil
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	})
+
+	tests := []struct {
+		path    string
+		code    int
+		body    []byte
+		headers map[string]string
+	}{
+		{"/", http.StatusOK, page, map[string]string{
+			"Content-Type":            "text/html",
+			"Content-Language":        "en",
+			"Content-Security-Policy": "default-src 'self'",
+		}},
+		{"/app.js", http.StatusOK, script, map[string]string{
+			"Content-Type":      "application/javascript",
+			"Content-Encoding":  "identity",
+			"SourceMap":         "/app.js.map",
+			"Speculation-Rules": "/rules.json",
+		}},
+		{"/missing.txt", http.StatusNotFound, nil, nil},
+		{"/nope", http.StatusNotFound, nil, nil},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != tt.code {
+			t.Fatalf("%s: expected status %d, got %d", tt.path, tt.code, w.Code)
+		}
+		if tt.body != nil && !bytes.Equal(w.Body.Bytes(), tt.body) {
+			t.Fatalf("%s: expected body %q, got %q", tt.path, tt.body, w.Body.Bytes())
+		}
+		for k, v := range tt.headers {
+			if got := w.Header().Get(k); got != v {
+				t.Errorf("%s: expected header %s %q, got %q", tt.path, k, v, got)
+			}
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+}
+
+func TestBundleHandlerManifest(t *testing.T) {
+	doc := &masl.Document{
+		Resource: masl.Resource{
+			Name:       "Test App",
+			ShortName:  "Test",
+			ThemeColor: "#000000",
+			Icons:      []masl.Icon{{Src: "/icon.png", Sizes: "512x512", Purpose: "any"}},
+		},
+		Resources: map[string]*masl.Resource{
+			"/icon.png": {Src: cid.HashBytes([]byte("png"))},
+		},
+	}
+	handler := masl.BundleHandler(doc, func(c cid.Cid) (io.ReadCloser, error) {
+		return nil, nil
+	})
+
+	req := httptest.NewRequest("GET", "/manifest.webmanifest", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/manifest+json" {
+		t.Fatalf("Expected Content-Type 'application/manifest+json', got '%s'", ct)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["name"] != "Test App" || m["short_name"] != "Test" || m["theme_color"] != "#000000" {
+		t.Fatalf("Unexpected manifest: %s", w.Body.Bytes())
+	}
+	icons := m["icons"].([]any)
+	if icon := icons[0].(map[string]any); icon["src"] != "/icon.png" || icon["sizes"] != "512x512" || icon["purpose"] != "any" {
+		t.Fatalf("Unexpected manifest icons: %s", w.Body.Bytes())
+	}
+
+	// No manifest without manifest fields
+	handler = masl.BundleHandler(&masl.Document{Resources: map[string]*masl.Resource{}}, func(c cid.Cid) (io.ReadCloser, error) {
+		return nil, nil
+	})
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
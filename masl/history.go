@@ -0,0 +1,213 @@
+package masl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+)
+
+// DefaultMaxHistoryDepth is the default maximum number of versions History walks.
+const DefaultMaxHistoryDepth = 1000
+
+var (
+	ErrCidMismatch    = errors.New("go-dasl/masl: document does not match CID")
+	ErrHistoryCycle   = errors.New("go-dasl/masl: cycle in version history")
+	ErrHistoryTooDeep = errors.New("go-dasl/masl: version history is too deep")
+)
+
+// Version is a single document in a version history.
+type Version struct {
+	Cid      cid.Cid
+	Document *Document
+}
+
+// HistoryOptions configures walking a version history.
+type HistoryOptions struct {
+	// MaxDepth is the maximum number of versions to walk, including the head.
+	// If it is zero, DefaultMaxHistoryDepth is used.
+	MaxDepth int
+}
+
+// History walks the version history of a document using the default options.
+// See HistoryOptions.History for details.
+func History(ctx context.Context, head cid.Cid, get func(cid.Cid) ([]byte, error)) iter.Seq2[Version, error] {
+	return HistoryOptions{}.History(ctx, head, get)
+}
+
+// History walks the version history of a document, starting with head and following
+// the Prev link of each document until one has no Prev.
+//
+// The get function retrieves the encoded document for a CID. It may return (nil, nil)
+// if the CID is not available, which is reported as an error.
+//
+// Each document is verified against its CID before it is decoded.
+// Iteration stops after the first error, which can be ErrCidMismatch, ErrHistoryCycle,
+// ErrHistoryTooDeep, an error from get or decoding, or the context error if ctx is done.
+func (opts HistoryOptions) History(ctx context.Context, head cid.Cid, get func(cid.Cid) ([]byte, error)) iter.Seq2[Version, error] {
+	maxDepth := opts.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultMaxHistoryDepth
+	}
+
+	return func(yield func(Version, error) bool) {
+		seen := make(map[cid.Cid]bool)
+		c := head
+		for depth := 0; c.Defined(); depth++ {
+			if err := ctx.Err(); err != nil {
+				yield(Version{}, err)
+				return
+			}
+			if depth == maxDepth {
+				yield(Version{}, ErrHistoryTooDeep)
+				return
+			}
+			if seen[c] {
+				yield(Version{}, ErrHistoryCycle)
+				return
+			}
+			seen[c] = true
+
+			data, err := get(c)
+			if err != nil {
+				yield(Version{}, err)
+				return
+			}
+			if data == nil {
+				yield(Version{}, fmt.Errorf("go-dasl/masl: version %s not found", c))
+				return
+			}
+			if !c.VerifyBytes(data) {
+				yield(Version{}, fmt.Errorf("%w: %s", ErrCidMismatch, c))
+				return
+			}
+			var doc Document
+			if err := drisl.Unmarshal(data, &doc); err != nil {
+				yield(Version{}, fmt.Errorf("go-dasl/masl: decoding %s: %w", c, err))
+				return
+			}
+			if !yield(Version{c, &doc}, nil) {
+				return
+			}
+			c = doc.Prev
+		}
+	}
+}
+
+// Changes describes the differences between two versions of a document.
+// All slices are sorted.
+type Changes struct {
+	// Added lists the paths of resources only in the new version.
+	Added []string
+
+	// Removed lists the paths of resources only in the old version.
+	Removed []string
+
+	// Changed lists the resources in both versions that have different attributes.
+	Changed []ResourceChanges
+
+	// Attributes lists the keys of document-level attributes that were added,
+	// removed or changed, such as "name" or "src". Resources are not included.
+	Attributes []string
+}
+
+// ResourceChanges describes the differences between two versions of a resource.
+type ResourceChanges struct {
+	Path string
+
+	// Attributes lists the keys of attributes that were added, removed or changed,
+	// such as "src" or "content-type".
+	Attributes []string
+}
+
+// Diff compares two versions of a document. Attributes are compared by their DRISL encoding,
+// so unknown attributes are compared as well. A nil document is treated as an empty one.
+func Diff(old, new *Document) (*Changes, error) {
+	var changes Changes
+
+	if old == nil {
+		old = &Document{}
+	}
+	if new == nil {
+		new = &Document{}
+	}
+
+	oldNode, err := toNode(old)
+	if err != nil {
+		return nil, err
+	}
+	newNode, err := toNode(new)
+	if err != nil {
+		return nil, err
+	}
+	oldNode.Delete("resources")
+	newNode.Delete("resources")
+	changes.Attributes = diffNodes(oldNode, newNode)
+
+	for path, r := range old.Resources {
+		if _, ok := new.Resources[path]; !ok {
+			changes.Removed = append(changes.Removed, path)
+			continue
+		}
+		oldNode, err := toNode(r)
+		if err != nil {
+			return nil, err
+		}
+		newNode, err := toNode(new.Resources[path])
+		if err != nil {
+			return nil, err
+		}
+		if attrs := diffNodes(oldNode, newNode); attrs != nil {
+			changes.Changed = append(changes.Changed, ResourceChanges{path, attrs})
+		}
+	}
+	for path := range new.Resources {
+		if _, ok := old.Resources[path]; !ok {
+			changes.Added = append(changes.Added, path)
+		}
+	}
+
+	slices.Sort(changes.Added)
+	slices.Sort(changes.Removed)
+	slices.SortFunc(changes.Changed, func(a, b ResourceChanges) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return &changes, nil
+}
+
+// toNode converts a value to a DRISL map Node, so attributes can be compared generically.
+func toNode(v any) (drisl.Node, error) {
+	data, err := drisl.Marshal(v)
+	if err != nil {
+		return drisl.Node{}, err
+	}
+	var n drisl.Node
+	if err := drisl.Unmarshal(data, &n); err != nil {
+		return drisl.Node{}, err
+	}
+	return n, nil
+}
+
+// diffNodes returns the sorted keys that differ between two map Nodes.
+func diffNodes(a, b drisl.Node) []string {
+	var keys []string
+	entries, _ := a.AsMap()
+	for _, e := range entries {
+		if v, ok := b.Get(e.Key); !ok || !v.Equal(e.Value) {
+			keys = append(keys, e.Key)
+		}
+	}
+	entries, _ = b.AsMap()
+	for _, e := range entries {
+		if _, ok := a.Get(e.Key); !ok {
+			keys = append(keys, e.Key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,184 @@
+package masl_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+	"github.com/hyphacoop/go-dasl/masl"
+)
+
+// buildHistory creates a chain of n versions, returning the blocks and the head CID.
+func buildHistory(t *testing.T, n int) (map[cid.Cid][]byte, []cid.Cid) {
+	blocks := make(map[cid.Cid][]byte)
+	var cids []cid.Cid
+	var prev cid.Cid
+	for i := range n {
+		doc := masl.Document{
+			Resource: masl.Resource{Src: cid.HashBytes([]byte{byte(i)}), Name: "v"},
+			Prev:     prev,
+		}
+		data, err := drisl.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prev, _ = cid.NewCidFromInfo(cid.CodecDrisl, cid.HashTypeSha256, cid.HashBytes(data).Digest())
+		blocks[prev] = data
+		cids = append([]cid.Cid{prev}, cids...)
+	}
+	return blocks, cids
+}
+
+func TestHistory(t *testing.T) {
+	blocks, cids := buildHistory(t, 5)
+	get := func(c cid.Cid) ([]byte, error) {
+		return blocks[c], nil
+	}
+
+	var got []cid.Cid
+	for v, err := range masl.History(context.Background(), cids[0], get) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, v.Cid)
+	}
+	if !reflect.DeepEqual(got, cids) {
+		t.Fatalf("got %v, want %v", got, cids)
+	}
+
+	// Max depth
+	var err error
+	n := 0
+	for _, err = range (masl.HistoryOptions{MaxDepth: 3}).History(context.Background(), cids[0], get) {
+		if err != nil {
+			break
+		}
+		n++
+	}
+	if n != 3 || !errors.Is(err, masl.ErrHistoryTooDeep) {
+		t.Fatalf("got %d versions and %v, want 3 and ErrHistoryTooDeep", n, err)
+	}
+
+	// Mismatched block
+	bad := func(c cid.Cid) ([]byte, error) {
+		if c == cids[2] {
+			return blocks[cids[3]], nil
+		}
+		return blocks[c], nil
+	}
+	for _, err = range masl.History(context.Background(), cids[0], bad) {
+		if err != nil {
+			break
+		}
+	}
+	if !errors.Is(err, masl.ErrCidMismatch) {
+		t.Fatalf("got %v, want ErrCidMismatch", err)
+	}
+
+	// Missing block
+	for _, err = range masl.History(context.Background(), cids[0], func(c cid.Cid) ([]byte, error) {
+		if c == cids[1] {
+			return nil, nil
+		}
+		return blocks[c], nil
+	}) {
+		if err != nil {
+			break
+		}
+	}
+	if err == nil {
+		t.Fatal("expected error for missing version")
+	}
+
+	// Cancelled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, err = range masl.History(ctx, cids[0], get) {
+		break
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := &masl.Document{
+		Resource: masl.Resource{Name: "App", Description: "old"},
+		Resources: map[string]*masl.Resource{
+			"/":        {Src: cid.HashBytes([]byte("index")), ContentType: "text/html"},
+			"/app.js":  {Src: cid.HashBytes([]byte("app")), ContentType: "text/javascript"},
+			"/old.css": {Src: cid.HashBytes([]byte("css"))},
+		},
+	}
+	new := &masl.Document{
+		Resource: masl.Resource{
+			Name:       "App",
+			ThemeColor: "#000000",
+			Attributes: map[string]any{"x-custom": "1"},
+		},
+		Resources: map[string]*masl.Resource{
+			"/":        {Src: cid.HashBytes([]byte("index")), ContentType: "text/html"},
+			"/app.js":  {Src: cid.HashBytes([]byte("app2")), ContentLanguage: "en"},
+			"/new.css": {Src: cid.HashBytes([]byte("css"))},
+		},
+		Prev: cid.HashBytes([]byte("prev")),
+	}
+
+	changes, err := masl.Diff(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &masl.Changes{
+		Added:   []string{"/new.css"},
+		Removed: []string{"/old.css"},
+		Changed: []masl.ResourceChanges{
+			{Path: "/app.js", Attributes: []string{"content-language", "content-type", "src"}},
+		},
+		Attributes: []string{"description", "prev", "theme_color", "x-custom"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("got %+v, want %+v", changes, want)
+	}
+
+	changes, err = masl.Diff(old, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changes, &masl.Changes{}) {
+		t.Fatalf("got %+v, want no changes", changes)
+	}
+
+	// A nil document is empty
+	changes, err = masl.Diff(nil, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = &masl.Changes{
+		Added:      []string{"/", "/app.js", "/old.css"},
+		Attributes: []string{"description", "name"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("got %+v, want %+v", changes, want)
+	}
+	changes, err = masl.Diff(old, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = &masl.Changes{
+		Removed:    []string{"/", "/app.js", "/old.css"},
+		Attributes: []string{"description", "name"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("got %+v, want %+v", changes, want)
+	}
+	changes, err = masl.Diff(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changes, &masl.Changes{}) {
+		t.Fatalf("got %+v, want no changes", changes)
+	}
+}
@@ -0,0 +1,126 @@
+package masl
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"path"
+
+	"github.com/hyphacoop/go-dasl/cid"
+)
+
+// manifest is the JSON form of a Web App Manifest.
+type manifest struct {
+	Name            string       `json:"name,omitempty"`
+	ShortName       string       `json:"short_name,omitempty"`
+	Description     string       `json:"description,omitempty"`
+	ID              string       `json:"id,omitempty"`
+	BackgroundColor string       `json:"background_color,omitempty"`
+	ThemeColor      string       `json:"theme_color,omitempty"`
+	Categories      []string     `json:"categories,omitempty"`
+	Icons           []Icon       `json:"icons,omitempty"`
+	Screenshots     []Screenshot `json:"screenshots,omitempty"`
+}
+
+// hasManifestFields returns true if any of the Web App Manifest fields are set.
+func (d *Document) hasManifestFields() bool {
+	return d.Name != "" || d.ShortName != "" || d.Description != "" || d.ID != "" ||
+		d.BackgroundColor != "" || d.ThemeColor != "" || len(d.Categories) > 0 ||
+		len(d.Icons) > 0 || len(d.Screenshots) > 0
+}
+
+// WebManifest generates a W3C Web App Manifest from the Web App Manifest fields
+// of the document.
+//
+// Icon and screenshot src paths are bundle paths, which are absolute URL paths,
+// so they resolve correctly when the manifest is served from the same origin as the bundle.
+// This is what BundleHandler does.
+//
+// https://www.w3.org/TR/appmanifest/
+func (d *Document) WebManifest() ([]byte, error) {
+	return json.Marshal(manifest{
+		Name:            d.Name,
+		ShortName:       d.ShortName,
+		Description:     d.Description,
+		ID:              d.ID,
+		BackgroundColor: d.BackgroundColor,
+		ThemeColor:      d.ThemeColor,
+		Categories:      d.Categories,
+		Icons:           d.Icons,
+		Screenshots:     d.Screenshots,
+	})
+}
+
+// FromWebManifest creates a bundle mode document from a W3C Web App Manifest, so an
+// existing web app can be converted into a bundle. Manifest members that MASL does
+// not support, such as start_url, are ignored.
+//
+// paths maps the bundle path of each file in the web app to the CID of its content,
+// and each one becomes a resource in the document. ContentType is set based on the
+// file extension, if it is known.
+//
+// Icon and screenshot src URLs are resolved against the root of the app and rewritten
+// to bundle paths. An error is returned if one points to a different origin, or
+// to a file that is not in paths.
+//
+// https://www.w3.org/TR/appmanifest/
+func FromWebManifest(data []byte, paths map[string]cid.Cid) (*Document, error) {
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("go-dasl/masl: parsing manifest: %w", err)
+	}
+
+	doc := &Document{
+		Resource: Resource{
+			Name:            m.Name,
+			ShortName:       m.ShortName,
+			Description:     m.Description,
+			ID:              m.ID,
+			BackgroundColor: m.BackgroundColor,
+			ThemeColor:      m.ThemeColor,
+			Categories:      m.Categories,
+			Icons:           m.Icons,
+			Screenshots:     m.Screenshots,
+		},
+		Resources: make(map[string]*Resource, len(paths)),
+	}
+	for p, c := range paths {
+		doc.Resources[p] = &Resource{Src: c, ContentType: mime.TypeByExtension(path.Ext(p))}
+	}
+
+	for i := range doc.Icons {
+		p, err := bundlePath(doc.Icons[i].Src, paths)
+		if err != nil {
+			return nil, fmt.Errorf("go-dasl/masl: icon %d: %w", i, err)
+		}
+		doc.Icons[i].Src = p
+	}
+	for i := range doc.Screenshots {
+		p, err := bundlePath(doc.Screenshots[i].Src, paths)
+		if err != nil {
+			return nil, fmt.Errorf("go-dasl/masl: screenshot %d: %w", i, err)
+		}
+		doc.Screenshots[i].Src = p
+	}
+	return doc, nil
+}
+
+// rootURL is what manifest URLs are resolved against.
+var rootURL = &url.URL{Path: "/"}
+
+// bundlePath converts a manifest URL into a path in the bundle.
+func bundlePath(src string, paths map[string]cid.Cid) (string, error) {
+	u, err := url.Parse(src)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "" || u.Host != "" {
+		return "", fmt.Errorf("src %q is not in the same origin as the app", src)
+	}
+	p := rootURL.ResolveReference(u).Path
+	if _, ok := paths[p]; !ok {
+		return "", fmt.Errorf("src %q is not in the bundle", src)
+	}
+	return p, nil
+}
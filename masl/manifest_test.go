@@ -0,0 +1,98 @@
+package masl_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/masl"
+)
+
+func TestFromWebManifest(t *testing.T) {
+	manifest := []byte(`{
+		"name": "Test App",
+		"short_name": "Test",
+		"start_url": "/",
+		"display": "standalone",
+		"theme_color": "#000000",
+		"categories": ["productivity"],
+		"icons": [
+			{"src": "icons/192.png", "sizes": "192x192", "type": "image/png"},
+			{"src": "/icons/512.png", "sizes": "512x512", "purpose": "maskable"}
+		],
+		"screenshots": [
+			{"src": "./shots/../shot.png", "sizes": "1280x720", "form_factor": "wide"}
+		]
+	}`)
+	paths := map[string]cid.Cid{
+		"/":              cid.HashBytes([]byte("index")),
+		"/icons/192.png": cid.HashBytes([]byte("192")),
+		"/icons/512.png": cid.HashBytes([]byte("512")),
+		"/shot.png":      cid.HashBytes([]byte("shot")),
+	}
+
+	doc, err := masl.FromWebManifest(manifest, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Validate(); err != nil {
+		t.Fatalf("invalid document: %v", err)
+	}
+	if doc.Name != "Test App" || doc.ShortName != "Test" || doc.ThemeColor != "#000000" {
+		t.Fatalf("unexpected document: %+v", doc)
+	}
+	wantIcons := []masl.Icon{
+		{Src: "/icons/192.png", Sizes: "192x192"},
+		{Src: "/icons/512.png", Sizes: "512x512", Purpose: "maskable"},
+	}
+	if !reflect.DeepEqual(doc.Icons, wantIcons) {
+		t.Fatalf("got icons %+v, want %+v", doc.Icons, wantIcons)
+	}
+	if doc.Screenshots[0].Src != "/shot.png" || doc.Screenshots[0].FormFactor != "wide" {
+		t.Fatalf("unexpected screenshots: %+v", doc.Screenshots)
+	}
+	if r := doc.Resources["/icons/192.png"]; r.ContentType != "image/png" || !r.Src.Equal(paths["/icons/192.png"]) {
+		t.Fatalf("unexpected resource: %+v", r)
+	}
+
+	// Export again
+	out, err := doc.WebManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(out, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["name"] != "Test App" || m["start_url"] != nil {
+		t.Fatalf("unexpected manifest: %s", out)
+	}
+	doc2, err := masl.FromWebManifest(out, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(doc, doc2) {
+		t.Fatalf("documents not equal after roundtrip:\n%+v\n%+v", doc, doc2)
+	}
+}
+
+func TestFromWebManifestInvalid(t *testing.T) {
+	paths := map[string]cid.Cid{"/icon.png": cid.HashBytes([]byte("icon"))}
+	tests := []struct {
+		name     string
+		manifest string
+	}{
+		{"not JSON", `{`},
+		{"other origin", `{"icons": [{"src": "https://example.com/icon.png"}]}`},
+		{"missing icon", `{"icons": [{"src": "/other.png"}]}`},
+		{"missing screenshot", `{"screenshots": [{"src": "shot.png"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := masl.FromWebManifest([]byte(tt.manifest), paths); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
@@ -65,7 +65,8 @@ Compatibility Fields:
 
 # Validation
 
-Documents can be validated using the Valid() method, which checks:
+Documents can be validated using the Validate() method, which lists every rule
+violation found, or the Valid() method for a quick check. These check:
   - CAR version must be 0 or 1
   - AT type must be empty or "ing.dasl.masl"
   - Bundle mode: resource paths must start with "/"
@@ -96,9 +97,9 @@ import (
 // Sizes specifies image dimensions (e.g., "512x512" or "192x192 512x512").
 // Purpose indicates icon usage context (e.g., "any", "maskable", "monochrome").
 type Icon struct {
-	Src     string `cbor:"src"`
-	Sizes   string `cbor:"sizes,omitempty"`
-	Purpose string `cbor:"purpose,omitempty"`
+	Src     string `cbor:"src" json:"src"`
+	Sizes   string `cbor:"sizes,omitempty" json:"sizes,omitempty"`
+	Purpose string `cbor:"purpose,omitempty" json:"purpose,omitempty"`
 }
 
 // Screenshot represents a screenshot entry in a Web App Manifest.
@@ -111,11 +112,11 @@ type Icon struct {
 // FormFactor indicates the display format (e.g., "wide", "narrow").
 // Platform specifies the target platform (e.g., "windows", "macos", "android").
 type Screenshot struct {
-	Src        string `cbor:"src"`
-	Sizes      string `cbor:"sizes,omitempty"`
-	Label      string `cbor:"label,omitempty"`
-	FormFactor string `cbor:"form_factor,omitempty"`
-	Platform   string `cbor:"platform,omitempty"`
+	Src        string `cbor:"src" json:"src"`
+	Sizes      string `cbor:"sizes,omitempty" json:"sizes,omitempty"`
+	Label      string `cbor:"label,omitempty" json:"label,omitempty"`
+	FormFactor string `cbor:"form_factor,omitempty" json:"form_factor,omitempty"`
+	Platform   string `cbor:"platform,omitempty" json:"platform,omitempty"`
 }
 
 // Resource represents metadata for a single resource in a MASL document.
@@ -224,7 +225,7 @@ type Resource struct {
 // Versioning (Prev):
 //   - Prev: Links to previous document version CID, creating version chains
 //
-// Use IsBundle() to check the document mode, and Valid() to validate
+// Use IsBundle() to check the document mode, and Validate() or Valid() to validate
 // according to the MASL specification.
 type Document struct {
 	Resource
@@ -260,132 +261,38 @@ func (d *Document) IsBundle() bool {
 	return d.Resources != nil
 }
 
-// Valid validates the MASL document according to the MASL specification.
+// Lookup returns the resource for a URL path, following the MASL path resolution rules.
 //
-// Validation checks depend on document mode (single vs bundle) and include:
+// An empty path is treated as "/". If there is no exact match and the path ends
+// with "/", "index.html" is appended and tried as well.
 //
-// CAR Compatibility:
-//   - Version must be 0 or 1 (if specified)
-//
-// AT Protocol Compatibility:
-//   - Type must be empty or "ing.dasl.masl" (if specified)
-//
-// Bundle Mode Validation:
-//   - All resource paths must start with "/"
-//   - All resource paths must be non-empty
-//   - All resources must have a defined Src CID
-//   - Sourcemap fields must reference existing resources in the Resources map
-//   - SpeculationRules fields must reference existing resources in the Resources map
-//   - Icon Src fields must reference existing resources in the Resources map
-//   - Screenshot Src fields must reference existing resources in the Resources map
-//
-// Single Mode Validation:
-//   - Icon Src fields must be empty (no Resources map to reference)
-//   - Screenshot Src fields must be empty (no Resources map to reference)
-//
-// Returns true if the document is valid according to all applicable rules,
-// false otherwise. This method does not return specific validation errors;
-// it is intended for quick validity checks.
-func (d *Document) Valid() bool {
-	// Validate CAR compatibility fields
-	if d.Version != 0 && d.Version != 1 {
-		return false
-	}
-
-	// Validate AT compatibility fields
-	if d.Type != "" && d.Type != "ing.dasl.masl" {
-		return false
-	}
-
-	// Validate versioning fields
-	// Prev field validation is handled by CID type itself
-
-	if d.IsBundle() {
-		// Bundle mode validation
-		return d.validateBundle()
-	} else {
-		// Single mode validation
-		return d.validateSingle()
-	}
-}
-
-func (d *Document) validateBundle() bool {
-	// Bundle mode: validate resources map
-	for path, resource := range d.Resources {
-		// Resource paths MUST start with /
-		if path == "" || !strings.HasPrefix(path, "/") {
-			return false
-		}
-
-		// Resources MUST have a src field
-		if !resource.Src.Defined() {
-			return false
-		}
-
-		// Validate HTTP header references within this resource
-		if !d.validateResourceReferences(resource) {
-			return false
-		}
-	}
-
-	// Validate app manifest references (icons, screenshots)
-	return d.validateAppManifestReferences()
-}
-
-func (d *Document) validateSingle() bool {
-	// Single mode: app manifest icons/screenshots should not reference paths
-	// since there's no resources map to resolve them against
-	for _, icon := range d.Icons {
-		if icon.Src != "" {
-			return false
-		}
+// For single mode documents, the embedded Resource is returned for "/" and no
+// other path.
+func (d *Document) Lookup(path string) (*Resource, bool) {
+	if path == "" {
+		path = "/"
 	}
-
-	for _, screenshot := range d.Screenshots {
-		if screenshot.Src != "" {
-			return false
+	if !d.IsBundle() {
+		if path == "/" {
+			return &d.Resource, true
 		}
+		return nil, false
 	}
-
-	return true
-}
-
-func (d *Document) validateResourceReferences(resource *Resource) bool {
-	// Validate sourcemap references
-	if resource.Sourcemap != "" {
-		if _, exists := d.Resources[resource.Sourcemap]; !exists {
-			return false
-		}
+	if r, ok := d.Resources[path]; ok {
+		return r, true
 	}
-
-	// Validate speculation-rules references
-	if resource.SpeculationRules != "" {
-		if _, exists := d.Resources[resource.SpeculationRules]; !exists {
-			return false
+	if strings.HasSuffix(path, "/") {
+		if r, ok := d.Resources[path+"index.html"]; ok {
+			return r, true
 		}
 	}
-
-	return true
+	return nil, false
 }
 
-func (d *Document) validateAppManifestReferences() bool {
-	// Validate icon references
-	for _, icon := range d.Icons {
-		if icon.Src != "" {
-			if _, exists := d.Resources[icon.Src]; !exists {
-				return false
-			}
-		}
-	}
-
-	// Validate screenshot references
-	for _, screenshot := range d.Screenshots {
-		if screenshot.Src != "" {
-			if _, exists := d.Resources[screenshot.Src]; !exists {
-				return false
-			}
-		}
-	}
-
-	return true
+// Valid reports whether the MASL document is valid according to the MASL specification.
+//
+// It is a shortcut for checking whether Validate returns nil.
+// See Validate for the rules that are checked.
+func (d *Document) Valid() bool {
+	return d.Validate() == nil
 }
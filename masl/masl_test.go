@@ -141,3 +141,43 @@ func TestMASLHeaderCaseInsensitive(t *testing.T) {
 		t.Errorf("expected Src to match testCid")
 	}
 }
+
+func TestDocumentLookup(t *testing.T) {
+	index := &masl.Resource{Src: cid.HashBytes([]byte("index"))}
+	docsIndex := &masl.Resource{Src: cid.HashBytes([]byte("docs"))}
+	app := &masl.Resource{Src: cid.HashBytes([]byte("app"))}
+	doc := masl.Document{
+		Resources: map[string]*masl.Resource{
+			"/":                index,
+			"/docs/index.html": docsIndex,
+			"/app.js":          app,
+		},
+	}
+
+	tests := []struct {
+		path string
+		want *masl.Resource
+	}{
+		{"", index},
+		{"/", index},
+		{"/app.js", app},
+		{"/docs/", docsIndex},
+		{"/docs/index.html", docsIndex},
+		{"/docs", nil},
+		{"/missing", nil},
+	}
+	for _, tt := range tests {
+		got, ok := doc.Lookup(tt.path)
+		if ok != (tt.want != nil) || got != tt.want {
+			t.Errorf("Lookup(%q) = %v, %v, want %v", tt.path, got, ok, tt.want)
+		}
+	}
+
+	single := masl.Document{Resource: masl.Resource{Src: app.Src}}
+	if got, ok := single.Lookup(""); !ok || got.Src != app.Src {
+		t.Errorf("single mode Lookup(\"\") = %v, %v", got, ok)
+	}
+	if _, ok := single.Lookup("/app.js"); ok {
+		t.Error("single mode Lookup(\"/app.js\") succeeded")
+	}
+}
@@ -0,0 +1,59 @@
+package masl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hyphacoop/go-dasl/drisl"
+	"github.com/hyphacoop/go-dasl/rasl"
+)
+
+// maxDocumentSize limits how much is read when fetching a MASL document.
+const maxDocumentSize = 1 << 20
+
+var ErrPathNotFound = errors.New("go-dasl/masl: path not found in document")
+
+// Resolve fetches the resource a RASL URL points to, following its Path through a MASL document.
+//
+// The root CID of the URL is fetched and decoded as a MASL document, and the URL Path is
+// looked up in it using Document.Lookup. If it isn't found, ErrPathNotFound is returned.
+// Then the Src CID of the resource is fetched from the same hints.
+//
+// The returned Resource holds the HTTP metadata for the body, such as ContentType.
+// The body is verified against the Src CID as it is read, like with rasl.URL.FetchContext.
+// Close the body to clean up the network connection.
+func Resolve(ctx context.Context, u *rasl.URL, opts rasl.FetchOptions) (*Resource, io.ReadCloser, error) {
+	rc, err := u.FetchContext(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(rc, maxDocumentSize+1))
+	rc.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) > maxDocumentSize {
+		return nil, nil, fmt.Errorf("go-dasl/masl: document is larger than %d bytes", maxDocumentSize)
+	}
+
+	var doc Document
+	if err := drisl.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("go-dasl/masl: decoding document: %w", err)
+	}
+	resource, ok := doc.Lookup(u.Path)
+	if !ok {
+		return nil, nil, ErrPathNotFound
+	}
+	if !resource.Src.Defined() {
+		return nil, nil, errors.New("go-dasl/masl: resource has no src")
+	}
+
+	srcURL := rasl.URL{Cid: resource.Src, Hints: u.Hints}
+	body, err := srcURL.FetchContext(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resource, body, nil
+}
@@ -0,0 +1,85 @@
+package masl_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
+	"github.com/hyphacoop/go-dasl/masl"
+	"github.com/hyphacoop/go-dasl/rasl"
+)
+
+func TestResolve(t *testing.T) {
+	page := []byte("<h1>Hello</h1>")
+	pageCid := cid.HashBytes(page)
+	doc := masl.Document{
+		Resources: map[string]*masl.Resource{
+			"/index.html": {Src: pageCid, ContentType: "text/html", ContentLanguage: "en"},
+		},
+	}
+	docData, err := drisl.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docCid, err := drisl.CidForValue(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The page is served with the wrong data, to check verification
+	bad := []byte("bad")
+	badCid := cid.HashBytes([]byte("something else"))
+	doc.Resources["/bad"] = &masl.Resource{Src: badCid}
+	badDocData, _ := drisl.Marshal(doc)
+	badDocCid, _ := drisl.CidForValue(doc)
+
+	blocks := map[cid.Cid][]byte{
+		docCid:    docData,
+		pageCid:   page,
+		badDocCid: badDocData,
+		badCid:    bad,
+	}
+	server := httptest.NewTLSServer(rasl.FuncHandler(func(c cid.Cid) (io.Reader, error) {
+		if data, ok := blocks[c]; ok {
+			return bytes.NewReader(data), nil
+		}
+		return nil, nil
+	}))
+	defer server.Close()
+	opts := rasl.FetchOptions{Client: server.Client()}
+	hints := []string{server.URL[8:]}
+
+	resource, body, err := masl.Resolve(context.Background(), &rasl.URL{Cid: docCid, Hints: hints, Path: "/"}, opts)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	defer body.Close()
+	if resource.ContentType != "text/html" || resource.ContentLanguage != "en" {
+		t.Fatalf("Unexpected resource: %+v", resource)
+	}
+	got, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, page) {
+		t.Fatalf("got %q, want %q", got, page)
+	}
+
+	_, _, err = masl.Resolve(context.Background(), &rasl.URL{Cid: docCid, Hints: hints, Path: "/missing"}, opts)
+	if !errors.Is(err, masl.ErrPathNotFound) {
+		t.Fatalf("got %v, want ErrPathNotFound", err)
+	}
+
+	_, body, err = masl.Resolve(context.Background(), &rasl.URL{Cid: badDocCid, Hints: hints, Path: "/bad"}, opts)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	defer body.Close()
+	if _, err := io.ReadAll(body); !errors.Is(err, rasl.ErrCidValidation) {
+		t.Fatalf("got %v, want ErrCidValidation", err)
+	}
+}
@@ -0,0 +1,182 @@
+package masl
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Rule identifies a MASL validation rule that a document can violate.
+type Rule string
+
+const (
+	// RuleVersion: the CAR version must be 0 or 1.
+	RuleVersion Rule = "version"
+	// RuleType: the AT Protocol type must be empty or "ing.dasl.masl".
+	RuleType Rule = "type"
+	// RulePath: bundle resource paths must start with "/".
+	RulePath Rule = "path"
+	// RuleSrc: bundle resources must have a Src CID.
+	RuleSrc Rule = "src"
+	// RuleSourcemap: a sourcemap must reference a resource in the bundle.
+	RuleSourcemap Rule = "sourcemap-reference"
+	// RuleSpeculationRules: speculation rules must reference a resource in the bundle.
+	RuleSpeculationRules Rule = "speculation-rules-reference"
+	// RuleIcon: in bundle mode an icon src must reference a resource in the bundle,
+	// and in single mode it must be empty.
+	RuleIcon Rule = "icon-reference"
+	// RuleScreenshot: in bundle mode a screenshot src must reference a resource in the bundle,
+	// and in single mode it must be empty.
+	RuleScreenshot Rule = "screenshot-reference"
+)
+
+// ValidationError is a single violation of a MASL rule.
+type ValidationError struct {
+	// Location is where in the document the violation is, for example:
+	// resources["/app.js"].sourcemap
+	Location string
+
+	// Rule is the rule that was violated.
+	Rule Rule
+
+	// Message describes the violation.
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s (%s)", e.Location, e.Message, e.Rule)
+}
+
+// ValidationErrors lists every violation found in a document.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	var sb strings.Builder
+	sb.WriteString("go-dasl/masl: invalid document: ")
+	for i, ve := range e {
+		if i > 0 {
+			sb.WriteString("; ")
+		}
+		sb.WriteString(ve.Error())
+	}
+	return sb.String()
+}
+
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, ve := range e {
+		errs[i] = ve
+	}
+	return errs
+}
+
+// Validate validates the MASL document according to the MASL specification.
+// If the document is invalid, ValidationErrors is returned, listing every violation
+// in a stable order.
+//
+// Validation checks depend on document mode (single vs bundle) and include:
+//
+// CAR Compatibility:
+//   - Version must be 0 or 1 (if specified)
+//
+// AT Protocol Compatibility:
+//   - Type must be empty or "ing.dasl.masl" (if specified)
+//
+// Bundle Mode Validation:
+//   - All resource paths must start with "/"
+//   - All resource paths must be non-empty
+//   - All resources must have a defined Src CID
+//   - Sourcemap fields must reference existing resources in the Resources map
+//   - SpeculationRules fields must reference existing resources in the Resources map
+//   - Icon Src fields must reference existing resources in the Resources map
+//   - Screenshot Src fields must reference existing resources in the Resources map
+//
+// Single Mode Validation:
+//   - Icon Src fields must be empty (no Resources map to reference)
+//   - Screenshot Src fields must be empty (no Resources map to reference)
+func (d *Document) Validate() error {
+	var errs ValidationErrors
+	add := func(loc string, rule Rule, format string, a ...any) {
+		errs = append(errs, &ValidationError{loc, rule, fmt.Sprintf(format, a...)})
+	}
+
+	// Validate CAR compatibility fields
+	if d.Version != 0 && d.Version != 1 {
+		add("version", RuleVersion, "version must be 0 or 1, got %d", d.Version)
+	}
+
+	// Validate AT compatibility fields
+	if d.Type != "" && d.Type != "ing.dasl.masl" {
+		add("$type", RuleType, `type must be "ing.dasl.masl", got %q`, d.Type)
+	}
+
+	// Validate versioning fields
+	// Prev field validation is handled by CID type itself
+
+	if d.IsBundle() {
+		// Bundle mode: validate resources map, in a stable order
+		paths := make([]string, 0, len(d.Resources))
+		for path := range d.Resources {
+			paths = append(paths, path)
+		}
+		slices.Sort(paths)
+		for _, path := range paths {
+			resource := d.Resources[path]
+			loc := "resources[" + strconv.Quote(path) + "]"
+
+			// Resource paths MUST start with /
+			if !strings.HasPrefix(path, "/") {
+				add(loc, RulePath, "path must start with /")
+			}
+
+			// Resources MUST have a src field
+			if !resource.Src.Defined() {
+				add(loc+".src", RuleSrc, "resource has no src")
+			}
+
+			// Validate HTTP header references within this resource
+			if resource.Sourcemap != "" && !d.hasResource(resource.Sourcemap) {
+				add(loc+".sourcemap", RuleSourcemap, "sourcemap %q is not in the bundle", resource.Sourcemap)
+			}
+			if resource.SpeculationRules != "" && !d.hasResource(resource.SpeculationRules) {
+				add(loc+".speculation-rules", RuleSpeculationRules,
+					"speculation rules %q are not in the bundle", resource.SpeculationRules)
+			}
+		}
+	}
+
+	// Validate app manifest references (icons, screenshots)
+	for i, icon := range d.Icons {
+		if icon.Src == "" {
+			continue
+		}
+		loc := fmt.Sprintf("icons[%d].src", i)
+		if !d.IsBundle() {
+			add(loc, RuleIcon, "single mode icons can't have a src")
+		} else if !d.hasResource(icon.Src) {
+			add(loc, RuleIcon, "icon %q is not in the bundle", icon.Src)
+		}
+	}
+	for i, screenshot := range d.Screenshots {
+		if screenshot.Src == "" {
+			continue
+		}
+		loc := fmt.Sprintf("screenshots[%d].src", i)
+		if !d.IsBundle() {
+			add(loc, RuleScreenshot, "single mode screenshots can't have a src")
+		} else if !d.hasResource(screenshot.Src) {
+			add(loc, RuleScreenshot, "screenshot %q is not in the bundle", screenshot.Src)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (d *Document) hasResource(path string) bool {
+	_, ok := d.Resources[path]
+	return ok
+}
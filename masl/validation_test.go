@@ -1,6 +1,7 @@
 package masl_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/hyphacoop/go-dasl/cid"
@@ -437,3 +438,72 @@ func TestValidDocuments(t *testing.T) {
 		}
 	})
 }
+
+func TestValidateErrors(t *testing.T) {
+	doc := &masl.Document{
+		Resource: masl.Resource{
+			Icons:       []masl.Icon{{Src: "/icon.png"}, {Src: "/missing.png"}},
+			Screenshots: []masl.Screenshot{{Src: "/missing.png"}},
+		},
+		Resources: map[string]*masl.Resource{
+			"/app.js":   {Src: cid.HashBytes([]byte("app")), Sourcemap: "/app.js.map"},
+			"/icon.png": {Src: cid.HashBytes([]byte("icon"))},
+			"no-slash":  {SpeculationRules: "/rules.json"},
+		},
+		Version: 2,
+		Type:    "app.bsky.feed.post",
+	}
+
+	err := doc.Validate()
+	var errs masl.ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	want := []struct {
+		location string
+		rule     masl.Rule
+	}{
+		{"version", masl.RuleVersion},
+		{"$type", masl.RuleType},
+		{`resources["/app.js"].sourcemap`, masl.RuleSourcemap},
+		{`resources["no-slash"]`, masl.RulePath},
+		{`resources["no-slash"].src`, masl.RuleSrc},
+		{`resources["no-slash"].speculation-rules`, masl.RuleSpeculationRules},
+		{"icons[1].src", masl.RuleIcon},
+		{"screenshots[0].src", masl.RuleScreenshot},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %v", len(want), len(errs), err)
+	}
+	for i, w := range want {
+		if errs[i].Location != w.location || errs[i].Rule != w.rule {
+			t.Errorf("error %d: expected %s (%s), got %s (%s)", i, w.location, w.rule, errs[i].Location, errs[i].Rule)
+		}
+	}
+
+	var ve *masl.ValidationError
+	if !errors.As(err, &ve) || ve.Rule != masl.RuleVersion {
+		t.Fatalf("expected errors.As to find the first ValidationError, got %v", ve)
+	}
+	if doc.Valid() {
+		t.Fatal("expected Valid to be false")
+	}
+}
+
+func TestValidateSingleMode(t *testing.T) {
+	doc := &masl.Document{
+		Resource: masl.Resource{
+			Icons: []masl.Icon{{Sizes: "512x512"}, {Src: "/icon.png"}},
+		},
+	}
+	err := doc.Validate()
+	var errs masl.ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Location != "icons[1].src" || errs[0].Rule != masl.RuleIcon {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	doc.Icons = doc.Icons[:1]
+	if err := doc.Validate(); err != nil {
+		t.Fatalf("expected valid document, got %v", err)
+	}
+}
@@ -1,14 +1,17 @@
 package rasl
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"hash"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/hyphacoop/go-dasl/bao"
 	"github.com/hyphacoop/go-dasl/cid"
 )
 
@@ -17,35 +20,186 @@ var (
 	ErrCidValidation  = errors.New("go-dasl/rasl: data doesn't match CID")
 )
 
+// HintError describes why fetching from a single hint failed.
+type HintError struct {
+	// Hint is the host that was tried.
+	Hint string
+
+	// StatusCode is the HTTP status code of the response,
+	// or 0 if no response was received.
+	StatusCode int
+
+	// Err is the error that prevented a response from being received,
+	// or nil if a response was received.
+	Err error
+}
+
+func (e *HintError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Hint, e.Err)
+	}
+	return fmt.Sprintf("%s: status %d", e.Hint, e.StatusCode)
+}
+
+func (e *HintError) Unwrap() error {
+	return e.Err
+}
+
+// FetchError is returned when fetching fails for every hint.
+//
+// It matches ErrAllHintsFailed when using errors.Is.
+type FetchError struct {
+	// Hints holds the failure for each hint, in the same order as URL.Hints.
+	Hints []*HintError
+}
+
+func (e *FetchError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(ErrAllHintsFailed.Error())
+	for i, he := range e.Hints {
+		if i == 0 {
+			sb.WriteString(": ")
+		} else {
+			sb.WriteString("; ")
+		}
+		sb.WriteString(he.Error())
+	}
+	return sb.String()
+}
+
+func (e *FetchError) Is(target error) bool {
+	return target == ErrAllHintsFailed
+}
+
+func (e *FetchError) Unwrap() []error {
+	errs := make([]error, len(e.Hints))
+	for i, he := range e.Hints {
+		errs[i] = he
+	}
+	return errs
+}
+
+// FetchOptions configures FetchContext.
+type FetchOptions struct {
+	// Client is used to make requests. If nil, http.DefaultClient is used.
+	// It can be used to set an overall timeout, make requests with cookies,
+	// allow custom certificates, etc.
+	Client *http.Client
+
+	// HintTimeout is how long each hint has to respond with a successful status,
+	// before it is cancelled and counted as failed. It does not limit reading the
+	// body of a successful response. Zero means no timeout.
+	HintTimeout time.Duration
+}
+
 // Fetch retrieves the content if possible.
+// It is the same as calling FetchContext with a background context and the default options,
+// except that ErrAllHintsFailed is returned as is instead of a *FetchError.
+func (ru *URL) Fetch() (io.ReadCloser, error) {
+	return allHintsFailed(ru.FetchContext(context.Background(), FetchOptions{}))
+}
+
+// FetchWithClient is the same as Fetch(), but allows setting a custom http.Client.
+// See FetchOptions.Client.
+func (ru *URL) FetchWithClient(client *http.Client) (io.ReadCloser, error) {
+	if client == nil {
+		return nil, errors.New("client cannot be nil")
+	}
+	return allHintsFailed(ru.FetchContext(context.Background(), FetchOptions{Client: client}))
+}
+
+// allHintsFailed replaces a *FetchError with ErrAllHintsFailed, which Fetch and
+// FetchWithClient have always returned.
+func allHintsFailed(rc io.ReadCloser, err error) (io.ReadCloser, error) {
+	var fetchErr *FetchError
+	if errors.As(err, &fetchErr) {
+		return nil, ErrAllHintsFailed
+	}
+	return rc, err
+}
+
+// FetchContext retrieves the content if possible.
 //
 // All the provided hints are attempted in parallel, and the first successful response is used.
-// There is no overall timeout, although there may be underlying handshake timeouts, etc.
-// If all hints fail, ErrAllHintsFailed is returned.
-// It is not safe to modify the hints slice while Fetch is running.
+// Cancelling ctx cancels all requests, including reading the body of a successful response.
+// If all hints fail, a *FetchError is returned describing the failure of each hint.
+// It matches ErrAllHintsFailed when using errors.Is.
+// It is not safe to modify the hints slice while FetchContext is running.
 //
 // The data is streamed back. CID validation is performed, but can only be confirmed once all the
 // data has been read out. If the CID doesn't match the data, ErrCidValidation will be returned on the
-// last Read call instead of io.EOF.
+// last Read call instead of io.EOF. For BLAKE3 CIDs, FetchRange verifies data as it arrives instead.
 //
 // Close the reader to clean up the network connection.
-func (ru *URL) Fetch() (io.ReadCloser, error) {
-	return ru.FetchWithClient(http.DefaultClient)
+func (ru *URL) FetchContext(ctx context.Context, opts FetchOptions) (io.ReadCloser, error) {
+	resp, cancel, err := ru.fetch(ctx, opts, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	// Validate CID while letting user read
+	return &fetchReader{
+		Reader: cid.NewVerifyingReader(resp.Body, ru.Cid),
+		rc:     resp.Body,
+		cancel: cancel,
+	}, nil
 }
 
-// FetchWithClient is the same as Fetch(), but allows setting a custom http.Client.
-// This can be used to set an overall timeout, make requests with cookies,
-// allow custom certificates, etc.
-func (ru *URL) FetchWithClient(client *http.Client) (io.ReadCloser, error) {
+// FetchRange retrieves a byte range of BLAKE3-addressed content, starting at offset.
+// A negative length, or one going past the end of the content, returns the rest of it.
+// It is like FetchContext otherwise.
+//
+// Instead of the raw data, the hints are asked for a Bao slice: the range along with
+// the BLAKE3 tree nodes needed to verify it, as served by ServeSlice. The data is
+// verified as it arrives, and only returned once verified: if it doesn't match the CID,
+// ErrCidValidation is returned by Read. Hints that don't support slices count as failed.
+//
+// bao.ErrNotBlake3 is returned if the CID doesn't use BLAKE3.
+func (ru *URL) FetchRange(ctx context.Context, opts FetchOptions, offset, length int64) (io.ReadCloser, error) {
+	if ru.Cid.HashType() != cid.HashTypeBlake3 {
+		return nil, bao.ErrNotBlake3
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("%w: offset %d", bao.ErrInvalidRange, offset)
+	}
+	query := url.Values{sliceOffsetParam: {strconv.FormatInt(offset, 10)}}
+	if length >= 0 {
+		query.Set(sliceLengthParam, strconv.FormatInt(length, 10))
+	}
+	var group int
+	resp, cancel, err := ru.fetch(ctx, opts, query.Encode(), func(resp *http.Response) error {
+		var err error
+		group, err = strconv.Atoi(resp.Header.Get(sliceGroupHeader))
+		if err != nil || group < 0 || group > bao.MaxGroup {
+			return errors.New("response is not a Bao slice")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sr, _ := bao.NewSliceReader(resp.Body, ru.Cid, group, offset, length)
+	return &fetchReader{Reader: sr, rc: resp.Body, cancel: cancel, slice: sr}, nil
+}
+
+// fetch requests the content from all the hints in parallel, with the query string if
+// not empty, and returns the first successful response. If check is not nil, it is
+// called on responses with a 200 status, and those it returns an error for count as failed.
+// The returned function releases the request context.
+func (ru *URL) fetch(ctx context.Context, opts FetchOptions, query string, check func(*http.Response) error) (*http.Response, context.CancelFunc, error) {
+	client := opts.Client
 	if client == nil {
-		return nil, errors.New("client cannot be nil")
+		client = http.DefaultClient
 	}
 	if len(ru.Hints) == 0 {
-		return nil, ErrAllHintsFailed
+		return nil, nil, &FetchError{}
+	}
+	if query != "" {
+		query = "?" + query
 	}
 
 	// Collect request results
 	type ret struct {
+		i    int
 		resp *http.Response
 		err  error
 	}
@@ -55,94 +209,107 @@ func (ru *URL) FetchWithClient(client *http.Client) (io.ReadCloser, error) {
 	cidStr := ru.Cid.String()
 	cancelers := make([]context.CancelFunc, numReqs)
 	for i, hint := range ru.Hints {
-		ctx, cancel := context.WithCancel(context.Background())
+		hintCtx, cancel := context.WithCancel(ctx)
 		cancelers[i] = cancel
 		go func() {
-			req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("https://%s/.well-known/rasl/%s", hint, cidStr), nil)
+			req, err := http.NewRequestWithContext(hintCtx, "GET", fmt.Sprintf("https://%s/.well-known/rasl/%s%s", hint, cidStr, query), nil)
 			if err != nil {
-				retCh <- ret{nil, err}
+				retCh <- ret{i, nil, err}
 				return
 			}
+			// Only time out waiting for the response, not reading the body
+			var timer *time.Timer
+			if opts.HintTimeout > 0 {
+				timer = time.AfterFunc(opts.HintTimeout, cancel)
+			}
 			resp, err := client.Do(req)
-			retCh <- ret{resp, err}
+			if timer != nil && !timer.Stop() {
+				// The timeout fired, so the request was cancelled or the body is unusable
+				if resp != nil {
+					resp.Body.Close()
+				}
+				resp, err = nil, context.DeadlineExceeded
+			}
+			retCh <- ret{i, resp, err}
 		}()
 	}
 
-	i := 0
-	var body io.ReadCloser
+	fetchErr := &FetchError{Hints: make([]*HintError, numReqs)}
+	n := 0
+	var winner ret
 	for r := range retCh {
-		if r.err == nil && r.resp.StatusCode == 200 {
+		n++
+		var checkErr error
+		if r.err == nil && r.resp.StatusCode == 200 && check != nil {
+			checkErr = check(r.resp)
+		}
+		if r.err == nil && r.resp.StatusCode == 200 && checkErr == nil {
 			// One hint succeeded, continue with this one only
 			for j := range cancelers {
-				if i == j {
-					continue
+				if j != r.i {
+					cancelers[j]()
 				}
-				cancelers[j]()
 			}
-			body = r.resp.Body
-			i++
+			winner = r
 			break
-		} else if r.resp != nil {
+		}
+		if r.resp != nil {
 			// Clean up resources
 			r.resp.Body.Close()
+			fetchErr.Hints[r.i] = &HintError{Hint: ru.Hints[r.i], StatusCode: r.resp.StatusCode, Err: checkErr}
+		} else {
+			fetchErr.Hints[r.i] = &HintError{Hint: ru.Hints[r.i], Err: r.err}
 		}
-		i++
-		if i == numReqs {
+		cancelers[r.i]()
+		if n == numReqs {
 			// All requests processed, nothing worked
-			return nil, ErrAllHintsFailed
+			return nil, nil, fetchErr
 		}
 	}
 
 	// Clean up the other goroutines and the network requests
-	if i < numReqs {
+	if n < numReqs {
 		go func() {
 			for r := range retCh {
 				if r.resp != nil {
 					r.resp.Body.Close()
 				}
-				i++
-				if i == numReqs {
+				n++
+				if n == numReqs {
 					// Nothing else will come out of that channel
 					return
 				}
 			}
 		}()
 	}
-
-	// Validate CID while letting user read
-	return &verifyReader{
-		cid:    ru.Cid,
-		rc:     body,
-		hasher: ru.Cid.Hasher(),
-	}, nil
+	return winner.resp, cancelers[winner.i], nil
 }
 
-type verifyReader struct {
-	cid    cid.Cid
-	rc     io.ReadCloser
-	hasher hash.Hash
+// fetchReader verifies the body of a response, and closes it.
+type fetchReader struct {
+	io.Reader
+	rc io.ReadCloser
+	// cancel is called on Close to release the request context, if set.
+	cancel context.CancelFunc
+	// slice is the Bao slice decoder reading rc, if any.
+	slice io.Closer
 }
 
-func (vr *verifyReader) Read(p []byte) (n int, err error) {
-	n, err = vr.rc.Read(p)
-	if n > 0 {
-		// A copy is required since Write is allowed to modify the input
-		// Only copy the bytes actually read
-		pCopy := make([]byte, n)
-		copy(pCopy, p[:n])
-		vr.hasher.Write(pCopy)
+func (fr *fetchReader) Read(p []byte) (int, error) {
+	n, err := fr.Reader.Read(p)
+	if errors.Is(err, cid.ErrCidMismatch) {
+		err = fmt.Errorf("%w: %w", ErrCidValidation, err)
 	}
-	if err == io.EOF {
-		// All bytes have been read
-		// Check hash and report
-		dgst := vr.cid.Digest()
-		if !bytes.Equal(dgst[:], vr.hasher.Sum(nil)) {
-			return 0, ErrCidValidation
-		}
-	}
-	return
+	return n, err
 }
 
-func (vr *verifyReader) Close() error {
-	return vr.rc.Close()
+func (fr *fetchReader) Close() error {
+	if fr.slice != nil {
+		fr.slice.Close()
+	}
+	err := fr.rc.Close()
+	if fr.cancel != nil {
+		fr.cancel()
+	}
+	return err
 }
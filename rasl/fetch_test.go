@@ -2,12 +2,16 @@ package rasl_test
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/hyphacoop/go-dasl/bao"
 	"github.com/hyphacoop/go-dasl/cid"
 	"github.com/hyphacoop/go-dasl/rasl"
 )
@@ -118,3 +122,137 @@ func TestFetch_ParallelHints(t *testing.T) {
 		t.Fatalf("Data mismatch: got %q, want %q", data, testData)
 	}
 }
+
+func TestFetchContext_HintErrors(t *testing.T) {
+	testCid := cid.HashBytes([]byte("hello world"))
+
+	notFound := httptest.NewTLSServer(http.NotFoundHandler())
+	defer notFound.Close()
+	slow := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	raslURL := &rasl.URL{
+		Cid:   testCid,
+		Hints: []string{notFound.URL[8:], slow.URL[8:]},
+	}
+	// Both servers use the same certificate
+	_, err := raslURL.FetchContext(context.Background(), rasl.FetchOptions{
+		Client:      notFound.Client(),
+		HintTimeout: 100 * time.Millisecond,
+	})
+	if !errors.Is(err, rasl.ErrAllHintsFailed) {
+		t.Fatalf("Expected ErrAllHintsFailed, got: %v", err)
+	}
+	var fetchErr *rasl.FetchError
+	if !errors.As(err, &fetchErr) {
+		t.Fatalf("Expected FetchError, got: %T", err)
+	}
+	if len(fetchErr.Hints) != 2 {
+		t.Fatalf("Expected 2 hint errors, got %d", len(fetchErr.Hints))
+	}
+	if he := fetchErr.Hints[0]; he.Hint != raslURL.Hints[0] || he.StatusCode != 404 || he.Err != nil {
+		t.Errorf("Unexpected first hint error: %v", he)
+	}
+	if he := fetchErr.Hints[1]; he.StatusCode != 0 || !errors.Is(he.Err, context.DeadlineExceeded) {
+		t.Errorf("Unexpected second hint error: %v", he)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected error to wrap DeadlineExceeded: %v", err)
+	}
+}
+
+func TestFetchContext_Cancel(t *testing.T) {
+	testCid := cid.HashBytes([]byte("hello world"))
+	slow := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	raslURL := &rasl.URL{Cid: testCid, Hints: []string{slow.URL[8:]}}
+	start := time.Now()
+	_, err := raslURL.FetchContext(ctx, rasl.FetchOptions{Client: slow.Client()})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected DeadlineExceeded, got: %v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Fatal("Fetch was not cancelled")
+	}
+}
+
+func TestFetchRange(t *testing.T) {
+	testData := bytes.Repeat([]byte("hello world "), 10000)
+	testCid := cid.HashBytesBlake3(testData)
+	o, err := bao.NewOutboard(bytes.NewReader(testData), int64(len(testData)), bao.DefaultGroup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupted := bytes.Clone(testData)
+	corrupted[50000] = 'x'
+
+	var serve []byte
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serve == nil || !rasl.IsSliceRequest(r) {
+			w.Write(testData)
+			return
+		}
+		rasl.ServeSlice(w, r, o, bytes.NewReader(serve))
+	}))
+	defer server.Close()
+	raslURL := &rasl.URL{Cid: testCid, Hints: []string{server.URL[8:]}}
+	opts := rasl.FetchOptions{Client: server.Client()}
+
+	// Server without slice support
+	if _, err := raslURL.FetchRange(context.Background(), opts, 0, 10); !errors.Is(err, rasl.ErrAllHintsFailed) {
+		t.Fatalf("Expected ErrAllHintsFailed, got: %v", err)
+	}
+
+	serve = testData
+	for _, rg := range []struct{ offset, length, end int64 }{
+		{0, 12, 12},
+		{40000, 30000, 70000},
+		{100000, -1, 120000},
+		{119000, 5000, 120000},
+	} {
+		reader, err := raslURL.FetchRange(context.Background(), opts, rg.offset, rg.length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, testData[rg.offset:rg.end]) {
+			t.Fatalf("Range %v: got %d bytes, want %d", rg, len(data), rg.end-rg.offset)
+		}
+	}
+
+	// Corrupted data is detected without reading the rest of the range
+	serve = corrupted
+	reader, err := raslURL.FetchRange(context.Background(), opts, 0, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if !errors.Is(err, rasl.ErrCidValidation) || !errors.Is(err, cid.ErrCidMismatch) {
+		t.Fatalf("Expected ErrCidValidation wrapping cid.ErrCidMismatch, got: %v", err)
+	}
+	if len(data) >= 50000 || !bytes.Equal(data, testData[:len(data)]) {
+		t.Fatalf("Got %d bytes before the error", len(data))
+	}
+
+	if _, err := (&rasl.URL{Cid: cid.HashBytes(testData)}).FetchRange(context.Background(), opts, 0, 1); err != bao.ErrNotBlake3 {
+		t.Fatalf("Expected ErrNotBlake3, got: %v", err)
+	}
+}
@@ -1,22 +1,111 @@
 package rasl
 
 import (
-	"context"
-	"crypto/sha256"
-	"hash"
+	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"net/http"
 	"os"
 	"path/filepath"
-	"runtime"
+	"strconv"
 	"strings"
-	"sync"
+	"time"
 
+	"github.com/hyphacoop/go-dasl/bao"
 	"github.com/hyphacoop/go-dasl/cid"
-	"lukechampine.com/blake3"
+	"github.com/hyphacoop/go-dasl/drisl"
+	"github.com/hyphacoop/go-dasl/internal/dirhash"
 )
 
+// Bao slice requests, see ServeSlice.
+const (
+	sliceOffsetParam = "bao-offset"
+	sliceLengthParam = "bao-length"
+	sliceGroupHeader = "Rasl-Bao-Group"
+)
+
+// cacheControl is the Cache-Control header of RASL responses.
+// Content addressed by CID never changes, so it can be cached forever.
+const cacheControl = "public, max-age=31536000, immutable"
+
+// setCacheHeaders sets the ETag of a RASL response, and marks it as immutable.
+// The ETag of the whole content is the quoted CID. It reports whether the client
+// already has the response, according to If-None-Match.
+func setCacheHeaders(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl)
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// serveContent responds to a RASL request with the content of a CID.
+// Range requests are supported if content is an io.ReadSeeker, and conditional
+// and HEAD requests always are.
+func serveContent(w http.ResponseWriter, r *http.Request, cidStr string, content io.Reader) {
+	notModified := setCacheHeaders(w, r, `"`+cidStr+`"`)
+	// Spec requires forcing this to prevent smuggling extra info
+	w.Header().Set("Content-Type", "application/octet-stream")
+
+	if rs, ok := content.(io.ReadSeeker); ok {
+		// Handles Range, If-None-Match, and HEAD using the headers set above
+		http.ServeContent(w, r, "", time.Time{}, rs)
+		return
+	}
+	if notModified {
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if r.Method == "HEAD" {
+		return
+	}
+	if _, err := io.Copy(w, content); err != nil {
+		// Unfortunately some data might already be written at this point
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// serveFile responds to a RASL request with the contents of a file.
+func serveFile(w http.ResponseWriter, r *http.Request, cidStr, path string) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !fi.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+	serveContent(w, r, cidStr, f)
+}
+
+// cidFromPath returns the CID string from the path of a RASL request.
+// It returns false if the path is not in the RASL well-known directory, or if it
+// doesn't start like a DASL CID: raw (bafkr) or DRISL (bafyr).
+func cidFromPath(path string) (string, bool) {
+	cidStr, ok := strings.CutPrefix(path, "/.well-known/rasl/")
+	if !ok || !(strings.HasPrefix(cidStr, "bafkr") || strings.HasPrefix(cidStr, "bafyr")) {
+		return "", false
+	}
+	return cidStr, true
+}
+
 // RedirectHandler takes RASL requests and redirects them based on the map.
 //
 // redirects is a map of CIDs to redirect URLs or paths.
@@ -30,11 +119,11 @@ func RedirectHandler(redirects map[string]string) http.Handler {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
-		if !strings.HasPrefix(r.URL.Path, "/.well-known/rasl/bafkr") {
+		cidStr, ok := cidFromPath(r.URL.Path)
+		if !ok {
 			http.NotFound(w, r)
 			return
 		}
-		cidStr := r.URL.Path[len("/.well-known/rasl/"):]
 		// Could do further validation on the CID but tbh it's up to the caller to provide
 		// valid CID strings
 		redir, ok := redirects[cidStr]
@@ -58,6 +147,11 @@ func RedirectHandler(redirects map[string]string) http.Handler {
 // This makes returning files and other Closers safe, although note errors when closing
 // cannot be handled.
 //
+// If it implements io.ReadSeeker, like *os.File and *bytes.Reader, Range requests are
+// supported, which allows seeking in videos and resuming downloads. Like all RASL handlers,
+// responses have the CID as their ETag and can be cached forever, and conditional requests
+// with If-None-Match get status 304 without the content.
+//
 // It is up to the caller to validate that the data returned actually matches the hash digest
 // of the CID it's in response to.
 func FuncHandler(f func(cid.Cid) (io.Reader, error)) http.Handler {
@@ -66,11 +160,12 @@ func FuncHandler(f func(cid.Cid) (io.Reader, error)) http.Handler {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
-		if !strings.HasPrefix(r.URL.Path, "/.well-known/rasl/bafkr") {
+		cidStr, ok := cidFromPath(r.URL.Path)
+		if !ok {
 			http.NotFound(w, r)
 			return
 		}
-		c, err := cid.NewCidFromString(r.URL.Path[len("/.well-known/rasl/"):])
+		c, err := cid.NewCidFromString(cidStr)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -84,23 +179,16 @@ func FuncHandler(f func(cid.Cid) (io.Reader, error)) http.Handler {
 			http.NotFound(w, r)
 			return
 		}
-
-		// Spec requires forcing this to prevent smuggling extra info
-		w.Header().Add("Content-Type", "application/octet-stream")
-
-		if _, err := io.Copy(w, reader); err != nil {
-			// Unfortunately some data might already be written at this point
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
 		if closer, ok := reader.(io.Closer); ok {
-			closer.Close()
+			defer closer.Close()
 		}
+		serveContent(w, r, cidStr, reader)
 	})
 }
 
 // CidDirectoryHandler takes RASL requests and opens a file on disk with the relevant CID as its name.
-// This is just a wrapper around the existing http.FileServer, with correct path and CID checking.
+// Files are served like with http.FileServer, including Range requests, with correct path
+// and CID checking.
 //
 // This is useful if you already have a storage system that is just files with CID names
 // stored in a single directory.
@@ -110,26 +198,22 @@ func FuncHandler(f func(cid.Cid) (io.Reader, error)) http.Handler {
 // CIDs in requests are validated, so if this directory contains other files without CID names,
 // these will not be retrieved. Directories are not descended into.
 func CidDirectoryHandler(dir string) http.Handler {
-	dirHandler := http.StripPrefix("/.well-known/rasl/", http.FileServer(http.Dir(dir)))
-
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "GET" && r.Method != "HEAD" {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
-		if !strings.HasPrefix(r.URL.Path, "/.well-known/rasl/bafkr") {
+		cidStr, ok := cidFromPath(r.URL.Path)
+		if !ok {
 			http.NotFound(w, r)
 			return
 		}
-		cidStr := r.URL.Path[len("/.well-known/rasl/"):]
-		// Validate CID
+		// Validate CID, which also makes it safe to use as a file name
 		if _, err := cid.NewCidFromString(cidStr); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-
-		w.Header().Add("Content-Type", "application/octet-stream")
-		dirHandler.ServeHTTP(w, r)
+		serveFile(w, r, cidStr, filepath.Join(dir, cidStr))
 	})
 }
 
@@ -144,157 +228,166 @@ func CidDirectoryHandler(dir string) http.Handler {
 // in the initial hashing period.
 //
 // Set hashBlake3 to true to also hash every file with BLAKE3, not just SHA-256.
+//
+// This is the same as calling DirectoryHandlerWithOptions with only HashBlake3 set.
 func DirectoryHandler(dir string, hashBlake3 bool) (http.Handler, error) {
-	// Initial hashing
-	// Have worker pool iterate over path channel
-	// Inspired by: https://github.com/makew0rld/merkdir/blob/f69ec2d2218689a423d548f56aabd8514ec49591/commands.go#L34
-	// Which I give myself permission to reuse under the license in this repo
+	return DirectoryHandlerWithOptions(dir, DirectoryOptions{HashBlake3: hashBlake3})
+}
 
-	var wg sync.WaitGroup
-	errCh := make(chan error, 16)
-	pathCh := make(chan string)
+// DirectoryOptions configures DirectoryHandlerWithOptions.
+type DirectoryOptions struct {
+	// HashBlake3 hashes every file with BLAKE3 as well as SHA-256, so files can be
+	// requested with either type of CID.
+	HashBlake3 bool
 
-	type ret struct {
-		digestBlake3 []byte
-		digestSha256 []byte
-		path         string
-	}
-	retCh := make(chan ret, 16)
-	ctx, cancel := context.WithCancel(context.Background())
-
-	// Launch workers, waiting for paths
-	for i := 0; i < runtime.NumCPU(); i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for {
-				select {
-				case <-ctx.Done():
-					// Cancelled
-					return
-				case path, ok := <-pathCh:
-					if !ok {
-						// No more paths
-						return
-					}
+	// BaoOutboards computes the BLAKE3 tree of every file when the handler is created,
+	// so byte ranges of files can be requested with URL.FetchRange and verified as
+	// they arrive. This implies HashBlake3. The outboards are kept in memory, taking
+	// 1/256 of the size of the files.
+	BaoOutboards bool
 
-					f, err := os.Open(filepath.Join(dir, path))
-					if err != nil {
-						errCh <- err
-						return
-					}
-					// Close f manually so that files aren't left open while the loop runs
-					// Otherwise the max open file limit will be hit for large dirs on at
-					// least some OSes like macOS.
-
-					// Calculate data
-					var w io.Writer
-					hasherSha256 := sha256.New()
-					var hasherBlake3 hash.Hash
-					if hashBlake3 {
-						hasherBlake3 = blake3.New(cid.HashSize, nil)
-						w = io.MultiWriter(hasherSha256, hasherBlake3)
-					} else {
-						w = hasherSha256
-					}
-					_, err = io.Copy(w, f)
-					if err != nil {
-						errCh <- err
-						return
-					}
-					err = f.Close()
-					if err != nil {
-						errCh <- err
-						return
-					}
+	// IndexDrisl makes files that are valid DRISL available under DRISL CIDs (bafyr),
+	// as well as raw CIDs (bafkr). This requires reading each file into memory.
+	IndexDrisl bool
+}
 
-					// Return data
-					r := ret{
-						digestSha256: hasherSha256.Sum(nil),
-						path:         path,
-					}
-					if hashBlake3 {
-						r.digestBlake3 = hasherBlake3.Sum(nil)
-					}
-					retCh <- r
-				}
-			}
-		}()
+// DirectoryHandlerWithOptions is the same as DirectoryHandler, but with more options.
+// See DirectoryOptions for details.
+func DirectoryHandlerWithOptions(dir string, opts DirectoryOptions) (http.Handler, error) {
+	// Initial hashing
+	opts.HashBlake3 = opts.HashBlake3 || opts.BaoOutboards
+	hashOpts := dirhash.Options{Sha256: true, Blake3: opts.HashBlake3}
+	if opts.IndexDrisl {
+		// Whole file is needed for validation
+		hashOpts.Read = func(_ string, data []byte) any {
+			return drisl.Valid(data)
+		}
+	}
+	files, err := dirhash.Hash(dir, hashOpts)
+	if err != nil {
+		return nil, err
 	}
 
-	// Distribute file paths to workers
-	go func() {
-		fs.WalkDir(os.DirFS(dir), ".", func(path string, d fs.DirEntry, err error) error {
-			if err != nil {
-				errCh <- err
-				return err // Stop
-			}
-			if d.IsDir() {
-				return nil
-			}
-			if d.Type() != 0 {
-				// Some sort of special file
-				return nil
-			}
-			pathCh <- path
-			return nil
-		})
-		close(pathCh)
-	}()
-
-	// Signal when all workers are done with no errors
-	go func() {
-		wg.Wait()
-		errCh <- nil
-	}()
-
-	// Process worker results
 	cidPaths := make(map[string]string)
-outer:
-	for {
-		select {
-		case err := <-errCh:
-			if err == nil {
-				// All workers done without errors
-				break outer
-			} else {
-				// One worker had an error, stop all of them
-				cancel()
+	outboards := make(map[[cid.HashSize]byte]*bao.Outboard)
+	for _, f := range files {
+		if opts.BaoOutboards {
+			o, err := bao.NewOutboardFile(filepath.Join(dir, f.Path), bao.DefaultGroup)
+			if err != nil {
 				return nil, err
 			}
-		case r := <-retCh:
-			c, _ := cid.NewCidFromInfo(cid.CodecRaw, cid.HashTypeSha256, [32]byte(r.digestSha256))
-			cidPaths[c.String()] = r.path
-			if hashBlake3 {
-				c, _ := cid.NewCidFromInfo(cid.CodecRaw, cid.HashTypeBlake3, [32]byte(r.digestBlake3))
-				cidPaths[c.String()] = r.path
+			if o.Cid().Digest() != f.Blake3 {
+				return nil, fmt.Errorf("go-dasl/rasl: %s changed while hashing", f.Path)
+			}
+			outboards[f.Blake3] = o
+		}
+		codecs := []cid.Codec{cid.CodecRaw}
+		if isDrisl, _ := f.Info.(bool); isDrisl {
+			codecs = append(codecs, cid.CodecDrisl)
+		}
+		for _, codec := range codecs {
+			c, _ := cid.NewCidFromInfo(codec, cid.HashTypeSha256, f.Sha256)
+			cidPaths[c.String()] = f.Path
+			if opts.HashBlake3 {
+				c, _ := cid.NewCidFromInfo(codec, cid.HashTypeBlake3, f.Blake3)
+				cidPaths[c.String()] = f.Path
 			}
 		}
 	}
 
-	// To reach this point, all workers must have completed
-	// But for safety, and to silence go vet, cancel them anyway
-	cancel()
-
 	// Create handler
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "GET" && r.Method != "HEAD" {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
-		if !strings.HasPrefix(r.URL.Path, "/.well-known/rasl/bafkr") {
+		cidStr, ok := cidFromPath(r.URL.Path)
+		if !ok {
 			http.NotFound(w, r)
 			return
 		}
-		cidStr := r.URL.Path[len("/.well-known/rasl/"):]
 
 		path, ok := cidPaths[cidStr]
 		if !ok {
 			http.NotFound(w, r)
 			return
 		}
+		if IsSliceRequest(r) {
+			c, _ := cid.NewCidFromString(cidStr)
+			if o := outboards[c.Digest()]; o != nil && c.HashType() == cid.HashTypeBlake3 {
+				f, err := os.Open(filepath.Join(dir, path))
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				defer f.Close()
+				ServeSlice(w, r, o, f)
+				return
+			}
+		}
 
-		w.Header().Add("Content-Type", "application/octet-stream")
-		http.ServeFile(w, r, filepath.Join(dir, path))
+		serveFile(w, r, cidStr, filepath.Join(dir, path))
 	}), nil
 }
+
+// IsSliceRequest reports whether a RASL request is for a Bao slice of the content,
+// made with URL.FetchRange, and should be served with ServeSlice.
+// Handlers that don't support slices for a CID can serve the data as usual.
+func IsSliceRequest(r *http.Request) bool {
+	return r.URL.Query().Has(sliceOffsetParam)
+}
+
+// ServeSlice responds to a Bao slice request with the requested byte range of the data
+// and the tree nodes needed to verify it, taken from the outboard. The outboard must be
+// for the CID of the request, and data must be the content it was computed from.
+//
+// The range is given as an offset and an optional length in the query string of the
+// request. The chunk group size of the outboard is sent in the Rasl-Bao-Group header,
+// so any group size can be used. Status 416 is returned if the offset is past the end
+// of the data. Caching and If-None-Match are handled like for the whole content, but
+// the ETag is made of the CID, the group size, and the range of the slice.
+func ServeSlice(w http.ResponseWriter, r *http.Request, o *bao.Outboard, data io.ReaderAt) {
+	query := r.URL.Query()
+	offset, err := strconv.ParseInt(query.Get(sliceOffsetParam), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "invalid "+sliceOffsetParam, http.StatusBadRequest)
+		return
+	}
+	length := int64(-1)
+	if query.Has(sliceLengthParam) {
+		length, err = strconv.ParseInt(query.Get(sliceLengthParam), 10, 64)
+		if err != nil || length < 0 {
+			http.Error(w, "invalid "+sliceLengthParam, http.StatusBadRequest)
+			return
+		}
+	}
+	if offset > o.Size() {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", o.Size()))
+		http.Error(w, "offset past the end of the content", http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	cidStr, ok := cidFromPath(r.URL.Path)
+	if !ok {
+		cidStr = o.Cid().String()
+	}
+	// The slice is different bytes from the whole content, so it has its own ETag
+	end := o.Size()
+	if length >= 0 && length < end-offset {
+		end = offset + length
+	}
+	etag := fmt.Sprintf(`"%s-bao%d-%d-%d"`, cidStr, o.Group(), offset, end)
+	if setCacheHeaders(w, r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set(sliceGroupHeader, strconv.Itoa(o.Group()))
+	if r.Method == "HEAD" {
+		return
+	}
+	if err := o.WriteSlice(w, data, offset, length); err != nil {
+		// Unfortunately some data might already be written at this point
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -3,13 +3,16 @@ package rasl_test
 import (
 	"bytes"
 	"io"
+	"maps"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/hyphacoop/go-dasl/bao"
 	"github.com/hyphacoop/go-dasl/cid"
+	"github.com/hyphacoop/go-dasl/drisl"
 	"github.com/hyphacoop/go-dasl/rasl"
 )
 
@@ -205,3 +208,283 @@ func TestDirectoryHandler_NotFound(t *testing.T) {
 		t.Fatalf("Expected status %d, got %d", http.StatusNotFound, w.Code)
 	}
 }
+
+func TestFuncHandler_DrislCid(t *testing.T) {
+	testData, _ := drisl.Marshal(map[string]any{"hello": "world"})
+	testCid, _ := drisl.CidForValue(map[string]any{"hello": "world"})
+
+	handler := rasl.FuncHandler(func(c cid.Cid) (io.Reader, error) {
+		if c.Equal(testCid) {
+			return bytes.NewReader(testData), nil
+		}
+		return nil, nil
+	})
+
+	req := httptest.NewRequest("GET", "/.well-known/rasl/"+testCid.String(), nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if !bytes.Equal(w.Body.Bytes(), testData) {
+		t.Fatalf("Expected body %x, got %x", testData, w.Body.Bytes())
+	}
+
+	// Other multibase prefixes are still rejected
+	req = httptest.NewRequest("GET", "/.well-known/rasl/zb2rhe5P4gXftAwvA4eXQ5HJwsER2owDyS9sKaQRRVQPn93bA", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestCidDirectoryHandler_DrislCid(t *testing.T) {
+	tmpDir := t.TempDir()
+	testData, _ := drisl.Marshal(map[string]any{"hello": "world"})
+	testCid, _ := drisl.CidForValue(map[string]any{"hello": "world"})
+
+	if err := os.WriteFile(filepath.Join(tmpDir, testCid.String()), testData, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	handler := rasl.CidDirectoryHandler(tmpDir)
+
+	req := httptest.NewRequest("GET", "/.well-known/rasl/"+testCid.String(), nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if !bytes.Equal(w.Body.Bytes(), testData) {
+		t.Fatalf("Expected body %x, got %x", testData, w.Body.Bytes())
+	}
+}
+
+func TestDirectoryHandlerWithOptions_IndexDrisl(t *testing.T) {
+	tmpDir := t.TempDir()
+	drislData, _ := drisl.Marshal(map[string]any{"hello": "world"})
+	textData := []byte("hello world")
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "record.drisl"), drislData, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "testfile.txt"), textData, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	handler, err := rasl.DirectoryHandlerWithOptions(tmpDir, rasl.DirectoryOptions{
+		HashBlake3: true,
+		IndexDrisl: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create DirectoryHandler: %v", err)
+	}
+
+	drislSha256, _ := drisl.CidForValue(map[string]any{"hello": "world"})
+	drislBlake3, _ := cid.NewCidFromInfo(cid.CodecDrisl, cid.HashTypeBlake3, cid.HashBytesBlake3(drislData).Digest())
+	textDrisl, _ := cid.NewCidFromInfo(cid.CodecDrisl, cid.HashTypeSha256, cid.HashBytes(textData).Digest())
+
+	tests := []struct {
+		c    cid.Cid
+		code int
+		body []byte
+	}{
+		{drislSha256, http.StatusOK, drislData},
+		{drislBlake3, http.StatusOK, drislData},
+		{cid.HashBytes(drislData), http.StatusOK, drislData},
+		{cid.HashBytes(textData), http.StatusOK, textData},
+		{textDrisl, http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/.well-known/rasl/"+tt.c.String(), nil)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		if w.Code != tt.code {
+			t.Fatalf("%s: expected status %d, got %d", tt.c, tt.code, w.Code)
+		}
+		if tt.body != nil && !bytes.Equal(w.Body.Bytes(), tt.body) {
+			t.Fatalf("%s: expected body %x, got %x", tt.c, tt.body, w.Body.Bytes())
+		}
+	}
+}
+
+func TestDirectoryHandlerWithOptions_BaoOutboards(t *testing.T) {
+	tmpDir := t.TempDir()
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+	if err := os.WriteFile(filepath.Join(tmpDir, "data"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	handler, err := rasl.DirectoryHandlerWithOptions(tmpDir, rasl.DirectoryOptions{BaoOutboards: true})
+	if err != nil {
+		t.Fatalf("Failed to create DirectoryHandler: %v", err)
+	}
+	path := "/.well-known/rasl/" + cid.HashBytesBlake3(data).String()
+
+	req := httptest.NewRequest("GET", path+"?bao-offset=20000&bao-length=100", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Rasl-Bao-Group") != "4" {
+		t.Fatalf("Expected a slice, got status %d and headers %v", w.Code, w.Header())
+	}
+	r, err := bao.NewSliceReader(w.Body, cid.HashBytesBlake3(data), bao.DefaultGroup, 20000, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data[20000:20100]) {
+		t.Fatalf("Got %q, want %q", got, data[20000:20100])
+	}
+
+	// Slices have their own ETag, which is used for conditional requests
+	etag := w.Header().Get("ETag")
+	if want := `"` + cid.HashBytesBlake3(data).String() + `-bao4-20000-20100"`; etag != want {
+		t.Fatalf("Got ETag %s, want %s", etag, want)
+	}
+	req = httptest.NewRequest("GET", path+"?bao-offset=20000&bao-length=100", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("Expected status %d, got %d", http.StatusNotModified, w.Code)
+	}
+	req = httptest.NewRequest("GET", path+"?bao-offset=20000&bao-length=100", nil)
+	req.Header.Set("If-None-Match", `"`+cid.HashBytesBlake3(data).String()+`"`)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for the ETag of the whole content, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", path+"?bao-offset=100001", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Fatalf("Expected status %d, got %d", http.StatusRequestedRangeNotSatisfiable, w.Code)
+	}
+
+	// SHA-256 CIDs are served as usual
+	req = httptest.NewRequest("GET", "/.well-known/rasl/"+cid.HashBytes(data).String()+"?bao-offset=0", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Rasl-Bao-Group") != "" || !bytes.Equal(w.Body.Bytes(), data) {
+		t.Fatalf("Expected the whole file, got status %d and headers %v", w.Code, w.Header())
+	}
+}
+
+func TestHandlers_RangeAndConditional(t *testing.T) {
+	testData := []byte("0123456789abcdef")
+	testCid := cid.HashBytes(testData)
+	etag := `"` + testCid.String() + `"`
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, testCid.String()), testData, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dirHandler, err := rasl.DirectoryHandler(tmpDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handlers := map[string]http.Handler{
+		"FuncHandler": rasl.FuncHandler(func(c cid.Cid) (io.Reader, error) {
+			return bytes.NewReader(testData), nil
+		}),
+		"CidDirectoryHandler": rasl.CidDirectoryHandler(tmpDir),
+		"DirectoryHandler":    dirHandler,
+	}
+
+	for name, handler := range handlers {
+		serve := func(method string, header http.Header) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, "/.well-known/rasl/"+testCid.String(), nil)
+			maps.Copy(req.Header, header)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			return w
+		}
+
+		w := serve("GET", nil)
+		if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), testData) {
+			t.Fatalf("%s: expected the content, got status %d", name, w.Code)
+		}
+		if w.Header().Get("ETag") != etag || w.Header().Get("Cache-Control") != "public, max-age=31536000, immutable" {
+			t.Fatalf("%s: unexpected headers %v", name, w.Header())
+		}
+		if w.Header().Get("Content-Type") != "application/octet-stream" {
+			t.Fatalf("%s: unexpected Content-Type %q", name, w.Header().Get("Content-Type"))
+		}
+
+		w = serve("GET", http.Header{"Range": {"bytes=4-7"}})
+		if w.Code != http.StatusPartialContent || w.Body.String() != "4567" {
+			t.Fatalf("%s: expected partial content, got status %d and %q", name, w.Code, w.Body)
+		}
+		if w.Header().Get("Content-Range") != "bytes 4-7/16" {
+			t.Fatalf("%s: unexpected Content-Range %q", name, w.Header().Get("Content-Range"))
+		}
+
+		for _, inm := range []string{etag, `"other", W/` + etag, "*"} {
+			w = serve("GET", http.Header{"If-None-Match": {inm}})
+			if w.Code != http.StatusNotModified || w.Body.Len() != 0 || w.Header().Get("ETag") != etag {
+				t.Fatalf("%s: If-None-Match %s: expected status 304, got %d", name, inm, w.Code)
+			}
+		}
+		w = serve("GET", http.Header{"If-None-Match": {`"other"`}})
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200 for another ETag, got %d", name, w.Code)
+		}
+
+		w = serve("HEAD", nil)
+		if w.Code != http.StatusOK || w.Body.Len() != 0 || w.Header().Get("Content-Length") != "16" {
+			t.Fatalf("%s: unexpected HEAD response, status %d and headers %v", name, w.Code, w.Header())
+		}
+	}
+}
+
+func TestFuncHandler_NotSeekable(t *testing.T) {
+	testData := []byte("hello world")
+	testCid := cid.HashBytes(testData)
+	handler := rasl.FuncHandler(func(c cid.Cid) (io.Reader, error) {
+		return io.MultiReader(bytes.NewReader(testData)), nil
+	})
+
+	req := httptest.NewRequest("GET", "/.well-known/rasl/"+testCid.String(), nil)
+	req.Header.Set("Range", "bytes=0-4")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), testData) {
+		t.Fatalf("Expected the whole content, got status %d and %q", w.Code, w.Body)
+	}
+	if w.Header().Get("ETag") != `"`+testCid.String()+`"` {
+		t.Fatalf("Unexpected ETag %q", w.Header().Get("ETag"))
+	}
+
+	req = httptest.NewRequest("GET", "/.well-known/rasl/"+testCid.String(), nil)
+	req.Header.Set("If-None-Match", `"`+testCid.String()+`"`)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("Expected status 304, got %d", w.Code)
+	}
+}
+
+func TestCidDirectoryHandler_Directory(t *testing.T) {
+	tmpDir := t.TempDir()
+	testCid := cid.HashBytes([]byte("a directory"))
+	if err := os.Mkdir(filepath.Join(tmpDir, testCid.String()), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("GET", "/.well-known/rasl/"+testCid.String(), nil)
+	w := httptest.NewRecorder()
+	rasl.CidDirectoryHandler(tmpDir).ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -30,7 +30,7 @@ type URL struct {
 	Hints []string
 
 	// Path is the optional URL path.
-	// This can be used if the CID resolves to MASL data.
+	// This can be used if the CID resolves to MASL data, see masl.Resolve.
 	Path string
 }
 